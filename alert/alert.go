@@ -0,0 +1,252 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+// Package alert evaluates alert rules over the samples and aggregates, and
+// reports when alerts fire and clear.
+package alert
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/geoffholden/gowx/data"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// Point is a value at a point in time.
+type Point struct {
+	T time.Time `json:"t"`
+	V float64   `json:"v"`
+}
+
+// State is the state of a rule for one sensor of a station.
+type State struct {
+	Rule    string `json:"rule"`
+	Station string `json:"station,omitempty"`
+	// Name is the logical sensor, if the sensor is registered, and Sensor
+	// the physical sensor last seen.
+	Name   string         `json:"name,omitempty"`
+	Sensor data.SensorKey `json:"sensor"`
+	Firing bool           `json:"firing"`
+	// Since is when the alert fired.
+	Since time.Time `json:"since,omitempty"`
+	// Pending is when the condition started to hold, while it hasn't held
+	// long enough to fire.
+	Pending time.Time `json:"pending,omitempty"`
+	Value   float64   `json:"value"`
+	Updated time.Time `json:"updated"`
+	History []Point   `json:"history,omitempty"`
+}
+
+// Alert is a rule firing or clearing.
+type Alert struct {
+	Rule    string
+	Message string
+	// Station is the station of the sensor, "" for the default station.
+	Station string
+	Sensor  data.SensorKey
+	Key     string
+	Firing  bool
+	// Value is the value or change the condition was checked against.
+	Value float64
+	Time  time.Time
+	// Since is when the alert fired.
+	Since time.Time
+}
+
+// Engine evaluates the rules. The state of the rules is saved to a file, so
+// that an alert isn't reported again after a restart. It is safe for
+// concurrent use.
+type Engine struct {
+	// Now is the clock, used for samples without a timestamp.
+	Now func() time.Time
+	// OnAlert is called when an alert fires or clears.
+	OnAlert func(a Alert)
+	// Name, if set, returns the logical name of a physical sensor of a
+	// station. The state of a logical sensor carries on when its physical
+	// sensor changes, such as after a battery change.
+	Name func(station string, key data.SensorKey) (string, bool)
+
+	rules  []*Rule
+	path   string
+	mu     sync.Mutex
+	states map[string]*State
+	dirty  bool
+}
+
+// NewEngine creates an engine, loading the saved state from the path if
+// there is one.
+func NewEngine(rules []*Rule, path string) (*Engine, error) {
+	e := &Engine{
+		Now:    time.Now,
+		rules:  rules,
+		path:   path,
+		states: make(map[string]*State),
+	}
+	if path == "" {
+		return e, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return e, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var states []*State
+	if err := json.Unmarshal(b, &states); err != nil {
+		return nil, err
+	}
+	// The states of rules no longer configured are dropped.
+	names := make(map[string]bool)
+	for _, r := range rules {
+		names[r.Name] = true
+	}
+	for _, s := range states {
+		if names[s.Rule] {
+			e.states[stateKey(s.Rule, s.Station, s.Name, s.Sensor)] = s
+		}
+	}
+	return e, nil
+}
+
+func stateKey(rule string, station string, name string, sensor data.SensorKey) string {
+	if name != "" {
+		if station != "" {
+			name += "@" + station
+		}
+		return rule + " " + name
+	}
+	return rule + " " + data.SensorID{ID: sensor.ID, Channel: sensor.Channel, Serial: sensor.Serial, Station: station}.String()
+}
+
+// Sample evaluates the sample rules.
+func (e *Engine) Sample(d data.SensorData) {
+	t := d.TimeStamp
+	if t.IsZero() {
+		t = e.Now()
+	}
+	for _, r := range e.rules {
+		if r.Aggregate {
+			continue
+		}
+		if v, ok := d.Data[r.Key]; ok && r.Match(d.Key()) {
+			e.evaluate(r, d.Station, d.Key(), t, v)
+		}
+	}
+}
+
+// Aggregate evaluates the aggregate rules for a sensor of a station.
+func (e *Engine) Aggregate(t time.Time, station string, sensor data.SensorKey, key string, min, max, avg float64) {
+	for _, r := range e.rules {
+		if !r.Aggregate || r.Key != key || !r.Match(sensor) {
+			continue
+		}
+		v := avg
+		switch r.Stat {
+		case "min":
+			v = min
+		case "max":
+			v = max
+		}
+		e.evaluate(r, station, sensor, t, v)
+	}
+}
+
+func (e *Engine) evaluate(r *Rule, station string, sensor data.SensorKey, t time.Time, v float64) {
+	var name string
+	if e.Name != nil {
+		name, _ = e.Name(station, sensor)
+	}
+	e.mu.Lock()
+	key := stateKey(r.Name, station, name, sensor)
+	s := e.states[key]
+	if s == nil {
+		s = &State{Rule: r.Name, Station: station, Name: name}
+		e.states[key] = s
+	}
+	// Late values, such as replayed samples, would evaluate the rule out of
+	// order.
+	if t.Before(s.Updated) {
+		e.mu.Unlock()
+		return
+	}
+	x := r.value(s, t, v)
+	s.Sensor, s.Value, s.Updated = sensor, x, t
+	e.dirty = true
+
+	var alert *Alert
+	if s.Firing {
+		if r.When.cleared(x, r.Clear) {
+			alert = &Alert{Firing: false, Since: s.Since}
+			s.Firing, s.Since, s.Pending = false, time.Time{}, time.Time{}
+		}
+	} else if r.When.Holds(x) {
+		if s.Pending.IsZero() {
+			s.Pending = t
+		}
+		if t.Sub(s.Pending) >= r.For {
+			s.Firing, s.Since, s.Pending = true, t, time.Time{}
+			alert = &Alert{Firing: true, Since: t}
+		}
+	} else {
+		s.Pending = time.Time{}
+	}
+	e.mu.Unlock()
+
+	if alert == nil {
+		return
+	}
+	alert.Rule, alert.Message, alert.Station, alert.Sensor, alert.Key = r.Name, r.Message, station, sensor, r.Key
+	alert.Value, alert.Time = x, t
+	if err := e.Save(); err != nil {
+		jww.ERROR.Println(err)
+	}
+	if e.OnAlert != nil {
+		e.OnAlert(*alert)
+	}
+}
+
+// States returns the state of every rule and sensor, sorted by rule.
+func (e *Engine) States() []State {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	result := make([]State, 0, len(e.states))
+	for _, s := range e.states {
+		state := *s
+		state.History = nil
+		result = append(result, state)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return stateKey(result[i].Rule, result[i].Station, result[i].Name, result[i].Sensor) < stateKey(result[j].Rule, result[j].Station, result[j].Name, result[j].Sensor)
+	})
+	return result
+}
+
+// Save writes the state to the file, if it has changed.
+func (e *Engine) Save() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.path == "" || !e.dirty {
+		return nil
+	}
+	states := make([]*State, 0, len(e.states))
+	for _, s := range e.states {
+		states = append(states, s)
+	}
+	b, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+	tmp := e.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, e.path); err != nil {
+		return err
+	}
+	e.dirty = false
+	return nil
+}
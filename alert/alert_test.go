@@ -0,0 +1,219 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package alert
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/geoffholden/gowx/data"
+	"github.com/spf13/viper"
+)
+
+var greenhouse = data.SensorKey{ID: "OS3:1D20", Channel: 2, Serial: "A4"}
+
+func newTestRule(t *testing.T, name string, settings map[string]interface{}) *Rule {
+	config := viper.New()
+	for key, value := range settings {
+		config.Set(key, value)
+	}
+	r, err := NewRule(name, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// clock is a fake clock, advanced by hand.
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newTestEngine(t *testing.T, path string, rules ...*Rule) (*Engine, *clock, *[]Alert) {
+	engine, err := NewEngine(rules, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &clock{time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)}
+	engine.Now = c.Now
+	var alerts []Alert
+	engine.OnAlert = func(a Alert) {
+		alerts = append(alerts, a)
+	}
+	return engine, c, &alerts
+}
+
+func sample(key string, v float64) data.SensorData {
+	return data.SensorData{ID: greenhouse.ID, Channel: greenhouse.Channel, Serial: greenhouse.Serial, Data: map[string]float64{key: v}}
+}
+
+func TestParseCondition(t *testing.T) {
+	c, err := ParseCondition("drop > 3 in 3h")
+	if err != nil || c.Change != "drop" || c.Op != ">" || c.Value != 3 || c.Window != 3*time.Hour {
+		t.Error("Unexpected condition", c, err)
+	}
+	if c.String() != "drop > 3 in 3h0m0s" {
+		t.Error("Unexpected string", c.String())
+	}
+	for _, s := range []string{"", "0", "= 3", "< x", "fall > 3 in 3h", "drop > 3 in soon"} {
+		if _, err := ParseCondition(s); err == nil {
+			t.Errorf("%q should be an error", s)
+		}
+	}
+}
+
+func TestHysteresis(t *testing.T) {
+	r := newTestRule(t, "frost", map[string]interface{}{"type": "Temperature", "when": "< 0", "clear": 1})
+	engine, c, alerts := newTestEngine(t, "", r)
+
+	for _, v := range []float64{2, -0.5, 0.5, -0.2, 0.8, 1.5, 0.5} {
+		c.now = c.now.Add(time.Minute)
+		engine.Sample(sample("Temperature", v))
+	}
+	if len(*alerts) != 2 {
+		t.Fatal("Expected the alert to fire and clear once", *alerts)
+	}
+	if a := (*alerts)[0]; !a.Firing || a.Value != -0.5 || a.Sensor != greenhouse || a.Message != "Temperature < 0" {
+		t.Error("Unexpected alert", a)
+	}
+	if a := (*alerts)[1]; a.Firing || a.Value != 1.5 || a.Since != (*alerts)[0].Time {
+		t.Error("Unexpected clear", a)
+	}
+}
+
+func TestSustained(t *testing.T) {
+	r := newTestRule(t, "gusts", map[string]interface{}{"type": "CurrentWind[max]", "when": "> 19.4", "for": "10m"})
+	if !r.Aggregate || r.Stat != "max" {
+		t.Fatal("Expected an aggregate rule", r)
+	}
+	engine, c, alerts := newTestEngine(t, "", r)
+
+	aggregate := func(max float64) {
+		c.now = c.now.Add(5 * time.Minute)
+		engine.Aggregate(c.now, "", greenhouse, "CurrentWind", 0, max, 10)
+	}
+	aggregate(25)
+	aggregate(15)
+	aggregate(25)
+	aggregate(25)
+	if len(*alerts) != 0 {
+		t.Fatal("Alert fired too early", *alerts)
+	}
+	aggregate(22)
+	if len(*alerts) != 1 || !(*alerts)[0].Firing || !(*alerts)[0].Time.Equal(c.now) {
+		t.Error("Expected the alert to fire after 10 minutes", *alerts)
+	}
+	engine.Sample(sample("CurrentWind", 30))
+	if len(*alerts) != 1 {
+		t.Error("Samples shouldn't count for aggregate rules", *alerts)
+	}
+}
+
+func TestRateOfChange(t *testing.T) {
+	r := newTestRule(t, "pressure", map[string]interface{}{"type": "Pressure", "when": "drop > 3 in 3h"})
+	engine, c, alerts := newTestEngine(t, "", r)
+
+	for _, v := range []float64{1012, 1011, 1010, 1008.5, 1008} {
+		c.now = c.now.Add(time.Hour)
+		engine.Sample(sample("Pressure", v))
+	}
+	// The drop from 1012 three hours earlier fires the alert, then the drop
+	// from 1011 is only 3.
+	if len(*alerts) != 2 || !(*alerts)[0].Firing || (*alerts)[0].Value != 3.5 || (*alerts)[1].Firing {
+		t.Error("Unexpected alerts", *alerts)
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.json")
+	r := newTestRule(t, "frost", map[string]interface{}{"type": "Temperature", "when": "< 0"})
+	engine, _, alerts := newTestEngine(t, path, r)
+	engine.Sample(sample("Temperature", -1))
+	if len(*alerts) != 1 {
+		t.Fatal("Expected an alert", *alerts)
+	}
+
+	engine, _, alerts = newTestEngine(t, path, r)
+	engine.Sample(sample("Temperature", -2))
+	if len(*alerts) != 0 {
+		t.Error("Alerts shouldn't repeat after a restart", *alerts)
+	}
+	if states := engine.States(); len(states) != 1 || !states[0].Firing || states[0].Value != -2 {
+		t.Error("Unexpected states", states)
+	}
+
+	// Rules that are gone lose their state.
+	engine, _, _ = newTestEngine(t, path)
+	if states := engine.States(); len(states) != 0 {
+		t.Error("Unexpected states", states)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	r := newTestRule(t, "frost", map[string]interface{}{"type": "Temperature", "when": "< 0"})
+	r.Match = func(key data.SensorKey) bool { return key.Channel == 1 }
+	engine, _, alerts := newTestEngine(t, "", r)
+	engine.Sample(sample("Temperature", -1))
+	if len(*alerts) != 0 {
+		t.Error("Other sensors shouldn't match", *alerts)
+	}
+}
+
+func TestLogicalSensor(t *testing.T) {
+	r := newTestRule(t, "frost", map[string]interface{}{"type": "Temperature", "when": "< 0"})
+	engine, _, alerts := newTestEngine(t, "", r)
+	engine.Name = func(station string, key data.SensorKey) (string, bool) {
+		return "greenhouse", key.ID == greenhouse.ID && key.Channel == greenhouse.Channel
+	}
+	engine.Sample(sample("Temperature", -1))
+
+	// A battery change gives the sensor a new serial number.
+	d := sample("Temperature", -2)
+	d.Serial = "B7"
+	engine.Sample(d)
+	if len(*alerts) != 1 {
+		t.Error("Alerts shouldn't repeat after a serial change", *alerts)
+	}
+	if states := engine.States(); len(states) != 1 || states[0].Name != "greenhouse" || states[0].Sensor.Serial != "B7" {
+		t.Error("Unexpected states", states)
+	}
+}
+
+func TestHistoryDownsampled(t *testing.T) {
+	r := newTestRule(t, "pressure", map[string]interface{}{"type": "Pressure", "when": "drop > 3 in 3h"})
+	engine, c, _ := newTestEngine(t, "", r)
+	for i := 0; i < 3*60*6; i++ {
+		c.now = c.now.Add(10 * time.Second)
+		engine.Sample(sample("Pressure", 1012))
+	}
+	for _, s := range engine.states {
+		if len(s.History) > 3*60+1 {
+			t.Error("History should have a point a minute", len(s.History))
+		}
+	}
+}
+
+func TestStations(t *testing.T) {
+	r := newTestRule(t, "frost", map[string]interface{}{"type": "Temperature", "when": "< 0"})
+	engine, _, alerts := newTestEngine(t, "", r)
+	engine.Name = func(station string, key data.SensorKey) (string, bool) {
+		return "greenhouse", true
+	}
+	engine.Sample(sample("Temperature", -1))
+	d := sample("Temperature", -1)
+	d.Station = "cabin"
+	engine.Sample(d)
+
+	// The same logical sensor at two stations has a state for each.
+	if len(*alerts) != 2 || (*alerts)[0].Station != "" || (*alerts)[1].Station != "cabin" {
+		t.Error("Unexpected alerts", *alerts)
+	}
+	if states := engine.States(); len(states) != 2 || states[0].Station != "" || states[1].Station != "cabin" {
+		t.Error("Unexpected states", states)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/geoffholden/gowx/data"
+	"github.com/spf13/viper"
+)
+
+// Condition is what a rule checks. Without a Change, the value itself is
+// compared; otherwise its drop, rise or absolute change over the Window.
+type Condition struct {
+	Change string // "", "drop", "rise" or "change"
+	Window time.Duration
+	Op     string // ">", ">=", "<" or "<="
+	Value  float64
+}
+
+// ParseCondition parses a condition such as "< 0" or "drop > 3 in 3h".
+func ParseCondition(s string) (Condition, error) {
+	var c Condition
+	fields := strings.Fields(s)
+	if len(fields) == 5 && fields[3] == "in" {
+		switch fields[0] {
+		case "drop", "rise", "change":
+			c.Change = fields[0]
+		default:
+			return c, fmt.Errorf("condition %q: unknown change %q", s, fields[0])
+		}
+		window, err := time.ParseDuration(fields[4])
+		if err != nil || window <= 0 {
+			return c, fmt.Errorf("condition %q: bad window %q", s, fields[4])
+		}
+		c.Window = window
+		fields = fields[1:3]
+	}
+	if len(fields) != 2 {
+		return c, fmt.Errorf("condition %q: expected e.g. \"> 10\" or \"drop > 3 in 3h\"", s)
+	}
+	switch fields[0] {
+	case ">", ">=", "<", "<=":
+		c.Op = fields[0]
+	default:
+		return c, fmt.Errorf("condition %q: unknown comparison %q", s, fields[0])
+	}
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return c, fmt.Errorf("condition %q: %s", s, err.Error())
+	}
+	c.Value = value
+	return c, nil
+}
+
+func (c Condition) String() string {
+	s := c.Op + " " + strconv.FormatFloat(c.Value, 'f', -1, 64)
+	if c.Change != "" {
+		s = c.Change + " " + s + " in " + c.Window.String()
+	}
+	return s
+}
+
+// Holds reports whether the condition holds for x, the value or its change.
+func (c Condition) Holds(x float64) bool {
+	switch c.Op {
+	case ">":
+		return x > c.Value
+	case ">=":
+		return x >= c.Value
+	case "<":
+		return x < c.Value
+	case "<=":
+		return x <= c.Value
+	}
+	return false
+}
+
+// cleared reports whether a firing condition has cleared, given the
+// hysteresis level.
+func (c Condition) cleared(x float64, clear *float64) bool {
+	if clear == nil {
+		return !c.Holds(x)
+	}
+	if c.Op == ">" || c.Op == ">=" {
+		return x < *clear
+	}
+	return x > *clear
+}
+
+// Rule is an alert rule.
+type Rule struct {
+	Name    string
+	Message string
+	// Key is the sample or aggregate key, e.g. Temperature.
+	Key string
+	// Aggregate rules check the aggregates, using the Stat value (min, max
+	// or avg), rather than the samples.
+	Aggregate bool
+	Stat      string
+	// Match selects the sensors the rule applies to.
+	Match func(key data.SensorKey) bool
+	When  Condition
+	// Clear is the level a firing alert has to cross back over to clear,
+	// by default the level of the condition.
+	Clear *float64
+	// For is how long the condition has to hold before the alert fires.
+	For time.Duration
+}
+
+var stat = regexp.MustCompile(`^([^[]*)(?:\[(min|max|avg)\])?$`)
+
+// NewRule creates a rule from its configuration section:
+//
+//	type: CurrentWind[max]   the key, with an aggregate value
+//	when: "> 19.4"           the condition
+//	clear: 15                the hysteresis level
+//	for: 10m                 how long the condition has to hold
+//	source: aggregate        sample (the default) or aggregate
+//	message: Strong gusts
+//
+// The sensor is selected by the caller, which sets Match.
+func NewRule(name string, config *viper.Viper) (*Rule, error) {
+	r := &Rule{Name: name, Message: config.GetString("message")}
+	m := stat.FindStringSubmatch(config.GetString("type"))
+	if m == nil || m[1] == "" {
+		return nil, errors.New("type is required")
+	}
+	r.Key, r.Stat = m[1], m[2]
+	switch config.GetString("source") {
+	case "", "sample":
+		r.Aggregate = r.Stat != ""
+	case "aggregate":
+		r.Aggregate = true
+	default:
+		return nil, fmt.Errorf("unknown source %q", config.GetString("source"))
+	}
+	if r.Stat == "" {
+		r.Stat = "avg"
+	}
+
+	when, err := ParseCondition(config.GetString("when"))
+	if err != nil {
+		return nil, err
+	}
+	r.When = when
+	if config.IsSet("clear") {
+		clear := config.GetFloat64("clear")
+		r.Clear = &clear
+	}
+	if config.IsSet("for") {
+		r.For, err = time.ParseDuration(config.GetString("for"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.Message == "" {
+		r.Message = r.Key + " " + r.When.String()
+	}
+	r.Match = func(data.SensorKey) bool { return true }
+	return r, nil
+}
+
+// historyPoints is the number of points kept over the window of a change
+// condition, at most one a minute.
+const historyPoints = 60
+
+// value returns what the condition is checked against, recording the value
+// in the history of change conditions.
+func (r *Rule) value(s *State, t time.Time, v float64) float64 {
+	if r.When.Change == "" {
+		return v
+	}
+	// Samples arrive every few seconds, so the history is downsampled to
+	// keep the saved state small.
+	step := r.When.Window / historyPoints
+	if step > time.Minute {
+		step = time.Minute
+	}
+	if n := len(s.History); n == 0 || t.Sub(s.History[n-1].T) >= step {
+		s.History = append(s.History, Point{t, v})
+	}
+	cutoff := t.Add(-r.When.Window)
+	for len(s.History) > 1 && s.History[0].T.Before(cutoff) {
+		s.History = s.History[1:]
+	}
+	first := s.History[0].V
+	switch r.When.Change {
+	case "drop":
+		return first - v
+	case "rise":
+		return v - first
+	}
+	return math.Abs(v - first)
+}
@@ -0,0 +1,400 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+// Package broker is a minimal MQTT 3.1.1 broker in front of a local bus, so
+// that external clients can publish and subscribe alongside the components
+// running in process. It supports wildcards, retained messages and last-will
+// messages, but always delivers at QoS 0 and doesn't keep sessions.
+package broker
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/geoffholden/gowx/bus"
+)
+
+// Connection describes a client connection.
+type Connection struct {
+	ClientID     string
+	Username     string
+	Password     string
+	CleanSession bool
+	Will         *bus.Message
+	// KeepAlive is the longest the client may stay silent, or zero for no
+	// limit.
+	KeepAlive time.Duration
+}
+
+const (
+	// MaxPacketSize is the largest packet accepted from a client.
+	MaxPacketSize = 1 << 20
+	// WriteTimeout is how long a client may take to accept a packet
+	// before it's disconnected.
+	WriteTimeout = 10 * time.Second
+	// ConnectTimeout is how long a client may take to send its CONNECT.
+	ConnectTimeout = 10 * time.Second
+	// queueSize is the number of messages queued for a subscriber. A
+	// subscriber that falls further behind is disconnected, rather than
+	// holding up the bus.
+	queueSize = 256
+)
+
+type conn struct {
+	net.Conn
+	write         sync.Mutex
+	subscriptions map[string]func()
+	queue         chan bus.Message
+	done          chan struct{}
+}
+
+// Broker is a running broker.
+type Broker struct {
+	Bus *bus.Local
+	// OnConnect and OnPublish, if set, are called for every accepted client
+	// connection and every message published by a client.
+	OnConnect func(Connection)
+	OnPublish func(m bus.Message, qos byte)
+
+	authorize func(Connection) bool
+	listener  net.Listener
+	mu        sync.Mutex
+	conns     map[*conn]bool
+}
+
+// Listen starts a broker for b on a TCP address. If authorize is set,
+// connections it returns false for are refused as not authorized.
+func Listen(address string, b *bus.Local, authorize func(Connection) bool) (*Broker, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	broker := &Broker{
+		Bus:       b,
+		authorize: authorize,
+		listener:  listener,
+		conns:     make(map[*conn]bool),
+	}
+	go broker.accept()
+	return broker, nil
+}
+
+// Addr returns the address the broker is listening on.
+func (b *Broker) Addr() net.Addr {
+	return b.listener.Addr()
+}
+
+// Close stops the broker and drops all client connections.
+func (b *Broker) Close() {
+	b.listener.Close()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.conns {
+		c.Close()
+	}
+}
+
+func (b *Broker) accept() {
+	for {
+		nc, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		c := &conn{
+			Conn:          nc,
+			subscriptions: make(map[string]func()),
+			queue:         make(chan bus.Message, queueSize),
+			done:          make(chan struct{}),
+		}
+		b.mu.Lock()
+		b.conns[c] = true
+		b.mu.Unlock()
+		go b.serve(c)
+		go c.deliver()
+	}
+}
+
+func (b *Broker) publish(m bus.Message, qos byte) {
+	if b.OnPublish != nil {
+		b.OnPublish(m, qos)
+	}
+	b.Bus.Publish(m.Topic, m.Payload, m.Retained)
+}
+
+func (b *Broker) serve(c *conn) {
+	var will *bus.Message
+	defer func() {
+		c.Close()
+		close(c.done)
+		b.mu.Lock()
+		delete(b.conns, c)
+		b.mu.Unlock()
+		for _, cancel := range c.subscriptions {
+			cancel()
+		}
+		if will != nil {
+			b.publish(*will, 0)
+		}
+	}()
+
+	// The first packet must be a CONNECT, and nothing else is handled
+	// until it has been accepted.
+	r := bufio.NewReader(c)
+	c.SetReadDeadline(time.Now().Add(ConnectTimeout))
+	header, body, err := readPacket(r)
+	if err != nil || header>>4 != 1 {
+		return
+	}
+	info, err := parseConnect(body)
+	if err != nil {
+		return
+	}
+	if b.authorize != nil && !b.authorize(info) {
+		c.send(0x20, []byte{0, 5}) // not authorized
+		return
+	}
+	will = info.Will
+	if b.OnConnect != nil {
+		b.OnConnect(info)
+	}
+	c.send(0x20, []byte{0, 0})
+
+	c.SetReadDeadline(time.Time{})
+	for {
+		// Clients that stay silent for one and a half times their keep
+		// alive are disconnected.
+		if info.KeepAlive > 0 {
+			c.SetReadDeadline(time.Now().Add(info.KeepAlive * 3 / 2))
+		}
+		header, body, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		switch header >> 4 {
+		case 1: // CONNECT
+			// A second CONNECT is a protocol violation.
+			return
+		case 3: // PUBLISH
+			m, qos, id, err := parsePublish(header, body)
+			if err != nil {
+				return
+			}
+			switch qos {
+			case 1:
+				c.send(0x40, id)
+			case 2:
+				c.send(0x50, id)
+			}
+			b.publish(m, qos)
+		case 6: // PUBREL
+			if len(body) < 2 {
+				return
+			}
+			c.send(0x70, body[:2])
+		case 8: // SUBSCRIBE
+			if len(body) < 2 {
+				return
+			}
+			var filters []string
+			granted := append([]byte(nil), body[:2]...)
+			for p := body[2:]; len(p) > 0; {
+				filter, rest, err := readString(p)
+				if err != nil || len(rest) < 1 {
+					return
+				}
+				filters = append(filters, filter)
+				granted = append(granted, 0)
+				p = rest[1:]
+			}
+			c.send(0x90, granted)
+			for _, filter := range filters {
+				if _, ok := c.subscriptions[filter]; !ok {
+					c.subscriptions[filter] = b.Bus.Add(filter, c.enqueue)
+				}
+			}
+		case 10: // UNSUBSCRIBE
+			if len(body) < 2 {
+				return
+			}
+			for p := body[2:]; len(p) > 0; {
+				filter, rest, err := readString(p)
+				if err != nil {
+					break
+				}
+				if cancel, ok := c.subscriptions[filter]; ok {
+					cancel()
+					delete(c.subscriptions, filter)
+				}
+				p = rest
+			}
+			c.send(0xB0, body[:2])
+		case 12: // PINGREQ
+			c.send(0xD0, nil)
+		case 14: // DISCONNECT
+			will = nil
+			return
+		}
+	}
+}
+
+func (c *conn) send(header byte, body []byte) error {
+	packet := []byte{header}
+	n := len(body)
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if n == 0 {
+			break
+		}
+	}
+	packet = append(packet, body...)
+	c.write.Lock()
+	defer c.write.Unlock()
+	c.SetWriteDeadline(time.Now().Add(WriteTimeout))
+	_, err := c.Write(packet)
+	if err != nil {
+		c.Close()
+	}
+	return err
+}
+
+// enqueue queues a message for the client, disconnecting it if its queue is
+// full.
+func (c *conn) enqueue(m bus.Message) {
+	select {
+	case c.queue <- m:
+	case <-c.done:
+	default:
+		c.Close()
+	}
+}
+
+// deliver sends the queued messages to the client until it disconnects.
+func (c *conn) deliver() {
+	for {
+		select {
+		case m := <-c.queue:
+			c.publish(m)
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *conn) publish(m bus.Message) error {
+	header := byte(0x30)
+	if m.Retained {
+		header |= 1
+	}
+	body := appendString(nil, m.Topic)
+	return c.send(header, append(body, m.Payload...))
+}
+
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7f) * multiplier
+		multiplier *= 128
+		if digit&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, nil, errors.New("broker: malformed remaining length")
+		}
+	}
+	if length > MaxPacketSize {
+		return 0, nil, errors.New("broker: packet too large")
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(r, body)
+	return header, body, err
+}
+
+func readString(p []byte) (string, []byte, error) {
+	if len(p) < 2 {
+		return "", nil, errors.New("broker: short packet")
+	}
+	n := int(binary.BigEndian.Uint16(p))
+	if len(p) < 2+n {
+		return "", nil, errors.New("broker: short packet")
+	}
+	return string(p[2 : 2+n]), p[2+n:], nil
+}
+
+func appendString(p []byte, s string) []byte {
+	p = append(p, byte(len(s)>>8), byte(len(s)))
+	return append(p, s...)
+}
+
+func parseConnect(body []byte) (Connection, error) {
+	var info Connection
+	_, p, err := readString(body) // protocol name
+	if err != nil || len(p) < 4 {
+		return info, errors.New("broker: malformed CONNECT")
+	}
+	flags := p[1]
+	info.KeepAlive = time.Duration(binary.BigEndian.Uint16(p[2:4])) * time.Second
+	p = p[4:] // level, flags, keep alive
+	info.CleanSession = flags&0x02 != 0
+	if info.ClientID, p, err = readString(p); err != nil {
+		return info, err
+	}
+	if flags&0x04 != 0 {
+		will := &bus.Message{Retained: flags&0x20 != 0}
+		var payload string
+		if will.Topic, p, err = readString(p); err != nil {
+			return info, err
+		}
+		if payload, p, err = readString(p); err != nil {
+			return info, err
+		}
+		will.Payload = []byte(payload)
+		info.Will = will
+	}
+	if flags&0x80 != 0 {
+		if info.Username, p, err = readString(p); err != nil {
+			return info, err
+		}
+	}
+	if flags&0x40 != 0 {
+		if info.Password, p, err = readString(p); err != nil {
+			return info, err
+		}
+	}
+	return info, nil
+}
+
+func parsePublish(header byte, body []byte) (bus.Message, byte, []byte, error) {
+	m := bus.Message{Retained: header&1 != 0}
+	qos := (header >> 1) & 3
+	topic, p, err := readString(body)
+	if err != nil {
+		return m, qos, nil, err
+	}
+	m.Topic = topic
+	var id []byte
+	if qos > 0 {
+		if len(p) < 2 {
+			return m, qos, nil, errors.New("broker: short packet")
+		}
+		id, p = p[:2], p[2:]
+	}
+	m.Payload = append([]byte(nil), p...)
+	return m, qos, id, nil
+}
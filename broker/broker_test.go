@@ -0,0 +1,232 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package broker
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+	"github.com/geoffholden/gowx/bus"
+)
+
+func TestBroker(t *testing.T) {
+	local := bus.NewLocal()
+	b, err := Listen("127.0.0.1:0", local, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	published := make(chan byte, 10)
+	b.OnPublish = func(m bus.Message, qos byte) {
+		if m.Topic == "test/value" {
+			published <- qos
+		}
+	}
+	connections := make(chan Connection, 10)
+	b.OnConnect = func(c Connection) {
+		connections <- c
+	}
+
+	connect := func(id string, opts *MQTT.ClientOptions) MQTT.Client {
+		client := MQTT.NewClient(opts.AddBroker("tcp://" + b.Addr().String()).SetClientID(id))
+		if token := client.Connect(); token.Wait() && token.Error() != nil {
+			t.Fatal(token.Error())
+		}
+		return client
+	}
+
+	publisher := connect("publisher", MQTT.NewClientOptions().SetUsername("gowx").SetPassword("secret").SetWill("test/status", "offline", 1, true))
+	if c := <-connections; c.ClientID != "publisher" || c.Username != "gowx" || c.Password != "secret" || c.Will == nil || c.Will.Topic != "test/status" {
+		t.Error("Unexpected connection", c)
+	}
+	if token := publisher.Publish("test/retained", 1, true, "42"); token.Wait() && token.Error() != nil {
+		t.Fatal(token.Error())
+	}
+
+	// Messages published in process reach external subscribers, and the
+	// other way around.
+	received := make(chan MQTT.Message, 10)
+	subscriber := connect("subscriber", MQTT.NewClientOptions())
+	defer subscriber.Disconnect(0)
+	if token := subscriber.Subscribe("test/#", 0, func(c MQTT.Client, m MQTT.Message) {
+		received <- m
+	}); token.Wait() && token.Error() != nil {
+		t.Fatal(token.Error())
+	}
+	expect := func(topic, payload string, retained bool) {
+		select {
+		case m := <-received:
+			if m.Topic() != topic || string(m.Payload()) != payload || m.Retained() != retained {
+				t.Error("Unexpected message", m.Topic(), string(m.Payload()), m.Retained())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Message not delivered", topic)
+		}
+	}
+	expect("test/retained", "42", true)
+	local.Publish("test/local", []byte("1"), false)
+	expect("test/local", "1", false)
+
+	if token := publisher.Publish("test/value", 2, false, "7"); token.Wait() && token.Error() != nil {
+		t.Fatal(token.Error())
+	}
+	if qos := <-published; qos != 2 {
+		t.Error("Unexpected QoS", qos)
+	}
+	expect("test/value", "7", false)
+
+	// Dropping the connection without a DISCONNECT sends the will.
+	b.mu.Lock()
+	for c := range b.conns {
+		if _, ok := c.subscriptions["test/#"]; !ok {
+			c.Close()
+		}
+	}
+	b.mu.Unlock()
+	expect("test/status", "offline", false)
+	if m, ok := local.Retained("test/status"); !ok || string(m.Payload) != "offline" {
+		t.Error("Will not retained", m)
+	}
+}
+
+func TestBrokerAuthorize(t *testing.T) {
+	b, err := Listen("127.0.0.1:0", bus.NewLocal(), func(c Connection) bool {
+		return c.Username == "gowx" && c.Password == "secret"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	for _, test := range []struct {
+		password string
+		ok       bool
+	}{{"secret", true}, {"wrong", false}} {
+		opts := MQTT.NewClientOptions().AddBroker("tcp://" + b.Addr().String()).SetUsername("gowx").SetPassword(test.password)
+		client := MQTT.NewClient(opts)
+		token := client.Connect()
+		token.Wait()
+		if (token.Error() == nil) != test.ok {
+			t.Error("Unexpected connection result", test.password, token.Error())
+		}
+		client.Disconnect(0)
+	}
+}
+
+func TestBrokerPacketSize(t *testing.T) {
+	b, err := Listen("127.0.0.1:0", bus.NewLocal(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	c, err := net.Dial("tcp", b.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	// A PUBLISH claiming to be about 256MB long is refused without reading
+	// the body.
+	c.Write([]byte{0x30, 0xff, 0xff, 0xff, 0x7f})
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.Read(make([]byte, 1)); err == nil {
+		t.Error("Connection should be closed")
+	}
+}
+
+// connectPacket is a CONNECT for an anonymous client with a keep alive.
+func connectPacket(keepAlive byte) []byte {
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, 0x02, 0, keepAlive)
+	body = appendString(body, "raw")
+	return append([]byte{0x10, byte(len(body))}, body...)
+}
+
+// closed reports whether the broker closes c within timeout, skipping
+// anything it sends first.
+func closed(c net.Conn, timeout time.Duration) bool {
+	c.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 64)
+	for {
+		if _, err := c.Read(buf); err != nil {
+			netErr, ok := err.(net.Error)
+			return !ok || !netErr.Timeout()
+		}
+	}
+}
+
+func TestBrokerConnectFirst(t *testing.T) {
+	local := bus.NewLocal()
+	b, err := Listen("127.0.0.1:0", local, func(c Connection) bool {
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	published := make(chan bus.Message, 10)
+	b.OnPublish = func(m bus.Message, qos byte) {
+		published <- m
+	}
+
+	// A PUBLISH before CONNECT skips authorization, so it's dropped along
+	// with the connection.
+	c, err := net.Dial("tcp", b.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	body := append(appendString(nil, "test/value"), '1')
+	c.Write(append([]byte{0x30, byte(len(body))}, body...))
+	if !closed(c, 5*time.Second) {
+		t.Error("Connection should be closed")
+	}
+	select {
+	case m := <-published:
+		t.Error("Unexpected message", m)
+	default:
+	}
+}
+
+func TestBrokerSecondConnect(t *testing.T) {
+	b, err := Listen("127.0.0.1:0", bus.NewLocal(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	c, err := net.Dial("tcp", b.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.Write(connectPacket(0))
+	c.Write(connectPacket(0))
+	if !closed(c, 5*time.Second) {
+		t.Error("Connection should be closed")
+	}
+}
+
+func TestBrokerKeepAlive(t *testing.T) {
+	b, err := Listen("127.0.0.1:0", bus.NewLocal(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	c, err := net.Dial("tcp", b.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	start := time.Now()
+	c.Write(connectPacket(1))
+	if !closed(c, 5*time.Second) {
+		t.Fatal("Silent client should be disconnected")
+	}
+	if d := time.Since(start); d < time.Second {
+		t.Error("Disconnected before the keep alive", d)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+// Package bus carries messages between the gowx components, either through an
+// MQTT broker or within a single process.
+package bus
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Message is a message published on a topic.
+type Message struct {
+	Topic    string
+	Payload  []byte
+	Retained bool
+}
+
+// Handler receives the messages of a subscription. Each subscription's
+// messages are delivered in order, one at a time.
+type Handler func(m Message)
+
+// Bus is a publish/subscribe transport with MQTT topic semantics.
+type Bus interface {
+	Publish(topic string, payload []byte, retained bool) error
+	// Subscribe receives the messages published on topics matching filter,
+	// which may contain the MQTT wildcards "+" and "#".
+	Subscribe(filter string, handler Handler) error
+	Close()
+}
+
+// Match reports whether a topic matches a subscription filter.
+func Match(filter string, topic string) bool {
+	f := strings.Split(filter, "/")
+	t := strings.Split(topic, "/")
+	for i, level := range f {
+		if level == "#" {
+			return true
+		}
+		if i >= len(t) || (level != "+" && level != t[i]) {
+			return false
+		}
+	}
+	return len(f) == len(t)
+}
+
+type subscription struct {
+	filter  string
+	handler Handler
+	queue   chan Message
+	done    chan struct{}
+}
+
+func (s *subscription) run() {
+	for {
+		select {
+		case m := <-s.queue:
+			s.handler(m)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *subscription) deliver(m Message) {
+	select {
+	case s.queue <- m:
+	case <-s.done:
+	}
+}
+
+// Local is an in-process bus. Retained messages are kept, and delivered to
+// new subscriptions, as a broker would.
+type Local struct {
+	mu       sync.Mutex
+	subs     map[*subscription]bool
+	retained map[string]Message
+}
+
+func NewLocal() *Local {
+	return &Local{
+		subs:     make(map[*subscription]bool),
+		retained: make(map[string]Message),
+	}
+}
+
+func (l *Local) Publish(topic string, payload []byte, retained bool) error {
+	m := Message{Topic: topic, Payload: append([]byte(nil), payload...)}
+
+	l.mu.Lock()
+	if retained {
+		if len(payload) == 0 {
+			delete(l.retained, topic)
+		} else {
+			l.retained[topic] = Message{Topic: topic, Payload: m.Payload, Retained: true}
+		}
+	}
+	var targets []*subscription
+	for s := range l.subs {
+		if Match(s.filter, topic) {
+			targets = append(targets, s)
+		}
+	}
+	l.mu.Unlock()
+
+	for _, s := range targets {
+		s.deliver(m)
+	}
+	return nil
+}
+
+func (l *Local) Subscribe(filter string, handler Handler) error {
+	l.Add(filter, handler)
+	return nil
+}
+
+// Add subscribes like Subscribe, and returns a function that cancels the
+// subscription.
+func (l *Local) Add(filter string, handler Handler) func() {
+	s := &subscription{
+		filter:  filter,
+		handler: handler,
+		queue:   make(chan Message, 64),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+
+	l.mu.Lock()
+	l.subs[s] = true
+	var retained []Message
+	for topic, m := range l.retained {
+		if Match(filter, topic) {
+			retained = append(retained, m)
+		}
+	}
+	l.mu.Unlock()
+	sort.Slice(retained, func(i, j int) bool {
+		return retained[i].Topic < retained[j].Topic
+	})
+
+	for _, m := range retained {
+		s.deliver(m)
+	}
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.subs[s] {
+			delete(l.subs, s)
+			close(s.done)
+		}
+	}
+}
+
+// Retained returns the retained message of a topic.
+func (l *Local) Retained(topic string) (Message, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m, ok := l.retained[topic]
+	return m, ok
+}
+
+// Close cancels every subscription.
+func (l *Local) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for s := range l.subs {
+		close(s.done)
+	}
+	l.subs = make(map[*subscription]bool)
+}
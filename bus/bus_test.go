@@ -0,0 +1,107 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package bus
+
+import (
+	"testing"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+func TestMatch(t *testing.T) {
+	for _, test := range []struct {
+		filter, topic string
+		match         bool
+	}{
+		{"gowx/sample", "gowx/sample", true},
+		{"gowx/+/sample", "gowx/home/sample", true},
+		{"gowx/+/sample", "gowx/sample", false},
+		{"gowx/#", "gowx/home/BMP/0/Pressure", true},
+		{"gowx/#", "gowx", true},
+		{"gowx/sample", "gowx/sample/aggregated", false},
+	} {
+		if Match(test.filter, test.topic) != test.match {
+			t.Error("Unexpected match", test.filter, test.topic)
+		}
+	}
+}
+
+func receive(t *testing.T, ch <-chan Message) Message {
+	select {
+	case m := <-ch:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("No message received")
+	}
+	return Message{}
+}
+
+func TestLocal(t *testing.T) {
+	l := NewLocal()
+	defer l.Close()
+
+	l.Publish("gowx/status/parser", []byte("online"), true)
+	l.Publish("gowx/status/server", []byte("online"), true)
+	l.Publish("gowx/status/server", nil, true)
+
+	ch := make(chan Message, 10)
+	cancel := l.Add("gowx/#", func(m Message) {
+		ch <- m
+	})
+	if m := receive(t, ch); m.Topic != "gowx/status/parser" || !m.Retained {
+		t.Error("Expected the retained status", m)
+	}
+
+	for _, payload := range []string{"1", "2", "3"} {
+		l.Publish("gowx/sample", []byte(payload), false)
+	}
+	l.Publish("other/sample", []byte("4"), false)
+	for _, payload := range []string{"1", "2", "3"} {
+		if m := receive(t, ch); string(m.Payload) != payload || m.Retained {
+			t.Error("Unexpected message", m)
+		}
+	}
+
+	cancel()
+	cancel()
+	l.Publish("gowx/sample", []byte("5"), false)
+	select {
+	case m := <-ch:
+		t.Error("Cancelled subscription received", m)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestLocalPublishFromHandler(t *testing.T) {
+	l := NewLocal()
+	defer l.Close()
+
+	done := make(chan Message, 1)
+	l.Subscribe("out", func(m Message) {
+		done <- m
+	})
+	l.Subscribe("in", func(m Message) {
+		l.Publish("out", m.Payload, false)
+	})
+	l.Publish("in", []byte("x"), false)
+	if m := receive(t, done); string(m.Payload) != "x" {
+		t.Error("Unexpected message", m)
+	}
+}
+
+func TestMQTTRoute(t *testing.T) {
+	b := NewMQTT(paho.NewClientOptions(), 1)
+	var got []string
+	b.Subscribe("gowx/+/sample", func(m Message) {
+		got = append(got, m.Topic)
+	})
+
+	// Queued messages of a persistent session arrive before the
+	// subscriptions are made.
+	b.route(Message{Topic: "gowx/home/sample"})
+	b.route(Message{Topic: "gowx/home/status"})
+	if len(got) != 1 || got[0] != "gowx/home/sample" {
+		t.Error("Unexpected messages", got)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package bus
+
+import (
+	"sync"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// MQTT is a bus through an MQTT broker. Subscriptions are made again each
+// time the client connects.
+type MQTT struct {
+	Client paho.Client
+	QoS    byte
+
+	mu   sync.Mutex
+	subs []mqttSubscription
+}
+
+type mqttSubscription struct {
+	filter  string
+	handler Handler
+}
+
+// NewMQTT creates the client from opts, wrapping its OnConnect handler. The
+// client still has to be connected.
+//
+// With a persistent session, the broker sends the messages it queued as soon
+// as the client connects, before the subscriptions are made again. These go
+// to the default publish handler, which routes them to the subscriptions.
+func NewMQTT(opts *paho.ClientOptions, qos byte) *MQTT {
+	b := &MQTT{QoS: qos}
+	opts.SetDefaultPublishHandler(func(c paho.Client, msg paho.Message) {
+		b.route(Message{Topic: msg.Topic(), Payload: msg.Payload(), Retained: msg.Retained()})
+	})
+	onConnect := opts.OnConnect
+	opts.SetOnConnectHandler(func(c paho.Client) {
+		b.mu.Lock()
+		subs := append([]mqttSubscription(nil), b.subs...)
+		b.mu.Unlock()
+		for _, s := range subs {
+			if err := b.subscribe(c, s); err != nil {
+				jww.ERROR.Println("Unable to subscribe to", s.filter, err)
+			}
+		}
+		if onConnect != nil {
+			onConnect(c)
+		}
+	})
+	b.Client = paho.NewClient(opts)
+	return b
+}
+
+func (b *MQTT) Publish(topic string, payload []byte, retained bool) error {
+	token := b.Client.Publish(topic, b.QoS, retained, payload)
+	token.Wait()
+	return token.Error()
+}
+
+func (b *MQTT) Subscribe(filter string, handler Handler) error {
+	s := mqttSubscription{filter, handler}
+	b.mu.Lock()
+	b.subs = append(b.subs, s)
+	b.mu.Unlock()
+	if b.Client.IsConnected() {
+		return b.subscribe(b.Client, s)
+	}
+	return nil
+}
+
+func (b *MQTT) subscribe(c paho.Client, s mqttSubscription) error {
+	token := c.Subscribe(s.filter, b.QoS, func(client paho.Client, msg paho.Message) {
+		s.handler(Message{Topic: msg.Topic(), Payload: msg.Payload(), Retained: msg.Retained()})
+	})
+	token.Wait()
+	return token.Error()
+}
+
+// route delivers a message that arrived outside a subscription to the
+// subscriptions it matches.
+func (b *MQTT) route(m Message) {
+	b.mu.Lock()
+	subs := append([]mqttSubscription(nil), b.subs...)
+	b.mu.Unlock()
+	for _, s := range subs {
+		if Match(s.filter, m.Topic) {
+			s.handler(m)
+		}
+	}
+}
+
+func (b *MQTT) Close() {
+	b.Client.Disconnect(250)
+}
@@ -0,0 +1,219 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package chart
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+type point struct {
+	X, Y float64
+}
+
+type anchor int
+
+const (
+	alignLeft anchor = iota
+	alignCenter
+	alignRight
+)
+
+// canvas is what charts are drawn on. Text is set in the 5x7 font scaled by
+// the canvas scale, so layouts come out the same in either format.
+type canvas interface {
+	// polyline strokes an open line of the given width.
+	polyline(pts []point, c color.RGBA, width float64)
+	// polygon fills a closed shape.
+	polygon(pts []point, c color.RGBA)
+	// text writes a line of text vertically centered on y.
+	text(x, y float64, s string, a anchor, c color.RGBA)
+	finish(w io.Writer) error
+}
+
+func newCanvas(format Format, width, height int, scale float64) (canvas, error) {
+	switch format {
+	case PNG:
+		return newRaster(width, height, scale), nil
+	case SVG:
+		return newVector(width, height, scale), nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// textWidth returns the width of a line of text at a scale.
+func textWidth(s string, scale float64) float64 {
+	return float64(len([]rune(s))) * 6 * scale
+}
+
+// vector draws SVG.
+type vector struct {
+	buf   bytes.Buffer
+	scale float64
+}
+
+func newVector(width, height int, scale float64) *vector {
+	v := &vector{scale: scale}
+	fmt.Fprintf(&v.buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height)
+	fmt.Fprintf(&v.buf, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", width, height)
+	return v
+}
+
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func svgOpacity(attr string, c color.RGBA) string {
+	if c.A == 0xff {
+		return ""
+	}
+	return fmt.Sprintf(` %s="%.2f"`, attr, float64(c.A)/0xff)
+}
+
+func svgPoints(pts []point) string {
+	s := make([]string, len(pts))
+	for i, p := range pts {
+		s[i] = fmt.Sprintf("%.1f,%.1f", p.X, p.Y)
+	}
+	return strings.Join(s, " ")
+}
+
+func (v *vector) polyline(pts []point, c color.RGBA, width float64) {
+	fmt.Fprintf(&v.buf, `<polyline points="%s" fill="none" stroke="%s"%s stroke-width="%.1f" stroke-linejoin="round"/>`+"\n",
+		svgPoints(pts), svgColor(c), svgOpacity("stroke-opacity", c), width)
+}
+
+func (v *vector) polygon(pts []point, c color.RGBA) {
+	fmt.Fprintf(&v.buf, `<polygon points="%s" fill="%s"%s/>`+"\n", svgPoints(pts), svgColor(c), svgOpacity("fill-opacity", c))
+}
+
+func (v *vector) text(x, y float64, s string, a anchor, c color.RGBA) {
+	textAnchor := map[anchor]string{alignLeft: "start", alignCenter: "middle", alignRight: "end"}[a]
+	fmt.Fprintf(&v.buf, `<text x="%.1f" y="%.1f" font-family="sans-serif" font-size="%.1f" text-anchor="%s" dominant-baseline="central" fill="%s">`,
+		x, y, 10*v.scale, textAnchor, svgColor(c))
+	xml.EscapeText(&v.buf, []byte(s))
+	v.buf.WriteString("</text>\n")
+}
+
+func (v *vector) finish(w io.Writer) error {
+	v.buf.WriteString("</svg>\n")
+	_, err := v.buf.WriteTo(w)
+	return err
+}
+
+// raster draws PNG.
+type raster struct {
+	img   *image.RGBA
+	scale float64
+}
+
+func newRaster(width, height int, scale float64) *raster {
+	r := &raster{img: image.NewRGBA(image.Rect(0, 0, width, height)), scale: scale}
+	for i := range r.img.Pix {
+		r.img.Pix[i] = 0xff
+	}
+	return r
+}
+
+// blend draws a pixel over what is already there.
+func (r *raster) blend(x, y int, c color.RGBA) {
+	if !(image.Point{x, y}.In(r.img.Rect)) {
+		return
+	}
+	i := r.img.PixOffset(x, y)
+	a := uint32(c.A)
+	for j, v := range []uint8{c.R, c.G, c.B} {
+		r.img.Pix[i+j] = uint8((uint32(v)*a + uint32(r.img.Pix[i+j])*(0xff-a)) / 0xff)
+	}
+}
+
+// polygon fills by scanline with the even-odd rule, covering each pixel
+// once so translucent shapes blend evenly.
+func (r *raster) polygon(pts []point, c color.RGBA) {
+	if len(pts) < 3 {
+		return
+	}
+	minY, maxY := pts[0].Y, pts[0].Y
+	for _, p := range pts {
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+	var xs []float64
+	for y := int(math.Floor(minY)); y <= int(math.Ceil(maxY)); y++ {
+		yc := float64(y) + 0.5
+		xs = xs[:0]
+		for i := range pts {
+			a, b := pts[i], pts[(i+1)%len(pts)]
+			if (a.Y <= yc) != (b.Y <= yc) {
+				xs = append(xs, a.X+(yc-a.Y)*(b.X-a.X)/(b.Y-a.Y))
+			}
+		}
+		sort.Float64s(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			for x := int(math.Ceil(xs[i] - 0.5)); x <= int(math.Floor(xs[i+1]-0.5)); x++ {
+				r.blend(x, y, c)
+			}
+		}
+	}
+}
+
+// polyline draws each segment as a rectangle, with a square over each
+// joint to fill the gaps. Overlaps blend twice, so lines should be opaque.
+func (r *raster) polyline(pts []point, c color.RGBA, width float64) {
+	h := math.Max(width, 1) / 2
+	for i := 0; i+1 < len(pts); i++ {
+		a, b := pts[i], pts[i+1]
+		length := math.Hypot(b.X-a.X, b.Y-a.Y)
+		if length == 0 {
+			continue
+		}
+		nx, ny := -(b.Y-a.Y)/length*h, (b.X-a.X)/length*h
+		r.polygon([]point{{a.X + nx, a.Y + ny}, {b.X + nx, b.Y + ny}, {b.X - nx, b.Y - ny}, {a.X - nx, a.Y - ny}}, c)
+	}
+	for i := 1; i+1 < len(pts); i++ {
+		p := pts[i]
+		r.polygon([]point{{p.X - h, p.Y - h}, {p.X + h, p.Y - h}, {p.X + h, p.Y + h}, {p.X - h, p.Y + h}}, c)
+	}
+}
+
+func (r *raster) text(x, y float64, s string, a anchor, c color.RGBA) {
+	scale := math.Max(1, math.Round(r.scale))
+	switch a {
+	case alignCenter:
+		x -= textWidth(s, scale) / 2
+	case alignRight:
+		x -= textWidth(s, scale)
+	}
+	left, top := int(math.Round(x)), int(math.Round(y-3.5*scale))
+	size := int(scale)
+	for i, ch := range []rune(s) {
+		glyph, ok := glyphs[ch]
+		if !ok {
+			glyph = glyphs['?']
+		}
+		for col, bits := range glyph {
+			for row := 0; row < 7; row++ {
+				if bits&(1<<uint(row)) == 0 {
+					continue
+				}
+				px, py := left+(i*6+col)*size, top+row*size
+				for dx := 0; dx < size; dx++ {
+					for dy := 0; dy < size; dy++ {
+						r.blend(px+dx, py+dy, c)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (r *raster) finish(w io.Writer) error {
+	return png.Encode(w, r.img)
+}
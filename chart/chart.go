@@ -0,0 +1,406 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+// Package chart renders line charts and wind roses as PNG or SVG images, for
+// clients that can't run the charts on the web page.
+package chart
+
+import (
+	"image/color"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Format is an image format.
+type Format string
+
+const (
+	PNG Format = "png"
+	SVG Format = "svg"
+)
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	if f == SVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Point is a point of a series. A NaN Value breaks the line.
+type Point struct {
+	Time     time.Time
+	Value    float64
+	Min, Max float64
+}
+
+// Series is a line of a chart.
+type Series struct {
+	Label  string
+	Points []Point
+}
+
+// Line is a chart of series over time. With Bands, the range between the
+// Min and Max of the points is shaded behind each line.
+type Line struct {
+	Title    string
+	Unit     string
+	Bands    bool
+	Location *time.Location
+	Series   []Series
+}
+
+// Rose is a series of a wind rose, with a value for each of 32 directions
+// clockwise from north.
+type Rose struct {
+	Label   string
+	Sectors []float64
+}
+
+// WindRose is a polar chart of values by wind direction.
+type WindRose struct {
+	Title string
+	Unit  string
+	Roses []Rose
+}
+
+// The colours of the series, as on the web page.
+var palette = []color.RGBA{
+	{0x7c, 0xb5, 0xec, 0xff},
+	{0x43, 0x43, 0x48, 0xff},
+	{0x90, 0xed, 0x7d, 0xff},
+	{0xf7, 0xa3, 0x5c, 0xff},
+	{0x80, 0x85, 0xe9, 0xff},
+	{0xf1, 0x5c, 0x80, 0xff},
+}
+
+var (
+	black = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	grey  = color.RGBA{0x66, 0x66, 0x66, 0xff}
+	grid  = color.RGBA{0xe6, 0xe6, 0xe6, 0xff}
+)
+
+func seriesColor(i int) color.RGBA {
+	return palette[i%len(palette)]
+}
+
+func translucent(c color.RGBA, alpha uint8) color.RGBA {
+	c.A = alpha
+	return c
+}
+
+// layout is the frame shared by both kinds of chart.
+type layout struct {
+	c             canvas
+	width, height float64
+	scale         float64
+	pad           float64
+	lineHeight    float64
+}
+
+func newLayout(format Format, width, height int) (*layout, error) {
+	scale := math.Max(1, math.Floor(math.Min(float64(width), float64(height))/300))
+	c, err := newCanvas(format, width, height, scale)
+	if err != nil {
+		return nil, err
+	}
+	return &layout{c: c, width: float64(width), height: float64(height), scale: scale, pad: 4 * scale, lineHeight: 7 * scale}, nil
+}
+
+// title writes the title and returns the top of the area below it.
+func (l *layout) title(title string, unit string) float64 {
+	if unit != "" {
+		title += " (" + unit + ")"
+	}
+	l.c.text(l.width/2, l.pad+l.lineHeight/2, title, alignCenter, black)
+	return 2*l.pad + l.lineHeight
+}
+
+// legend writes the labels of several series along the bottom and returns
+// the bottom of the area above it.
+func (l *layout) legend(labels []string) float64 {
+	if len(labels) < 2 {
+		return l.height - l.pad
+	}
+	y := l.height - l.pad - l.lineHeight/2
+	total := 0.0
+	for _, label := range labels {
+		total += l.lineHeight + l.pad + textWidth(label, l.scale) + 3*l.pad
+	}
+	x := math.Max(l.pad, (l.width-total)/2)
+	for i, label := range labels {
+		h := l.lineHeight / 2
+		l.c.polygon([]point{{x, y - h}, {x + 2*h, y - h}, {x + 2*h, y + h}, {x, y + h}}, seriesColor(i))
+		x += l.lineHeight + l.pad
+		l.c.text(x, y, label, alignLeft, black)
+		x += textWidth(label, l.scale) + 3*l.pad
+	}
+	return l.height - 2*l.pad - l.lineHeight
+}
+
+// niceStep returns a step of 1, 2 or 5 times a power of ten close to span
+// divided into n.
+func niceStep(span float64, n int) float64 {
+	if span <= 0 || n < 1 {
+		return 1
+	}
+	raw := span / float64(n)
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5} {
+		if raw <= m*mag {
+			return m * mag
+		}
+	}
+	return 10 * mag
+}
+
+// formatTick formats a tick value with as many decimals as the step needs.
+func formatTick(v float64, step float64) string {
+	decimals := 0
+	if step < 1 {
+		decimals = int(math.Ceil(-math.Log10(step)))
+	}
+	if v == 0 {
+		v = 0 // no "-0"
+	}
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
+
+// timeSteps are the spacings of the ticks on a time axis.
+var timeSteps = []time.Duration{
+	10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 2 * time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour,
+	24 * time.Hour, 2 * 24 * time.Hour, 7 * 24 * time.Hour, 14 * 24 * time.Hour, 28 * 24 * time.Hour,
+}
+
+// timeTicks returns the ticks between start and end, no more than n of
+// them, at round times in loc.
+func timeTicks(start, end time.Time, n int, loc *time.Location) ([]time.Time, string) {
+	step := timeSteps[len(timeSteps)-1]
+	for _, s := range timeSteps {
+		if end.Sub(start)/s <= time.Duration(n) {
+			step = s
+			break
+		}
+	}
+	format := "15:04"
+	if step >= 24*time.Hour {
+		format = "Jan 2"
+	}
+	seconds := int64(step / time.Second)
+	_, offset := start.In(loc).Zone()
+	first := (start.Unix()+int64(offset)+seconds-1)/seconds*seconds - int64(offset)
+	var ticks []time.Time
+	for t := first; t <= end.Unix(); t += seconds {
+		ticks = append(ticks, time.Unix(t, 0).In(loc))
+	}
+	return ticks, format
+}
+
+// Render draws the chart in format at a size in pixels.
+func (line *Line) Render(w io.Writer, format Format, width, height int) error {
+	l, err := newLayout(format, width, height)
+	if err != nil {
+		return err
+	}
+	loc := line.Location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	var labels []string
+	lo, hi := math.Inf(1), math.Inf(-1)
+	var start, end time.Time
+	for _, s := range line.Series {
+		labels = append(labels, s.Label)
+		for _, p := range s.Points {
+			if start.IsZero() || p.Time.Before(start) {
+				start = p.Time
+			}
+			if end.IsZero() || p.Time.After(end) {
+				end = p.Time
+			}
+			if math.IsNaN(p.Value) {
+				continue
+			}
+			lo, hi = math.Min(lo, p.Value), math.Max(hi, p.Value)
+			if line.Bands {
+				lo, hi = math.Min(lo, p.Min), math.Max(hi, p.Max)
+			}
+		}
+	}
+	if math.IsInf(lo, 0) {
+		lo, hi = 0, 1
+	} else if lo == hi {
+		lo, hi = lo-1, hi+1
+	}
+	if start.IsZero() {
+		end = time.Now()
+		start = end.Add(-24 * time.Hour)
+	} else if !end.After(start) {
+		start = end.Add(-time.Hour)
+	}
+
+	top := l.title(line.Title, line.Unit)
+	bottom := l.legend(labels) - l.lineHeight - 2*l.pad
+
+	step := niceStep(hi-lo, int(math.Max(2, (bottom-top)/(4*l.lineHeight))))
+	lo, hi = math.Floor(lo/step)*step, math.Ceil(hi/step)*step
+	var yTicks []float64
+	labelWidth := 0.0
+	for i := 0; lo+float64(i)*step <= hi+step/2; i++ {
+		v := lo + float64(i)*step
+		yTicks = append(yTicks, v)
+		labelWidth = math.Max(labelWidth, textWidth(formatTick(v, step), l.scale))
+	}
+	left, right := labelWidth+2*l.pad, l.width-3*l.pad
+	x := func(t time.Time) float64 {
+		return left + float64(t.Sub(start))/float64(end.Sub(start))*(right-left)
+	}
+	y := func(v float64) float64 {
+		return bottom - (v-lo)/(hi-lo)*(bottom-top)
+	}
+
+	for _, v := range yTicks {
+		l.c.polyline([]point{{left, y(v)}, {right, y(v)}}, grid, l.scale)
+		l.c.text(left-l.pad, y(v), formatTick(v, step), alignRight, grey)
+	}
+	ticks, timeFormat := timeTicks(start, end, int((right-left)/(textWidth("00:00", l.scale)+4*l.pad)), loc)
+	for _, t := range ticks {
+		l.c.polyline([]point{{x(t), bottom}, {x(t), bottom + l.pad}}, grey, l.scale)
+		l.c.text(x(t), bottom+2*l.pad+l.lineHeight/2, t.Format(timeFormat), alignCenter, grey)
+	}
+	l.c.polyline([]point{{left, bottom}, {right, bottom}}, grey, l.scale)
+
+	for i, s := range line.Series {
+		for _, segment := range segments(s.Points) {
+			if line.Bands && len(segment) > 1 {
+				band := make([]point, 0, 2*len(segment))
+				for _, p := range segment {
+					band = append(band, point{x(p.Time), y(p.Max)})
+				}
+				for j := len(segment) - 1; j >= 0; j-- {
+					band = append(band, point{x(segment[j].Time), y(segment[j].Min)})
+				}
+				l.c.polygon(band, translucent(seriesColor(i), 0x40))
+			}
+		}
+	}
+	for i, s := range line.Series {
+		for _, segment := range segments(s.Points) {
+			pts := make([]point, len(segment))
+			for j, p := range segment {
+				pts[j] = point{x(p.Time), y(p.Value)}
+			}
+			if len(pts) == 1 {
+				p, h := pts[0], l.scale*1.5
+				l.c.polygon([]point{{p.X - h, p.Y - h}, {p.X + h, p.Y - h}, {p.X + h, p.Y + h}, {p.X - h, p.Y + h}}, seriesColor(i))
+				continue
+			}
+			l.c.polyline(pts, seriesColor(i), 1.5*l.scale)
+		}
+	}
+	return l.c.finish(w)
+}
+
+// segments splits points into runs without breaks.
+func segments(points []Point) [][]Point {
+	var result [][]Point
+	var current []Point
+	for _, p := range points {
+		if math.IsNaN(p.Value) {
+			if len(current) > 0 {
+				result = append(result, current)
+			}
+			current = nil
+			continue
+		}
+		current = append(current, p)
+	}
+	if len(current) > 0 {
+		result = append(result, current)
+	}
+	return result
+}
+
+// Render draws the wind rose in format at a size in pixels. The roses are
+// drawn largest first, so smaller ones show in front.
+func (rose *WindRose) Render(w io.Writer, format Format, width, height int) error {
+	l, err := newLayout(format, width, height)
+	if err != nil {
+		return err
+	}
+
+	var labels []string
+	largest := 0.0
+	order := make([]int, len(rose.Roses))
+	totals := make([]float64, len(rose.Roses))
+	for i, r := range rose.Roses {
+		labels = append(labels, r.Label)
+		order[i] = i
+		for _, v := range r.Sectors {
+			largest = math.Max(largest, v)
+			totals[i] += v
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return totals[order[i]] > totals[order[j]]
+	})
+
+	top := l.title(rose.Title, rose.Unit)
+	bottom := l.legend(labels)
+	cx, cy := l.width/2, (top+bottom)/2
+	radius := math.Min(l.width, bottom-top)/2 - 2*l.lineHeight - 2*l.pad
+	if radius < l.lineHeight {
+		radius = l.lineHeight
+	}
+	step := niceStep(largest, 4)
+	outer := math.Max(step, math.Ceil(largest/step)*step)
+	at := func(r, degrees float64) point {
+		a := degrees * math.Pi / 180
+		return point{cx + r*radius/outer*math.Sin(a), cy - r*radius/outer*math.Cos(a)}
+	}
+
+	for i, name := range []string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"} {
+		degrees := float64(i) * 45
+		l.c.polyline([]point{at(0, degrees), at(outer, degrees)}, grid, l.scale)
+		p := at(outer, degrees)
+		p.X += (p.X - cx) / radius * (l.lineHeight + l.pad)
+		p.Y += (p.Y - cy) / radius * (l.lineHeight + l.pad)
+		l.c.text(p.X, p.Y, name, alignCenter, black)
+	}
+	var rings []float64
+	for i := 1; float64(i)*step <= outer+step/2; i++ {
+		rings = append(rings, float64(i)*step)
+	}
+	for _, r := range rings {
+		ring := make([]point, 65)
+		for i := range ring {
+			ring[i] = at(r, float64(i)*360/64)
+		}
+		l.c.polyline(ring, grid, l.scale)
+	}
+
+	sector := 360.0 / 32
+	for _, i := range order {
+		for dir, v := range rose.Roses[i].Sectors {
+			if v <= 0 {
+				continue
+			}
+			center := float64(dir) * sector
+			wedge := []point{at(0, 0)}
+			for k := 0; k <= 4; k++ {
+				wedge = append(wedge, at(v, center-sector/2+float64(k)*sector/4))
+			}
+			l.c.polygon(wedge, translucent(seriesColor(i), 0xc0))
+		}
+	}
+
+	for _, r := range rings {
+		p := at(r, 0)
+		l.c.text(p.X+l.pad, p.Y-l.lineHeight/2, formatTick(r, step), alignLeft, grey)
+	}
+	return l.c.finish(w)
+}
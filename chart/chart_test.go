@@ -0,0 +1,125 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package chart
+
+import (
+	"bytes"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testLine() *Line {
+	start := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	var points []Point
+	for i := 0; i < 48; i++ {
+		v := 10 + 5*math.Sin(float64(i)/8)
+		if i == 20 {
+			v = math.NaN()
+		}
+		points = append(points, Point{start.Add(time.Duration(i) * 30 * time.Minute), v, v - 1, v + 1})
+	}
+	return &Line{Title: "Temperature", Unit: "°C", Bands: true, Location: time.UTC, Series: []Series{{"Outside", points}, {"Inside", points[:10]}}}
+}
+
+func TestLinePNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testLine().Render(&buf, PNG, 800, 400); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 800 || b.Dy() != 400 {
+		t.Error("Unexpected size", b)
+	}
+	// Something other than the background was drawn.
+	drawn := false
+	for x := 0; x < 800 && !drawn; x++ {
+		for y := 0; y < 400 && !drawn; y++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			drawn = r != 0xffff || g != 0xffff || b != 0xffff
+		}
+	}
+	if !drawn {
+		t.Error("Blank image")
+	}
+}
+
+func TestLineSVG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testLine().Render(&buf, SVG, 800, 400); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	if !strings.HasPrefix(s, "<svg") || !strings.HasSuffix(s, "</svg>\n") {
+		t.Error("Unexpected SVG", s)
+	}
+	if !strings.Contains(s, ">Temperature (°C)</text>") || !strings.Contains(s, ">Outside</text>") {
+		t.Error("Missing labels", s)
+	}
+	// The gap splits the first series in two, for two lines and two bands.
+	if n := strings.Count(s, `stroke="#7cb5ec"`); n != 2 {
+		t.Error("Unexpected lines", n)
+	}
+	if n := strings.Count(s, `fill="#7cb5ec" fill-opacity`); n != 2 {
+		t.Error("Unexpected bands", n)
+	}
+}
+
+func TestWindRose(t *testing.T) {
+	sectors := make([]float64, 32)
+	sectors[0], sectors[8] = 4, 2.5
+	rose := &WindRose{Title: "Wind", Unit: "m/s", Roses: []Rose{{"Average", sectors}, {"Gusts", make([]float64, 32)}}}
+	for _, format := range []Format{PNG, SVG} {
+		var buf bytes.Buffer
+		if err := rose.Render(&buf, format, 400, 400); err != nil {
+			t.Fatal(format, err)
+		}
+		if buf.Len() == 0 {
+			t.Error("Empty image", format)
+		}
+	}
+	var buf bytes.Buffer
+	rose.Render(&buf, SVG, 400, 400)
+	if n := strings.Count(buf.String(), "<polygon"); n != 2+2 {
+		t.Error("Unexpected wedges", n)
+	}
+	if err := rose.Render(&buf, Format("gif"), 400, 400); err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&Line{Title: "Nothing"}).Render(&buf, PNG, 100, 50); err != nil {
+		t.Error(err)
+	}
+	if err := (&WindRose{Title: "Calm"}).Render(&buf, SVG, 100, 100); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNiceStep(t *testing.T) {
+	for _, c := range []struct {
+		span float64
+		n    int
+		step float64
+	}{
+		{10, 5, 2},
+		{7, 5, 2},
+		{30, 4, 10},
+		{0.3, 5, 0.1},
+		{0, 5, 1},
+	} {
+		if step := niceStep(c.span, c.n); math.Abs(step-c.step) > 1e-9 {
+			t.Error("Unexpected step", c, step)
+		}
+	}
+	if s := formatTick(0.30000000000000004, 0.1); s != "0.3" {
+		t.Error("Unexpected tick", s)
+	}
+}
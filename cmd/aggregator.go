@@ -5,19 +5,22 @@ package cmd
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
 	"math"
-	"os"
 	"regexp"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/spf13/cobra"
 	jww "github.com/spf13/jwalterweatherman"
 	"github.com/spf13/viper"
 
-	MQTT "github.com/eclipse/paho.mqtt.golang"
-
+	"github.com/geoffholden/gowx/bus"
 	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/homeassistant"
+	"github.com/geoffholden/gowx/records"
+	"github.com/geoffholden/gowx/sinks"
+	"github.com/geoffholden/gowx/webhook"
 )
 
 type aggdata struct {
@@ -49,76 +52,171 @@ func init() {
 }
 
 type mapKey struct {
+	Station string `json:",omitempty"`
 	ID      string
 	Channel int
 	Serial  string
 	Key     string
 }
 
+func (k mapKey) sensor() data.SensorKey {
+	return data.SensorKey{ID: k.ID, Channel: k.Channel, Serial: k.Serial}
+}
+
 func aggregator(cmd *cobra.Command, args []string) {
 	if verbose {
 		jww.SetStdoutThreshold(jww.LevelTrace)
 	}
+	serveMetrics()
 	db, err := data.OpenDatabase()
 	if err != nil {
 		jww.FATAL.Println(err)
 		panic(err)
 	}
 
+	outputs, err := sinks.Open()
+	if err != nil {
+		jww.FATAL.Println(err)
+		panic(err)
+	}
+
+	hooks, err := webhook.Open()
+	if err != nil {
+		jww.FATAL.Println(err)
+		panic(err)
+	}
+
+	tracker := newRecordTracker(db)
+	tracker.OnChange = func(c records.Change) {
+		hooks.Send(webhookRecord(db.Station(c.Station).Registry(), c))
+	}
+	// The aggregator follows the sensors of every station it gets samples
+	// from.
+	monitor, err := newHealthMonitor(db.Station(data.AllStations))
+	if err != nil {
+		jww.FATAL.Println(err)
+		panic(err)
+	}
+
 	dataChannel := make(chan data.SensorData)
 
-	topic := "/gowx/sample"
-	hostname, err := os.Hostname()
+	topic := subscribeTopics().samples()
+	// The discovery configs are published again on every connection, but
+	// discovery needs the bus, so it's set once the bus is open.
+	var discoveryMu sync.Mutex
+	var discovery *homeassistant.Discovery
+	b, err := openBus("aggregator", func() {
+		discoveryMu.Lock()
+		defer discoveryMu.Unlock()
+		if discovery != nil {
+			go republishDiscovery(discovery)
+		}
+	})
 	if err != nil {
-		hostname = "unknown"
-	}
-	clientid := fmt.Sprintf("gowx-aggregator-%s-%d", hostname, os.Getpid())
-	opts := MQTT.NewClientOptions().AddBroker(viper.GetString("broker")).SetClientID(clientid).SetCleanSession(true)
-
-	opts.OnConnect = func(c MQTT.Client) {
-		if token := c.Subscribe(topic, 0, func(client MQTT.Client, msg MQTT.Message) {
-			r := bytes.NewReader(msg.Payload())
-			decoder := json.NewDecoder(r)
-			var data data.SensorData
-			err := decoder.Decode(&data)
-			if err != nil {
-				jww.ERROR.Println(err)
-				return
-			}
-			dataChannel <- data
-		}); token.Wait() && token.Error() != nil {
-			jww.FATAL.Println(token.Error())
-			panic(token.Error())
+		jww.FATAL.Println(err)
+		panic(err)
+	}
+	defer closeBus(b, "aggregator")
+	if err := b.Subscribe(topic, func(msg bus.Message) {
+		r := bytes.NewReader(msg.Payload)
+		decoder := json.NewDecoder(r)
+		var data data.SensorData
+		err := decoder.Decode(&data)
+		if err != nil {
+			jww.ERROR.Println(err)
+			return
 		}
+		dataChannel <- data
+	}); err != nil {
+		jww.FATAL.Println(err)
+		panic(err)
+	}
+	discoveryMu.Lock()
+	discovery = newDiscovery(b)
+	discoveryMu.Unlock()
+	if discovery != nil {
+		subscribeDiscovery(b, discovery)
 	}
 
-	opts.OnConnectionLost = func(c MQTT.Client, e error) {
-		jww.ERROR.Println("MQTT Connection Lost", e)
-		if token := c.Connect(); token.Wait() && token.Error() != nil {
-			jww.FATAL.Println(token.Error())
-			panic(token.Error())
+	interval := time.Duration(viper.GetInt("interval")) * time.Second
+	ticker := time.NewTicker(interval)
+	// The last-seen table is saved often, for "gowx status" and the server.
+	healthTicker := time.NewTicker(30 * time.Second)
+	// The bus is reconnected if no data arrives for a while. The timer is
+	// only reset by data, not by the tickers.
+	const idleTimeout = 5 * time.Minute
+	idle := time.NewTimer(idleTimeout)
+
+	process := func(res []aggdata) {
+		if discovery != nil {
+			discoverAggregates(discovery, db, res)
+		}
+		publishData(res, db, b)
+		outputs.Write(sinkPoints(res))
+		for _, e := range webhookAggregates(db, res) {
+			hooks.Send(e)
+		}
+		if err := tracker.Update(recordAggregates(db, res)); err != nil {
+			jww.ERROR.Println(err)
 		}
 	}
 
-	opts.AutoReconnect = false
-
-	client := MQTT.NewClient(opts)
-	connect(client)
-	defer client.Disconnect(0)
-
-	ticker := time.NewTicker(time.Duration(viper.GetInt("interval")) * time.Second)
+	// Samples from before the current interval, e.g. replayed from the
+	// parser's queue, are aggregated into intervals of their own. stored
+	// is the time of the last row stored for each series.
+	start := time.Now()
+	late := make(map[int64]map[mapKey][]float64)
+	stored := make(map[mapKey]int64)
 
 	thedata := make(map[mapKey][]float64)
 	for {
 		select {
-		case <-ticker.C:
-			res := sumData(&thedata, db)
-			publishData(res, db, client)
+		case now := <-ticker.C:
+			for _, res := range sumIntervals(&thedata, late, stored, now.UTC().Unix()) {
+				process(res)
+			}
+			late = make(map[int64]map[mapKey][]float64)
+			start = now
+		case now := <-healthTicker.C:
+			for _, s := range monitor.Check(now) {
+				jww.WARN.Printf("No data from %s/%d/%s since %s\n", s.ID, s.Channel, s.Serial, s.LastSeen)
+				hooks.Send(webhookStatus(db.Station(s.Station).Registry(), s.Station, s.SensorKey, "offline", s.LastSeen, now))
+			}
+			if err := monitor.Save(db.SaveSensorStatus); err != nil {
+				jww.ERROR.Println(err)
+			}
 		case d := <-dataChannel:
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(idleTimeout)
+			if d.ID != "" {
+				if name, ok := db.Station(d.Station).Registry().Observe(d.Key()); ok {
+					jww.DEBUG.Printf("Sample from %s\n", name)
+				}
+				seen := d.TimeStamp
+				if seen.IsZero() {
+					seen = time.Now()
+				}
+				gap, back := monitor.Observe(d.Station, d.Key(), seen)
+				if gap != nil {
+					if err := db.InsertGap(*gap); err != nil {
+						jww.ERROR.Println(err)
+					}
+				}
+				if back {
+					hooks.Send(webhookStatus(db.Station(d.Station).Registry(), d.Station, d.Key(), "online", seen, time.Now()))
+				}
+			}
+			if !d.TimeStamp.IsZero() && d.TimeStamp.Before(start.Add(-lateGrace)) {
+				addLate(late, d, start, interval)
+				continue
+			}
 			addData(&thedata, d)
-		case <-time.After(5 * time.Minute):
+		case <-idle.C:
 			jww.ERROR.Println("No data in 5 minutes, reconnecting")
-			connect(client)
+			reconnect(b)
+			idle.Reset(idleTimeout)
 		}
 	}
 }
@@ -130,6 +228,7 @@ func addData(thedata *map[mapKey][]float64, d data.SensorData) {
 	for k, v := range d.Data {
 		jww.DEBUG.Printf("\t\t%s -> %f\n", k, v)
 		key := mapKey{
+			Station: d.Station,
 			ID:      d.ID,
 			Channel: d.Channel,
 			Serial:  d.Serial,
@@ -139,11 +238,9 @@ func addData(thedata *map[mapKey][]float64, d data.SensorData) {
 	}
 }
 
-func sumData(thedata *map[mapKey][]float64, db *data.Database) []aggdata {
+func sumData(thedata *map[mapKey][]float64, timestamp int64) []aggdata {
 	direction := regexp.MustCompile(`Dir$`)
 
-	timestamp := time.Now().UTC().Unix()
-
 	result := make([]aggdata, len(*thedata))
 	index := 0
 
@@ -171,24 +268,110 @@ func sumData(thedata *map[mapKey][]float64, db *data.Database) []aggdata {
 	return result
 }
 
-func publishData(data []aggdata, db *data.Database, client MQTT.Client) {
+// lateGrace is how late a sample may be and still count in the current
+// interval, allowing for delivery delays and clock differences.
+const lateGrace = 30 * time.Second
+
+// intervalEnd returns the end of the interval t falls in, with intervals
+// aligned to the Unix epoch.
+func intervalEnd(t time.Time, interval time.Duration) int64 {
+	seconds := int64(interval / time.Second)
+	if seconds <= 0 {
+		return t.Unix()
+	}
+	return t.Unix() - t.Unix()%seconds + seconds
+}
+
+// addLate adds a sample from before the current interval, which started at
+// start, to the late interval it falls in. Ticks aren't aligned to the
+// intervals, so the interval just closed ends at start.
+func addLate(late map[int64]map[mapKey][]float64, d data.SensorData, start time.Time, interval time.Duration) {
+	end := intervalEnd(d.TimeStamp, interval)
+	if end > start.Unix() {
+		end = start.Unix()
+	}
+	if late[end] == nil {
+		late[end] = make(map[mapKey][]float64)
+	}
+	bucket := late[end]
+	addData(&bucket, d)
+}
+
+// sumIntervals aggregates the late intervals, then the current one ending at
+// now, in order, so that cumulative keys only ever move forward. The late
+// values of a series with a row stored at or after the end of their
+// interval are dropped, as they'd be stored out of order, or twice at the
+// same time. stored is updated with the rows returned.
+func sumIntervals(current *map[mapKey][]float64, late map[int64]map[mapKey][]float64, stored map[mapKey]int64, now int64) [][]aggdata {
+	var result [][]aggdata
+	for _, end := range lateIntervals(late) {
+		bucket := late[end]
+		for key := range bucket {
+			if last, ok := stored[key]; ok && last >= end {
+				jww.WARN.Printf("Dropping late %s samples of %s/%d/%s from before %s\n", key.Key, key.ID, key.Channel, key.Serial, time.Unix(last, 0))
+				delete(bucket, key)
+			}
+		}
+		if len(bucket) > 0 {
+			result = append(result, sumData(&bucket, end))
+		}
+	}
+	result = append(result, sumData(current, now))
+	for _, res := range result {
+		for _, d := range res {
+			if d.Timestamp > stored[d.Key] {
+				stored[d.Key] = d.Timestamp
+			}
+		}
+	}
+	return result
+}
+
+// lateIntervals returns the ends of the late intervals in order.
+func lateIntervals(late map[int64]map[mapKey][]float64) []int64 {
+	ends := make([]int64, 0, len(late))
+	for end := range late {
+		ends = append(ends, end)
+	}
+	sort.Slice(ends, func(i, j int) bool { return ends[i] < ends[j] })
+	return ends
+}
+
+func publishData(data []aggdata, db *data.Database, b bus.Bus) {
 	for _, d := range data {
 		// publish the data to the database
-		err := db.InsertRow(d.Timestamp, d.Key.ID, d.Key.Channel, d.Key.Serial, d.Key.Key, d.Min, d.Max, d.Avg)
+		err := db.Station(d.Key.Station).InsertRow(d.Timestamp, d.Key.ID, d.Key.Channel, d.Key.Serial, d.Key.Key, d.Min, d.Max, d.Avg)
 		if err != nil {
 			jww.ERROR.Printf("%s\n", err.Error())
 		}
 
-		// publish the data to the broker
-		topic := "/gowx/sample/aggregated"
-		buf := new(bytes.Buffer)
-		encoder := json.NewEncoder(buf)
-		encoder.Encode(d)
-		payload := buf.Bytes()
-		if token := client.Publish(topic, 0, false, payload); token.Wait() && token.Error() != nil {
-			jww.ERROR.Println("Failed to send message.", token.Error())
+		// publish the data on the bus, for the station of the samples when
+		// samples from several stations are aggregated
+		topics := stationTopics()
+		if d.Key.Station != "" {
+			topics = newTopics(topicLevel(d.Key.Station, ""))
+		}
+		publishJSON(b, topics.aggregated(), d)
+		publishValue(b, topics.aggregatedValue(d.Key.sensor(), d.Key.Key), d.Avg)
+	}
+}
+
+func sinkPoints(data []aggdata) []sinks.Point {
+	points := make([]sinks.Point, len(data))
+	for i, d := range data {
+		points[i] = sinks.Point{
+			Timestamp: d.Timestamp,
+			ID:        d.Key.ID,
+			Channel:   d.Key.Channel,
+			Serial:    d.Key.Serial,
+			Key:       d.Key.Key,
+			Min:       d.Min,
+			Max:       d.Max,
+			Avg:       d.Avg,
+			Station:   d.Key.Station,
 		}
 	}
+	return points
 }
 
 func minimum(d []float64) float64 {
@@ -0,0 +1,90 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/geoffholden/gowx/data"
+)
+
+func TestSumIntervalsLate(t *testing.T) {
+	viper.Set("dbDriver", "sqlite3")
+	viper.Set("database", filepath.Join(t.TempDir(), "gowx.db"))
+	db, err := data.OpenDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	defer viper.Set("timezone", viper.Get("timezone"))
+	viper.Set("timezone", "UTC")
+	tracker := newRecordTracker(db)
+
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	rain := func(at time.Time, total float64) data.SensorData {
+		return data.SensorData{TimeStamp: at, ID: "PCR800", Data: map[string]float64{"RainTotal": total}}
+	}
+	stored := make(map[mapKey]int64)
+	tick := func(now time.Time, current []data.SensorData, late []data.SensorData) {
+		thedata := make(map[mapKey][]float64)
+		for _, d := range current {
+			addData(&thedata, d)
+		}
+		buckets := make(map[int64]map[mapKey][]float64)
+		for _, d := range late {
+			end := intervalEnd(d.TimeStamp, 5*time.Minute)
+			if buckets[end] == nil {
+				buckets[end] = make(map[mapKey][]float64)
+			}
+			bucket := buckets[end]
+			addData(&bucket, d)
+		}
+		for _, res := range sumIntervals(&thedata, buckets, stored, now.Unix()) {
+			if err := tracker.Update(recordAggregates(db, res)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tick(start, []data.SensorData{rain(start, 100)}, nil)
+	// The samples of the previous interval arrive late, e.g. replayed from
+	// the parser's queue.
+	tick(start.Add(10*time.Minute), []data.SensorData{rain(start.Add(9*time.Minute), 103)}, []data.SensorData{rain(start.Add(4*time.Minute), 101)})
+	tick(start.Add(15*time.Minute), []data.SensorData{rain(start.Add(14*time.Minute), 104)}, nil)
+
+	s, found, err := db.DailySummary("2026-10-19", "", "", data.SensorKey{ID: "PCR800"}, "RainTotal")
+	if err != nil || !found || s.Total != 4 {
+		t.Error("Unexpected daily total", s.Total, found, err)
+	}
+}
+
+func TestSumIntervalsJustClosed(t *testing.T) {
+	// Ticks aren't aligned to the intervals.
+	start := time.Date(2026, 10, 19, 12, 2, 30, 0, time.UTC)
+	rain := func(at time.Time, channel int, total float64) data.SensorData {
+		return data.SensorData{TimeStamp: at, ID: "PCR800", Channel: channel, Data: map[string]float64{"RainTotal": total}}
+	}
+	stored := make(map[mapKey]int64)
+	thedata := make(map[mapKey][]float64)
+	addData(&thedata, rain(start.Add(-time.Minute), 0, 100))
+	sumIntervals(&thedata, nil, stored, start.Unix())
+
+	// Samples just past the grace period belong to the interval just
+	// closed. The sensor stored at the tick has its sample dropped, rather
+	// than stored again at the same time.
+	late := make(map[int64]map[mapKey][]float64)
+	justLate := start.Add(-lateGrace - time.Second)
+	addLate(late, rain(justLate, 0, 99), start, 5*time.Minute)
+	addLate(late, rain(justLate, 1, 50), start, 5*time.Minute)
+	res := sumIntervals(&thedata, late, stored, start.Add(5*time.Minute).Unix())
+	if len(res) != 2 || len(res[0]) != 1 || len(res[1]) != 0 {
+		t.Fatal("Unexpected intervals", res)
+	}
+	if d := res[0][0]; d.Key.Channel != 1 || d.Timestamp != start.Unix() || d.Avg != 50 {
+		t.Error("Unexpected late row", d)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+
+	"github.com/geoffholden/gowx/alert"
+	"github.com/geoffholden/gowx/bus"
+	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/notify"
+)
+
+// alertsCmd represents the alerts command
+var alertsCmd = &cobra.Command{
+	Use:   "alerts",
+	Short: "Evaluate alert rules",
+	Long: `Evaluates the rules in the "alerts.rules" section over the samples and
+aggregates, and publishes the alerts as they fire and clear. The alerts are
+also sent to the notification channels, see "gowx help notify". Rules select
+sensors like "current_data", e.g.
+
+alerts:
+  rules:
+    greenhouse_frost:
+      sensor: greenhouse
+      type: Temperature
+      when: "< 0"
+      clear: 1
+      for: 10m
+    gusts:
+      id: VN1:6D27
+      type: CurrentWind[max]
+      when: "> 19.4"
+    pressure_drop:
+      type: Pressure
+      source: aggregate
+      when: "drop > 3 in 3h"
+      message: Pressure is dropping fast
+
+A condition compares the value, or with "drop", "rise" or "change" its
+change over a time window. Once firing, an alert clears when the value
+crosses back over "clear", or the condition's level without it. With "for",
+the condition has to hold that long before the alert fires. Rules check the
+samples, unless "source" is aggregate or the type selects the min, max or avg
+of the aggregates.
+
+The state of the alerts is kept in the "alerts.state" file, so that alerts
+aren't repeated after a restart.`,
+	Run: alertsRun,
+}
+
+var alertsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the state of the alert rules",
+	Run:   alertsList,
+}
+
+func init() {
+	RootCmd.AddCommand(alertsCmd)
+	alertsCmd.AddCommand(alertsListCmd)
+
+	viper.SetDefault("alerts.state", "gowx-alerts.json")
+}
+
+// alertRules creates the rules of the "alerts.rules" section.
+func alertRules(reg *data.Registry) ([]*alert.Rule, error) {
+	var rules []*alert.Rule
+	for name := range viper.GetStringMap("alerts.rules") {
+		config := viper.Sub("alerts.rules." + name)
+		if config == nil {
+			continue
+		}
+		r, err := alert.NewRule(name, config)
+		if err != nil {
+			return nil, fmt.Errorf("alert %s: %s", name, err.Error())
+		}
+		query := viper.GetStringMapString("alerts.rules." + name)
+		r.Match = func(key data.SensorKey) bool {
+			return sensorMatch(query, key, reg)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func openAlertEngine(db *data.Database) *alert.Engine {
+	rules, err := alertRules(db.Registry())
+	if err != nil {
+		fatal(err)
+	}
+	engine, err := alert.NewEngine(rules, viper.GetString("alerts.state"))
+	if err != nil {
+		fatal(err)
+	}
+	engine.Name = func(station string, key data.SensorKey) (string, bool) {
+		return db.Station(station).Registry().Name(key)
+	}
+	return engine
+}
+
+func alertsRun(cmd *cobra.Command, args []string) {
+	if verbose {
+		jww.SetStdoutThreshold(jww.LevelTrace)
+	}
+	serveMetrics()
+	db := openSensorDatabase()
+	engine := openAlertEngine(db)
+	notifiers, err := notify.Open(nil)
+	if err != nil {
+		fatal(err)
+	}
+	go dailySummaries(db, notifiers)
+
+	b, err := openBus("alerts", nil)
+	if err != nil {
+		jww.FATAL.Println(err)
+		panic(err)
+	}
+	defer closeBus(b, "alerts")
+
+	topic := stationTopics().alerts()
+	engine.OnAlert = func(a alert.Alert) {
+		state := "cleared"
+		if a.Firing {
+			state = "firing"
+		}
+		jww.WARN.Printf("Alert %s %s: %s (%g)\n", a.Rule, state, a.Message, a.Value)
+		publishJSON(b, topic, a)
+		notifiers.Send(notify.Event{Type: notify.Alert, Time: a.Time, Station: a.Station, Alert: &a})
+	}
+
+	if err := b.Subscribe(subscribeTopics().samples(), func(msg bus.Message) {
+		var d data.SensorData
+		if err := json.NewDecoder(bytes.NewReader(msg.Payload)).Decode(&d); err != nil {
+			jww.ERROR.Println(err)
+			return
+		}
+		engine.Sample(d)
+	}); err != nil {
+		jww.FATAL.Println(err)
+		panic(err)
+	}
+	if err := b.Subscribe(subscribeTopics().aggregated(), func(msg bus.Message) {
+		var d aggdata
+		if err := json.NewDecoder(bytes.NewReader(msg.Payload)).Decode(&d); err != nil {
+			jww.ERROR.Println(err)
+			return
+		}
+		engine.Aggregate(time.Unix(d.Timestamp, 0), d.Key.Station, d.Key.sensor(), d.Key.Key, d.Min, d.Max, d.Avg)
+	}); err != nil {
+		jww.FATAL.Println(err)
+		panic(err)
+	}
+
+	// Transitions are saved straight away, the rest of the state (the
+	// values and change histories) every minute.
+	for range time.Tick(time.Minute) {
+		if err := engine.Save(); err != nil {
+			jww.ERROR.Println(err)
+		}
+	}
+}
+
+func alertsList(cmd *cobra.Command, args []string) {
+	db := openSensorDatabase()
+	defer db.Close()
+	engine := openAlertEngine(db)
+
+	loc := stationLocation()
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "RULE\tSENSOR\tSTATE\tSINCE\tVALUE\tUPDATED")
+	for _, s := range engine.States() {
+		state, since := "ok", ""
+		if s.Firing {
+			state, since = "firing", s.Since.In(loc).Format("2006-01-02 15:04")
+		} else if !s.Pending.IsZero() {
+			state, since = "pending", s.Pending.In(loc).Format("2006-01-02 15:04")
+		}
+		sensor := data.SensorID{ID: s.Sensor.ID, Channel: s.Sensor.Channel, Serial: s.Sensor.Serial, Station: s.Station}.String()
+		if name, ok := db.Station(s.Station).Registry().Name(s.Sensor); ok {
+			sensor = name
+			if s.Station != "" {
+				sensor += "@" + s.Station
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%g\t%s\n", s.Rule, sensor, state, since, s.Value, s.Updated.In(loc).Format("2006-01-02 15:04"))
+	}
+	w.Flush()
+}
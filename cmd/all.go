@@ -10,14 +10,16 @@ import (
 // allCmd represents the all command
 var allCmd = &cobra.Command{
 	Use:   "all",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+	Short: "Run the parser, aggregator and server together",
+	Long: `Runs the parser, aggregator and server in a single process. By default
+the components are connected by an in-process bus, so no MQTT broker is
+needed; use --transport mqtt to go through the broker instead. With
+--listen, an embedded MQTT broker lets external clients subscribe to the
+bus too.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if viper.GetString("transport") == "" {
+			viper.Set("transport", "local")
+		}
 
 		go parserCmd.Run(parserCmd, args)
 		go aggregatorCmd.Run(aggregatorCmd, args)
@@ -38,6 +40,8 @@ func init() {
 	// is called directly, e.g.:
 	// allCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 
+	allCmd.Flags().String("listen", "", "Address of the embedded MQTT broker, e.g. :1883 (default is no broker), with the clients logging in as mqtt.username")
+
 	parserInit()
 	allCmd.Flags().AddFlagSet(parserCmd.Flags())
 
@@ -0,0 +1,488 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	jww "github.com/spf13/jwalterweatherman"
+
+	"github.com/geoffholden/gowx/data"
+)
+
+// The /api/v1 resources. Every resource takes the sensor selection of a
+// chart query as the "sensor", "id" and "channel" parameters, and a time
+// range as "start" and "end" in RFC 3339, by default the 24 hours up to now.
+// Values are converted to the configured units unless "units" is "raw".
+//
+//	/api/v1/stations         the stations
+//	/api/v1/stations/NAME    a station, and the resources below for just its
+//	                         samples, in its units, as /api/v1/stations/NAME/series
+//	/api/v1/sensors          the logical and physical sensors
+//	/api/v1/sensors/NAME     a logical sensor
+//	/api/v1/series           the samples of a "key", combined into intervals
+//	                         of "resolution" and picking the "aggregate" column
+//	/api/v1/windrose         the "aggregate" column of a "key" by wind direction
+//	/api/v1/delta            the change in the average of a "key"
+//	/api/v1/openapi.json     the OpenAPI description of the above
+//
+// Errors are returned with the matching HTTP status and an apiError body.
+
+// apiError is the body of an API error response.
+type apiError struct {
+	Status int
+	Error  string
+}
+
+var errNotFound = errors.New("not found")
+
+// apiRange is the time range of an API request.
+type apiRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+type apiStation struct {
+	Name      string
+	Latitude  float64
+	Longitude float64
+	Elevation float64
+	Timezone  string
+	Units     map[string]string
+}
+
+type apiSensors struct {
+	Sensors  []data.Sensor
+	Physical []data.SensorID
+}
+
+type apiPoint struct {
+	Time     time.Time
+	Value    float64
+	Min, Max float64
+}
+
+type apiSeries struct {
+	apiRange
+	Key        string
+	Unit       string
+	Aggregate  string
+	Resolution int64 // seconds, 0 for the raw samples
+	Points     []apiPoint
+}
+
+type apiWindRose struct {
+	apiRange
+	Key       string
+	Unit      string
+	Aggregate string
+	Sectors   []float64 // 32, clockwise from north
+}
+
+type apiDelta struct {
+	apiRange
+	Key    string
+	Unit   string
+	First  float64
+	Last   float64
+	Change float64
+}
+
+// apiHandler returns the handler for the /api/v1/ routes, for the samples
+// of the station of db and, below /api/v1/stations/, of the others.
+func apiHandler(db *data.Database) http.Handler {
+	return apiRoutes(db, true)
+}
+
+// apiRoutes returns the handler for the routes of the station of db, with
+// the routes of the stations if stations is set.
+func apiRoutes(db *data.Database, stations bool) http.Handler {
+	mux := http.NewServeMux()
+	if stations {
+		mux.HandleFunc("/api/v1/stations", func(w http.ResponseWriter, r *http.Request) {
+			apiServe(w, r, func() (interface{}, error) { return apiStationList(db) })
+		})
+		mux.HandleFunc("/api/v1/stations/", func(w http.ResponseWriter, r *http.Request) {
+			apiStationRoute(w, r, db)
+		})
+	}
+	mux.HandleFunc("/api/v1/sensors", func(w http.ResponseWriter, r *http.Request) {
+		apiServe(w, r, func() (interface{}, error) { return apiSensorList(db) })
+	})
+	mux.HandleFunc("/api/v1/sensors/", func(w http.ResponseWriter, r *http.Request) {
+		apiServe(w, r, func() (interface{}, error) {
+			s, ok := db.Registry().Lookup(strings.TrimPrefix(r.URL.Path, "/api/v1/sensors/"))
+			if !ok {
+				return nil, errNotFound
+			}
+			return s, nil
+		})
+	})
+	mux.HandleFunc("/api/v1/series", func(w http.ResponseWriter, r *http.Request) {
+		apiServe(w, r, func() (interface{}, error) { return apiSeriesQuery(r, db) })
+	})
+	mux.HandleFunc("/api/v1/windrose", func(w http.ResponseWriter, r *http.Request) {
+		apiServe(w, r, func() (interface{}, error) { return apiWindRoseQuery(r, db) })
+	})
+	mux.HandleFunc("/api/v1/delta", func(w http.ResponseWriter, r *http.Request) {
+		apiServe(w, r, func() (interface{}, error) { return apiDeltaQuery(r, db) })
+	})
+	mux.HandleFunc("/api/v1/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(openAPISpec))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		apiFail(w, http.StatusNotFound, errNotFound)
+	})
+	return mux
+}
+
+// apiStationRoute serves /api/v1/stations/NAME, and the other routes for
+// the station below it.
+func apiStationRoute(w http.ResponseWriter, r *http.Request, db *data.Database) {
+	name, route := strings.TrimPrefix(r.URL.Path, "/api/v1/stations/"), ""
+	if i := strings.Index(name, "/"); i >= 0 {
+		name, route = name[:i], name[i+1:]
+	}
+	station, found, err := lookupStation(db, name)
+	if err != nil {
+		apiFail(w, http.StatusInternalServerError, err)
+		return
+	} else if !found {
+		apiFail(w, http.StatusNotFound, errNotFound)
+		return
+	}
+	if route == "" {
+		apiServe(w, r, func() (interface{}, error) { return newAPIStation(station), nil })
+		return
+	}
+	scoped := r.Clone(r.Context())
+	scoped.URL.Path = "/api/v1/" + route
+	apiRoutes(db.Station(station), false).ServeHTTP(w, scoped)
+}
+
+func newAPIStation(name string) apiStation {
+	return apiStation{
+		Name:      name,
+		Latitude:  settingFloat(stationSetting(name, "latitude")),
+		Longitude: settingFloat(stationSetting(name, "longitude")),
+		Elevation: settingFloat(stationSetting(name, "elevation")),
+		Timezone:  stationLocationOf(name).String(),
+		Units:     stationUnits(name),
+	}
+}
+
+func apiStationList(db *data.Database) ([]apiStation, error) {
+	names, err := stationNames(db)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]apiStation, len(names))
+	for i, name := range names {
+		result[i] = newAPIStation(name)
+	}
+	return result, nil
+}
+
+// badRequest is an error in the parameters of an API request.
+type badRequest struct {
+	error
+}
+
+func badRequestf(format string, args ...interface{}) error {
+	return badRequest{fmt.Errorf(format, args...)}
+}
+
+// apiServe writes the result of a GET request as JSON, or its error.
+func apiServe(w http.ResponseWriter, r *http.Request, get func() (interface{}, error)) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		apiFail(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	result, err := get()
+	if err == errNotFound {
+		apiFail(w, http.StatusNotFound, err)
+		return
+	} else if _, ok := err.(badRequest); ok {
+		apiFail(w, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		apiFail(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func apiFail(w http.ResponseWriter, status int, err error) {
+	if status == http.StatusInternalServerError {
+		jww.ERROR.Println(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiError{status, err.Error()})
+}
+
+func apiSensorList(db *data.Database) (apiSensors, error) {
+	var result apiSensors
+	var err error
+	if result.Sensors, err = db.Sensors(); err != nil {
+		return result, err
+	}
+	if result.Physical, err = db.PhysicalSensors(); err != nil {
+		return result, err
+	}
+	if result.Sensors == nil {
+		result.Sensors = []data.Sensor{}
+	}
+	if result.Physical == nil {
+		result.Physical = []data.SensorID{}
+	}
+	return result, nil
+}
+
+// parseRange returns the time range of a request, the day up to now by
+// default.
+func parseRange(r *http.Request, now time.Time) (apiRange, error) {
+	result := apiRange{End: now.UTC()}
+	if end := r.FormValue("end"); end != "" {
+		t, err := time.Parse(time.RFC3339, end)
+		if err != nil {
+			return result, badRequestf("invalid end: %v", err)
+		}
+		result.End = t.UTC()
+	}
+	result.Start = result.End.Add(-24 * time.Hour)
+	if start := r.FormValue("start"); start != "" {
+		t, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return result, badRequestf("invalid start: %v", err)
+		}
+		result.Start = t.UTC()
+	}
+	if !result.Start.Before(result.End) {
+		return result, badRequestf("start must be before end")
+	}
+	return result, nil
+}
+
+// parseResolution returns the interval, in seconds, to combine samples into
+// over a range. "auto" or nothing picks it the way the charts do, and "raw"
+// returns the samples as they were stored.
+func parseResolution(resolution string, span apiRange) (int64, error) {
+	switch resolution {
+	case "", "auto":
+		return autoInterval(int64(span.End.Sub(span.Start) / time.Second)), nil
+	case "raw":
+		return 1, nil
+	}
+	d, err := time.ParseDuration(resolution)
+	if err != nil || d < time.Second {
+		return 0, badRequestf("invalid resolution %q", resolution)
+	}
+	return int64(d / time.Second), nil
+}
+
+func parseAggregate(aggregate string) (string, error) {
+	switch aggregate {
+	case "":
+		return "avg", nil
+	case "avg", "min", "max":
+		return aggregate, nil
+	}
+	return "", badRequestf("invalid aggregate %q", aggregate)
+}
+
+// apiQuery returns the key and sensors selected by a request.
+func apiQuery(r *http.Request, db *data.Database) (string, []data.SensorID, error) {
+	key := r.FormValue("key")
+	if key == "" {
+		return "", nil, badRequestf("missing key")
+	}
+	query := make(map[string]string)
+	for _, param := range []string{"sensor", "id", "channel"} {
+		if v := r.FormValue(param); v != "" {
+			query[param] = v
+		}
+	}
+	if channel, ok := query["channel"]; ok {
+		if _, err := strconv.Atoi(channel); err != nil {
+			return "", nil, badRequestf("invalid channel %q", channel)
+		}
+	}
+	if name, ok := query["sensor"]; ok {
+		if _, found := db.Registry().Lookup(name); !found {
+			return "", nil, errNotFound
+		}
+	}
+	return key, querySensors(query, db.Registry()), nil
+}
+
+// apiUnits returns the units to convert the values of a request for a
+// station to.
+func apiUnits(r *http.Request, station string) (map[string]string, error) {
+	switch r.FormValue("units") {
+	case "":
+		return stationUnits(station), nil
+	case "raw":
+		return nil, nil
+	}
+	return nil, badRequestf("invalid units %q", r.FormValue("units"))
+}
+
+func apiSeriesQuery(r *http.Request, db *data.Database) (apiSeries, error) {
+	var result apiSeries
+	var err error
+	if result.apiRange, err = parseRange(r, time.Now()); err != nil {
+		return result, err
+	}
+	interval, err := parseResolution(r.FormValue("resolution"), result.apiRange)
+	if err != nil {
+		return result, err
+	}
+	if interval > 1 {
+		result.Resolution = interval
+	}
+	if result.Aggregate, err = parseAggregate(r.FormValue("aggregate")); err != nil {
+		return result, err
+	}
+	unitmap, err := apiUnits(r, db.StationName())
+	if err != nil {
+		return result, err
+	}
+	key, ids, err := apiQuery(r, db)
+	if err != nil {
+		return result, err
+	}
+	result.Key = key
+	_, result.Unit = convertValueTo(unitmap, key, 0)
+
+	rows, err := querySeries(db, result.Start.Unix(), result.End.Unix(), key, ids, interval)
+	if err != nil {
+		return result, err
+	}
+	col := []string{"", result.Aggregate}
+	result.Points = make([]apiPoint, len(rows))
+	for i, row := range rows {
+		p := apiPoint{Time: time.Unix(row.Timestamp, 0).UTC()}
+		p.Value, _ = convertValueTo(unitmap, key, rowValue(col, row))
+		p.Min, _ = convertValueTo(unitmap, key, row.Min)
+		p.Max, _ = convertValueTo(unitmap, key, row.Max)
+		result.Points[i] = p
+	}
+	return result, nil
+}
+
+// queryWindRose returns the column of a key in each of the 32 wind
+// directions. With several sensors, the sectors take the highest maximum,
+// the lowest minimum, or the mean of the averages.
+func queryWindRose(db *data.Database, start int64, end int64, key string, col string, ids []data.SensorID) ([]float64, error) {
+	result := make([]float64, 32)
+	count := make([]int, 32)
+	for _, id := range ids {
+		rows, err := db.WindRose(start, end, key, col, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			dir := int(row.Dir)
+			if dir < 0 || dir >= len(result) {
+				continue
+			}
+			switch {
+			case count[dir] == 0:
+				result[dir] = row.Value
+			case col == "max" && row.Value > result[dir]:
+				result[dir] = row.Value
+			case col == "min" && row.Value < result[dir]:
+				result[dir] = row.Value
+			case col == "avg":
+				result[dir] = (result[dir]*float64(count[dir]) + row.Value) / float64(count[dir]+1)
+			}
+			count[dir]++
+		}
+	}
+	return result, nil
+}
+
+func apiWindRoseQuery(r *http.Request, db *data.Database) (apiWindRose, error) {
+	var result apiWindRose
+	var err error
+	if result.apiRange, err = parseRange(r, time.Now()); err != nil {
+		return result, err
+	}
+	if result.Aggregate, err = parseAggregate(r.FormValue("aggregate")); err != nil {
+		return result, err
+	}
+	unitmap, err := apiUnits(r, db.StationName())
+	if err != nil {
+		return result, err
+	}
+	key, ids, err := apiQuery(r, db)
+	if err != nil {
+		return result, err
+	}
+	result.Key = key
+	_, result.Unit = convertValueTo(unitmap, key, 0)
+
+	result.Sectors, err = queryWindRose(db, result.Start.Unix(), result.End.Unix(), key, result.Aggregate, ids)
+	if err != nil {
+		return result, err
+	}
+	for i, v := range result.Sectors {
+		result.Sectors[i], _ = convertValueTo(unitmap, key, v)
+	}
+	return result, nil
+}
+
+func apiDeltaQuery(r *http.Request, db *data.Database) (apiDelta, error) {
+	var result apiDelta
+	var err error
+	if result.apiRange, err = parseRange(r, time.Now()); err != nil {
+		return result, err
+	}
+	unitmap, err := apiUnits(r, db.StationName())
+	if err != nil {
+		return result, err
+	}
+	key, ids, err := apiQuery(r, db)
+	if err != nil {
+		return result, err
+	}
+	result.Key = key
+	_, result.Unit = convertValueTo(unitmap, key, 0)
+
+	var first, last float64
+	if len(ids) == 1 {
+		var found bool
+		first, last, found, err = db.Change(result.Start.Unix(), result.End.Unix(), key, ids[0])
+		if err != nil {
+			return result, err
+		} else if !found {
+			return result, errNotFound
+		}
+	} else {
+		// The samples of several sensors have to be merged to find the
+		// first and last.
+		rows, err := querySeries(db, result.Start.Unix(), result.End.Unix(), key, ids, 1)
+		if err != nil {
+			return result, err
+		} else if len(rows) == 0 {
+			return result, errNotFound
+		}
+		first, last = rows[0].Avg, rows[len(rows)-1].Avg
+	}
+	// Converting the ends rather than the difference keeps offsets, as
+	// between Celsius and Fahrenheit, out of the change.
+	result.First, _ = convertValueTo(unitmap, key, first)
+	result.Last, _ = convertValueTo(unitmap, key, last)
+	result.Change = result.Last - result.First
+	return result, nil
+}
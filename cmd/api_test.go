@@ -0,0 +1,91 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/geoffholden/gowx/data"
+)
+
+func TestAPI(t *testing.T) {
+	viper.Set("dbDriver", "sqlite3")
+	viper.Set("database", filepath.Join(t.TempDir(), "gowx.db"))
+	db, err := data.OpenDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	defer viper.Set("units", viper.Get("units"))
+	viper.Set("units", map[string]string{"temperature": "F"})
+
+	start := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	for i, temp := range []float64{10, 15, 20} {
+		ts := start.Add(time.Duration(i+1) * time.Hour).Unix()
+		db.InsertRow(ts, "OS3:1D20", 1, "", "Temperature", temp-1, temp+1, temp)
+		db.InsertRow(ts, "THGR810", 2, "", "Temperature", 0, 0, 0)
+	}
+	if err := db.AliasSensor("outside", data.SensorID{ID: "OS3:1D20", Channel: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(url string, v interface{}) int {
+		w := httptest.NewRecorder()
+		apiHandler(db).ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		if w.Header().Get("Content-Type") != "application/json" {
+			t.Error("Unexpected content type", url, w.Header())
+		}
+		if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+			t.Error(url, err)
+		}
+		return w.Code
+	}
+
+	var series apiSeries
+	if code := get("/api/v1/series?key=Temperature&sensor=outside&start=2026-10-19T00:00:00Z&end=2026-10-19T02:00:00Z&aggregate=max", &series); code != http.StatusOK {
+		t.Fatal("Unexpected status", code)
+	}
+	if series.Unit != "F" || series.Resolution != 0 || len(series.Points) != 2 {
+		t.Fatal("Unexpected series", series)
+	}
+	if p := series.Points[1]; p.Value != 60.8 || p.Min != 57.2 || !p.Time.Equal(start.Add(2*time.Hour)) {
+		t.Error("Unexpected point", p)
+	}
+
+	var delta apiDelta
+	if code := get("/api/v1/delta?key=Temperature&id=OS3:1D20&start=2026-10-19T00:00:00Z&end=2026-10-19T03:00:00Z&units=raw", &delta); code != http.StatusOK {
+		t.Fatal("Unexpected status", code)
+	}
+	if delta.Unit != "C" || delta.First != 10 || delta.Last != 20 || delta.Change != 10 {
+		t.Error("Unexpected delta", delta)
+	}
+
+	var sensors apiSensors
+	get("/api/v1/sensors", &sensors)
+	if len(sensors.Sensors) != 1 || len(sensors.Physical) != 2 {
+		t.Error("Unexpected sensors", sensors)
+	}
+
+	for url, status := range map[string]int{
+		"/api/v1/series?key=Temperature&start=yesterday":                                     http.StatusBadRequest,
+		"/api/v1/series?key=Temperature&start=2026-10-19T02:00:00Z&end=2026-10-19T01:00:00Z": http.StatusBadRequest,
+		"/api/v1/series?key=Temperature&resolution=fortnightly":                              http.StatusBadRequest,
+		"/api/v1/series": http.StatusBadRequest,
+		"/api/v1/series?key=Temperature&sensor=attic":                                       http.StatusNotFound,
+		"/api/v1/delta?key=Temperature&start=2020-01-01T00:00:00Z&end=2020-01-02T00:00:00Z": http.StatusNotFound,
+		"/api/v1/sensors/attic": http.StatusNotFound,
+		"/api/v1/nothing":       http.StatusNotFound,
+	} {
+		var e apiError
+		if code := get(url, &e); code != status || e.Status != status || e.Error == "" {
+			t.Error("Unexpected error", url, code, e)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+
+	"github.com/geoffholden/gowx/web"
+)
+
+// overlayFS serves the files of upper, falling back to those of lower.
+type overlayFS struct {
+	upper, lower fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.upper.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.lower.Open(name)
+	}
+	return f, err
+}
+
+// ReadDir merges the entries of both, so globs see every file.
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries := make(map[string]fs.DirEntry)
+	found := false
+	for _, fsys := range []fs.FS{o.lower, o.upper} {
+		list, err := fs.ReadDir(fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, e := range list {
+			entries[e.Name()] = e
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	result := make([]fs.DirEntry, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, e)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name() < result[j].Name()
+	})
+	return result, nil
+}
+
+// webAssets returns the files of the web page: the built-in ones, with
+// those in "webroot" in their place.
+func webAssets() fs.FS {
+	root := viper.GetString("webroot")
+	if root == "" {
+		return web.Assets
+	}
+	if _, err := os.Stat(root); err != nil {
+		jww.WARN.Println("Using the built-in web assets:", err)
+		return web.Assets
+	}
+	return overlayFS{os.DirFS(root), web.Assets}
+}
+
+// templateSet is the parsed templates of the web page. With reload set,
+// they're parsed again whenever a template file changes, otherwise once.
+type templateSet struct {
+	fsys   fs.FS
+	reload bool
+
+	mu       sync.Mutex
+	tmpl     *template.Template
+	modified time.Time
+}
+
+func newTemplateSet(fsys fs.FS, reload bool) (*templateSet, error) {
+	t := &templateSet{fsys: fsys, reload: reload}
+	_, err := t.get()
+	return t, err
+}
+
+// latest returns the time the newest template file was modified.
+func (t *templateSet) latest() (time.Time, error) {
+	names, err := fs.Glob(t.fsys, "*.html")
+	if err != nil {
+		return time.Time{}, err
+	}
+	var latest time.Time
+	for _, name := range names {
+		info, err := fs.Stat(t.fsys, name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (t *templateSet) get() (*template.Template, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tmpl != nil && !t.reload {
+		return t.tmpl, nil
+	}
+	modified, err := t.latest()
+	if err != nil {
+		return nil, err
+	}
+	if t.tmpl != nil && !modified.After(t.modified) {
+		return t.tmpl, nil
+	}
+	tmpl, err := template.ParseFS(t.fsys, "*.html")
+	if err != nil {
+		return nil, err
+	}
+	t.tmpl, t.modified = tmpl, modified
+	return tmpl, nil
+}
+
+// bufferedResponse holds a response so it can be given an ETag and
+// compressed once it's complete.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+// compressible reports whether a type of content is worth compressing.
+func compressible(contentType string) bool {
+	for _, prefix := range []string{"text/", "application/json", "application/javascript", "image/svg+xml"} {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheable gives the complete responses of h an ETag, answers conditional
+// requests that match it with 304 Not Modified, and compresses the rest
+// with gzip when the client accepts it. It's for small responses: streams
+// like /events can't be buffered.
+func cacheable(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A range of the uncompressed body would be wrong in a compressed
+		// response, so the whole body is always sent.
+		r.Header.Del("Range")
+		res := &bufferedResponse{header: w.Header()}
+		h.ServeHTTP(res, r)
+		if res.status == 0 {
+			res.status = http.StatusOK
+		}
+		body := res.body.Bytes()
+		if res.status != http.StatusOK || r.Method != http.MethodGet {
+			w.WriteHeader(res.status)
+			w.Write(body)
+			return
+		}
+
+		header := w.Header()
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", http.DetectContentType(body))
+		}
+		etag := header.Get("Etag")
+		if etag == "" {
+			sum := sha256.Sum256(body)
+			// Weak, as the body is the same whether or not it's compressed.
+			etag = `W/"` + hex.EncodeToString(sum[:8]) + `"`
+			header.Set("Etag", etag)
+		}
+		header.Add("Vary", "Accept-Encoding")
+		for _, match := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+			match = strings.TrimSpace(match)
+			if match == "*" || strings.TrimPrefix(match, "W/") == strings.TrimPrefix(etag, "W/") {
+				header.Del("Content-Type")
+				header.Del("Content-Length")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+
+		if len(body) < 512 || !compressible(header.Get("Content-Type")) || !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.WriteHeader(res.status)
+			w.Write(body)
+			return
+		}
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.WriteHeader(res.status)
+		gz := gzip.NewWriter(w)
+		gz.Write(body)
+		gz.Close()
+	})
+}
@@ -0,0 +1,117 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/geoffholden/gowx/web"
+)
+
+func TestOverlayFS(t *testing.T) {
+	upper := fstest.MapFS{"index.html": {Data: []byte("mine")}, "extra.html": {Data: []byte("extra")}}
+	o := overlayFS{upper, web.Assets}
+	if b, err := fs.ReadFile(o, "index.html"); err != nil || string(b) != "mine" {
+		t.Error("Unexpected index", string(b), err)
+	}
+	if _, err := fs.ReadFile(o, "gowx.css"); err != nil {
+		t.Error(err)
+	}
+	names, err := fs.Glob(o, "*.html")
+	if err != nil || strings.Join(names, ",") != "extra.html,index.html" {
+		t.Error("Unexpected templates", names, err)
+	}
+	if _, err := fs.ReadFile(o, "missing.css"); err == nil {
+		t.Error("Expected an error")
+	}
+	// The built-in page parses.
+	if _, err := newTemplateSet(web.Assets, false); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServeTemplate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":  {Data: []byte(`{{.Units.temperature}}`), ModTime: time.Unix(1, 0)},
+		"broken.html": {Data: []byte(`{{.Missing.field}}`), ModTime: time.Unix(1, 0)},
+		"gowx.css":    {Data: []byte(`body {}`)},
+	}
+	templates, err := newTemplateSet(fsys, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := templateData{Units: map[string]string{"temperature": "C"}}
+	handler := cacheable(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveTemplate(w, r, http.FileServer(http.FS(fsys)), templates, d)
+	}))
+	get := func(url string, header ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", url, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("/")
+	if w.Code != http.StatusOK || w.Body.String() != "C" {
+		t.Fatal("Unexpected page", w.Code, w.Body.String())
+	}
+	if w := get("/", "If-None-Match", w.Header().Get("Etag")); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Error("Unexpected conditional response", w.Code, w.Body.String())
+	}
+	if w := get("/gowx.css"); w.Code != http.StatusOK || w.Body.String() != "body {}" || w.Header().Get("Etag") == "" {
+		t.Error("Unexpected static file", w.Code, w.Body.String(), w.Header())
+	}
+	if w := get("/broken.html"); w.Code != http.StatusInternalServerError {
+		t.Error("Unexpected status", w.Code)
+	}
+
+	// Changed templates are picked up.
+	fsys["index.html"] = &fstest.MapFile{Data: []byte(`{{.Units.temperature}}!`), ModTime: time.Unix(2, 0)}
+	if w := get("/"); w.Body.String() != "C!" {
+		t.Error("Template wasn't reloaded", w.Body.String())
+	}
+	// Until they don't parse, which is an error rather than a crash.
+	fsys["index.html"] = &fstest.MapFile{Data: []byte(`{{`), ModTime: time.Unix(3, 0)}
+	if w := get("/"); w.Code != http.StatusInternalServerError {
+		t.Error("Unexpected status", w.Code)
+	}
+}
+
+func TestCacheableGzip(t *testing.T) {
+	body := strings.Repeat(`{"Temperature":21.5}`, 100)
+	handler := cacheable(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	}))
+	r := httptest.NewRequest("GET", "/data.json", nil)
+	r.Header.Set("Accept-Encoding", "gzip, deflate")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatal("Unexpected headers", w.Header())
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(gz); string(b) != body {
+		t.Error("Unexpected body", string(b))
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/data.json", nil))
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != body {
+		t.Error("Compressed without being asked", w.Header())
+	}
+}
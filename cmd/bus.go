@@ -0,0 +1,113 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/geoffholden/gowx/broker"
+	"github.com/geoffholden/gowx/bus"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+)
+
+// local is the bus shared by the components running in this process with
+// the local transport.
+var local struct {
+	sync.Once
+	bus *bus.Local
+	err error
+}
+
+// localBus returns the shared local bus, starting the embedded broker on the
+// "listen" address if one is set. Clients of the broker must log in with the
+// "mqtt.username" and "mqtt.password" if they're set.
+func localBus() (*bus.Local, error) {
+	local.Do(func() {
+		local.bus = bus.NewLocal()
+		if address := viper.GetString("listen"); address != "" {
+			authorize := brokerAuthorize(viper.GetString("mqtt.username"), viper.GetString("mqtt.password"))
+			if authorize == nil {
+				jww.WARN.Println("MQTT broker accepts clients without a username, set mqtt.username and mqtt.password")
+			}
+			b, err := broker.Listen(address, local.bus, authorize)
+			if err != nil {
+				local.err = err
+				return
+			}
+			jww.INFO.Println("MQTT broker listening on", b.Addr())
+		}
+	})
+	return local.bus, local.err
+}
+
+// brokerAuthorize returns the check of the embedded broker's clients, or
+// nil to accept any client if no username is set.
+func brokerAuthorize(username string, password string) func(broker.Connection) bool {
+	if username == "" {
+		return nil
+	}
+	return func(c broker.Connection) bool {
+		user := subtle.ConstantTimeCompare([]byte(c.Username), []byte(username))
+		pass := subtle.ConstantTimeCompare([]byte(c.Password), []byte(password))
+		return user&pass == 1
+	}
+}
+
+// openBus opens the bus of a component using the "transport" setting, either
+// "mqtt" for an external broker or "local" for the components running in
+// this process. onConnect is called whenever the bus is (re)connected.
+func openBus(component string, onConnect func()) (bus.Bus, error) {
+	switch transport := viper.GetString("transport"); transport {
+	case "", "mqtt":
+		b, err := newMQTTBus(component, onConnect)
+		if err != nil {
+			return nil, err
+		}
+		connect(b.Client)
+		return b, nil
+	case "local":
+		b, err := localBus()
+		if err != nil {
+			return nil, err
+		}
+		if err := b.Publish(stationTopics().status(component), []byte("online"), true); err != nil {
+			return nil, err
+		}
+		if onConnect != nil {
+			onConnect()
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("unknown transport %q", transport)
+	}
+}
+
+// closeBus marks the component offline and closes its bus. The local bus is
+// shared with the other components, so it stays open.
+func closeBus(b bus.Bus, component string) {
+	done := make(chan error, 1)
+	go func() {
+		done <- b.Publish(stationTopics().status(component), []byte("offline"), true)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			jww.ERROR.Println(err)
+		}
+	case <-time.After(time.Second):
+	}
+	if _, ok := b.(*bus.Local); !ok {
+		b.Close()
+	}
+}
+
+// reconnect reconnects a bus through an external broker.
+func reconnect(b bus.Bus) {
+	if m, ok := b.(*bus.MQTT); ok {
+		connect(m.Client)
+	}
+}
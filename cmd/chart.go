@@ -0,0 +1,243 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jww "github.com/spf13/jwalterweatherman"
+
+	"github.com/geoffholden/gowx/chart"
+	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/units"
+)
+
+// chartTitles are the titles of the charts of the web page, by the name of
+// their queries in the configuration.
+var chartTitles = map[string]string{
+	"temperature": "Temperature",
+	"pressure":    "Pressure",
+	"humidity":    "Humidity",
+	"wind":        "Wind Speed",
+	"rain":        "Rainfall",
+}
+
+// chartCacheTime is how long rendered charts are kept.
+const chartCacheTime = time.Minute
+
+// maxChartSize is the largest width or height of a chart, in pixels, and
+// maxChartRenders the number of charts rendered at once.
+const (
+	maxChartSize    = 2000
+	maxChartRenders = 4
+)
+
+type cachedChart struct {
+	body    []byte
+	expires time.Time
+}
+
+// chartCache keeps recently rendered charts by their parameters, and limits
+// how many are rendered at once.
+type chartCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]cachedChart
+	renders chan struct{}
+}
+
+func newChartCache(max int) *chartCache {
+	return &chartCache{max: max, entries: make(map[string]cachedChart), renders: make(chan struct{}, maxChartRenders)}
+}
+
+// chartKey returns the cache key of a chart, so that requests for the same
+// chart share it whatever the order or spelling of their parameters.
+func chartKey(station, name string, format chart.Format, span int64, bands bool, width, height int) string {
+	return fmt.Sprintf("%s/%s.%s?time=%d&range=%t&width=%d&height=%d", strings.ToLower(station), name, format, span, bands, width, height)
+}
+
+func (c *chartCache) get(key string, now time.Time) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || now.After(e.expires) {
+		return nil, false
+	}
+	return e.body, true
+}
+
+func (c *chartCache) put(key string, body []byte, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.max {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	// Still full of fresh charts, make room for this one anyway.
+	for k := range c.entries {
+		if len(c.entries) < c.max {
+			break
+		}
+		delete(c.entries, k)
+	}
+	c.entries[key] = cachedChart{body, now.Add(chartCacheTime)}
+}
+
+// chartUnit returns the unit charts of a type of /data.json are shown in,
+// with the units of unitmap.
+func chartUnit(unitmap map[string]string, unitType string) string {
+	unit := func(unit, base string, get func(string) (float64, error)) string {
+		if _, err := get(unit); err == nil {
+			return unit
+		}
+		return base
+	}
+	switch unitType {
+	case "temperature":
+		t := units.NewTemperatureCelsius(0)
+		return "°" + unit(unitmap["temperature"], "C", t.Get)
+	case "pressure":
+		p := units.NewPressureHpa(0)
+		return unit(unitmap["pressure"], "hPa", p.Get)
+	case "humidity":
+		return "%"
+	case "wind":
+		s := units.NewSpeedMetersPerSecond(0)
+		return unit(unitmap["windspeed"], "m/s", s.Get)
+	case "rain":
+		d := units.NewDistanceMillimeters(0)
+		return unit(unitmap["rain"], "mm", d.Get)
+	}
+	return ""
+}
+
+// chartHandler serves the charts of the web page as images, at
+// /chart/NAME.png or /chart/NAME.svg. NAME is one of the chart queries in
+// the configuration, or "windrose" for the wind queries by direction, for
+// the station of db. The "time" parameter is as for /data.json, "width" and
+// "height" are in pixels, up to maxChartSize, and "range=false" leaves out
+// the minimum to maximum bands.
+func chartHandler(w http.ResponseWriter, r *http.Request, db *data.Database, cache *chartCache) {
+	base := path.Base(r.URL.Path)
+	ext := path.Ext(base)
+	name, format := strings.TrimSuffix(base, ext), chart.Format(strings.TrimPrefix(ext, "."))
+	if format != chart.PNG && format != chart.SVG {
+		http.NotFound(w, r)
+		return
+	}
+	if _, ok := chartTitles[name]; !ok && name != "windrose" {
+		http.NotFound(w, r)
+		return
+	}
+
+	width, err := chartSize(r.FormValue("width"), 800)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defaultHeight := width / 2
+	if name == "windrose" {
+		defaultHeight = width
+	}
+	height, err := chartSize(r.FormValue("height"), defaultHeight)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	bands := true
+	if v := r.FormValue("range"); v != "" {
+		if bands, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	now := time.Now()
+	key := chartKey(db.StationName(), name, format, timeSpan(r.FormValue("time")), bands, width, height)
+	body, ok := cache.get(key, now)
+	if !ok {
+		select {
+		case cache.renders <- struct{}{}:
+			defer func() { <-cache.renders }()
+		case <-r.Context().Done():
+			return
+		}
+		// Another request may have rendered the chart while this one waited.
+		body, ok = cache.get(key, now)
+	}
+	if !ok {
+		var buf bytes.Buffer
+		if name == "windrose" {
+			err = renderWindRose(&buf, db, r.FormValue("time"), format, width, height)
+		} else {
+			err = renderChart(&buf, db, name, r.FormValue("time"), bands, format, width, height)
+		}
+		if err != nil {
+			jww.ERROR.Println(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		body = buf.Bytes()
+		cache.put(key, body, now)
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(chartCacheTime/time.Second)))
+	w.Write(body)
+}
+
+func chartSize(value string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 16 || size > maxChartSize {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return size, nil
+}
+
+func renderChart(buf *bytes.Buffer, db *data.Database, name string, timestr string, bands bool, format chart.Format, width, height int) error {
+	start, interval := computeTime(timestr)
+	labels, series, err := chartSeries(db, convertToStringMap(name), name, start, interval)
+	if err != nil {
+		return err
+	}
+	station := db.StationName()
+	line := chart.Line{Title: chartTitles[name], Unit: chartUnit(stationUnits(station), name), Bands: bands, Location: stationLocationOf(station)}
+	for i, points := range series {
+		s := chart.Series{Label: labels[i]}
+		for _, p := range points {
+			value := p.Value
+			if p.Gap {
+				value = math.NaN()
+			}
+			s.Points = append(s.Points, chart.Point{Time: time.Unix(p.Timestamp, 0), Value: value, Min: p.Min, Max: p.Max})
+		}
+		line.Series = append(line.Series, s)
+	}
+	return line.Render(buf, format, width, height)
+}
+
+func renderWindRose(buf *bytes.Buffer, db *data.Database, timestr string, format chart.Format, width, height int) error {
+	start, _ := computeTime(timestr)
+	labels, series, err := windSeries(db, convertToStringMap("wind"), start)
+	if err != nil {
+		return err
+	}
+	rose := chart.WindRose{Title: "Wind Direction", Unit: chartUnit(stationUnits(db.StationName()), "wind")}
+	for i, sectors := range series {
+		rose.Roses = append(rose.Roses, chart.Rose{Label: labels[i], Sectors: sectors})
+	}
+	return rose.Render(buf, format, width, height)
+}
@@ -0,0 +1,78 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/geoffholden/gowx/data"
+)
+
+func TestChartHandler(t *testing.T) {
+	viper.Set("dbDriver", "sqlite3")
+	viper.Set("database", filepath.Join(t.TempDir(), "gowx.db"))
+	db, err := data.OpenDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	for i, temp := range []float64{10, 12, 11} {
+		db.InsertRow(now.Add(time.Duration(i-3)*time.Hour).Unix(), "OS3:1D20", 1, "", "Temperature", temp-1, temp+1, temp)
+	}
+
+	cache := newChartCache(2)
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		chartHandler(w, httptest.NewRequest("GET", url, nil), db, cache)
+		return w
+	}
+
+	w := get("/chart/temperature.svg?time=48h&width=600")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/svg+xml" {
+		t.Fatal("Unexpected response", w.Code, w.Header())
+	}
+	if body := w.Body.String(); !strings.Contains(body, `width="600" height="300"`) || !strings.Contains(body, "Temperature (°C)") {
+		t.Error("Unexpected chart", body)
+	}
+
+	// The cached chart is served until it expires, even with new data.
+	db.InsertRow(now.Unix(), "OS3:1D20", 1, "", "Temperature", 40, 40, 40)
+	if again := get("/chart/temperature.svg?time=48h&width=600"); again.Body.String() != w.Body.String() {
+		t.Error("Chart wasn't cached")
+	}
+	// So is the same chart asked for differently.
+	if again := get("/chart/temperature.svg?width=600&height=300&time=48h&station="); again.Body.String() != w.Body.String() {
+		t.Error("Chart wasn't cached", len(cache.entries))
+	}
+	if _, ok := cache.get(chartKey("", "temperature", "svg", 48*60*60, true, 600, 300), now.Add(2*chartCacheTime)); ok {
+		t.Error("Chart didn't expire")
+	}
+
+	if w := get("/chart/windrose.png"); w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+		t.Error("Unexpected response", w.Code, w.Header())
+	}
+	if len(cache.entries) > 2 {
+		t.Error("Cache grew past its size", len(cache.entries))
+	}
+
+	for url, status := range map[string]int{
+		"/chart/attic.png":                 http.StatusNotFound,
+		"/chart/temperature.gif":           http.StatusNotFound,
+		"/chart/temperature.png?width=big": http.StatusBadRequest,
+		"/chart/wind.png?height=100000":    http.StatusBadRequest,
+		"/chart/rain.png?range=maybe":      http.StatusBadRequest,
+	} {
+		if w := get(url); w.Code != status {
+			t.Error("Unexpected status", url, w.Code)
+		}
+	}
+}
@@ -0,0 +1,520 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+
+	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/units"
+	"github.com/geoffholden/gowx/upload"
+)
+
+// The Cumulus realtime.txt and Weather Display clientraw.txt files are made
+// from the "current_data" selections below. Without selections of their own,
+// the gusts and rain total are taken from the sensors of "wind" and "rain".
+var compatNames = []string{
+	"temperature", "humidity", "pressure", "wind", "gust", "winddir",
+	"rain", "raintotal", "indoortemperature", "indoorhumidity",
+}
+
+// compatSeries is the key of a sensor a selection was made from.
+type compatSeries struct {
+	data.SensorKey
+	Key string
+}
+
+type compatExtreme struct {
+	Value float64
+	Time  time.Time
+}
+
+// compatData is the current values and today's extremes of the selections,
+// in °C, %, hPa, m/s and mm.
+type compatData struct {
+	Time     time.Time
+	Location *time.Location
+	Current  map[string]float64
+	High     map[string]compatExtreme
+	Low      map[string]compatExtreme
+	Trend    map[string]float64 // change per hour over the last three hours
+
+	RainToday     float64
+	RainYesterday float64
+	RainMonth     float64
+	RainYear      float64
+	RainHour      float64
+	WindRun       float64 // km
+}
+
+// newCompatData returns the data of the station of db, with the days
+// starting at midnight in its time zone.
+func newCompatData(db *data.Database, latest *stationLatest, now time.Time) (compatData, error) {
+	station := db.StationName()
+	loc := stationLocationOf(station)
+	d := compatData{
+		Time:     now,
+		Location: loc,
+		Current:  make(map[string]float64),
+		High:     make(map[string]compatExtreme),
+		Low:      make(map[string]compatExtreme),
+		Trend:    make(map[string]float64),
+	}
+
+	// The values are kept in the units they were measured in.
+	c := newCurrentConditions(latest.get(station), db.Registry(), nil, now)
+	series := make(map[string]compatSeries)
+	for _, name := range compatNames {
+		if h, ok := c.Headline[name]; ok {
+			d.Current[name] = h.Value
+			series[name] = compatSeries{h.Sensor, h.Key}
+		}
+	}
+	fallback := func(name string, from string, key string) {
+		s, ok := series[from]
+		if _, done := series[name]; done || !ok {
+			return
+		}
+		for _, sensor := range c.Sensors {
+			if v, ok := sensor.Values[key]; ok && sensor.SensorKey == s.SensorKey {
+				d.Current[name] = v.Value
+				series[name] = compatSeries{s.SensorKey, key}
+			}
+		}
+	}
+	fallback("gust", "wind", "CurrentWind")
+	fallback("raintotal", "rain", "RainTotal")
+
+	local := now.In(loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	today := midnight.Format("2006-01-02")
+	summaries, err := db.DailySummaries(today, today, "")
+	if err != nil {
+		return d, err
+	}
+	for _, s := range summaries {
+		for name, sr := range series {
+			if s.SensorKey != sr.SensorKey || s.Key != sr.Key || s.MeanCount == 0 {
+				continue
+			}
+			d.High[name] = compatExtreme{s.High, time.Unix(s.HighTime, 0)}
+			d.Low[name] = compatExtreme{s.Low, time.Unix(s.LowTime, 0)}
+			switch name {
+			case "wind":
+				d.WindRun = s.Mean() * 3.6 * now.Sub(midnight).Hours()
+			case "raintotal":
+				d.RainToday = s.Total
+			}
+		}
+	}
+
+	if sr, ok := series["raintotal"]; ok {
+		yesterday := midnight.AddDate(0, 0, -1).Format("2006-01-02")
+		first := midnight.Format("2006") + "-01-01"
+		if yesterday < first {
+			first = yesterday
+		}
+		summaries, err := db.DailySummaries(first, today, sr.Key)
+		if err != nil {
+			return d, err
+		}
+		for _, s := range summaries {
+			if s.SensorKey != sr.SensorKey {
+				continue
+			}
+			if s.Day == yesterday {
+				d.RainYesterday = s.Total
+			}
+			if strings.HasPrefix(s.Day, today[:8]) {
+				d.RainMonth += s.Total
+			}
+			if strings.HasPrefix(s.Day, today[:5]) {
+				d.RainYear += s.Total
+			}
+		}
+	}
+
+	change := func(name string, period time.Duration) (float64, error) {
+		sr, ok := series[name]
+		if !ok {
+			return 0, nil
+		}
+		first, last, _, err := db.Change(now.Add(-period).Unix(), now.Unix(), sr.Key, data.SensorID{ID: sr.ID, Channel: sr.Channel, Serial: sr.Serial})
+		return last - first, err
+	}
+	for _, name := range []string{"temperature", "pressure"} {
+		delta, err := change(name, 3*time.Hour)
+		if err != nil {
+			return d, err
+		}
+		d.Trend[name] = delta / 3
+	}
+	// The rain total goes back to zero when the sensor is reset.
+	if d.RainHour, err = change("raintotal", time.Hour); err != nil {
+		return d, err
+	}
+	d.RainHour = math.Max(d.RainHour, 0)
+	return d, nil
+}
+
+// dewPoint returns the dew point, and false without a temperature and
+// humidity.
+func (d compatData) dewPoint() (float64, bool) {
+	t, ok := d.Current["temperature"]
+	h, ok2 := d.Current["humidity"]
+	if !ok || !ok2 {
+		return 0, false
+	}
+	return upload.DewPointOf(t, h), true
+}
+
+// windChill returns the wind chill in °C from the temperature in °C and the
+// wind speed in m/s, as Environment Canada computes it.
+func windChill(temperature float64, speed float64) float64 {
+	if temperature > 10 || speed*3.6 <= 4.8 {
+		return temperature
+	}
+	v := math.Pow(speed*3.6, 0.16)
+	return 13.12 + 0.6215*temperature - 11.37*v + 0.3965*temperature*v
+}
+
+// heatIndex returns the heat index in °C from the temperature in °C and the
+// relative humidity in %, using the regression of the US National Weather
+// Service.
+func heatIndex(temperature float64, humidity float64) float64 {
+	if temperature < 26.7 {
+		return temperature
+	}
+	t, rh := temperature*1.8+32, humidity
+	hi := -42.379 + 2.04901523*t + 10.14333127*rh - 0.22475541*t*rh -
+		0.00683783*t*t - 0.05481717*rh*rh + 0.00122874*t*t*rh +
+		0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+	return (hi - 32) / 1.8
+}
+
+// humidex returns the humidex from the temperature and dew point in °C.
+func humidex(temperature float64, dewPoint float64) float64 {
+	e := 6.11 * math.Exp(5417.7530*(1/273.16-1/(273.15+dewPoint)))
+	return temperature + 0.5555*(e-10)
+}
+
+// apparentTemperature returns the apparent temperature in °C from the
+// temperature in °C, the relative humidity in % and the wind speed in m/s, as
+// the Australian Bureau of Meteorology computes it.
+func apparentTemperature(temperature float64, humidity float64, speed float64) float64 {
+	e := humidity / 100 * 6.105 * math.Exp(17.27*temperature/(237.7+temperature))
+	return temperature + 0.33*e - 0.70*speed - 4.00
+}
+
+var compassPoints = []string{
+	"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE",
+	"S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW",
+}
+
+// compassPoint returns the point of a 16-point compass closest to a
+// direction in degrees.
+func compassPoint(dir float64) string {
+	return compassPoints[int(math.Mod(dir+11.25+360, 360)/22.5)%16]
+}
+
+// beaufort returns the Beaufort force of a wind speed in m/s.
+func beaufort(speed float64) int {
+	for force, limit := range []float64{0.3, 1.6, 3.4, 5.5, 8.0, 10.8, 13.9, 17.2, 20.8, 24.5, 28.5, 32.7} {
+		if speed < limit {
+			return force
+		}
+	}
+	return 12
+}
+
+// formatFloat formats a value with a number of decimals.
+func formatFloat(value float64, decimals int) string {
+	return strconv.FormatFloat(value, 'f', decimals, 64)
+}
+
+// cumulusUnit is a unit as realtime.txt names it, and as the units package
+// does.
+type cumulusUnit struct {
+	Name string
+	Unit string
+}
+
+// cumulusUnits returns the units of realtime.txt closest to those of
+// unitmap. Cumulus only knows a few, and the others fall back to metric.
+func cumulusUnits(unitmap map[string]string) (temperature, wind, pressure, rain cumulusUnit) {
+	temperature, wind, pressure, rain = cumulusUnit{"C", "C"}, cumulusUnit{"m/s", "m/s"}, cumulusUnit{"hPa", "hPa"}, cumulusUnit{"mm", "mm"}
+	switch strings.ToLower(unitmap["temperature"]) {
+	case "f", "fahrenheit":
+		temperature = cumulusUnit{"F", "F"}
+	}
+	switch strings.ToLower(unitmap["windspeed"]) {
+	case "mph":
+		wind = cumulusUnit{"mph", "mph"}
+	case "km/h":
+		wind = cumulusUnit{"km/h", "km/h"}
+	case "knots", "kts":
+		wind = cumulusUnit{"kts", "kts"}
+	}
+	switch strings.ToLower(unitmap["pressure"]) {
+	case "inhg":
+		pressure = cumulusUnit{"in", "inhg"}
+	case "mbar", "millibar":
+		pressure = cumulusUnit{"mb", "mbar"}
+	}
+	switch strings.ToLower(unitmap["rain"]) {
+	case "in", "inch", "inches":
+		rain = cumulusUnit{"in", "in"}
+	}
+	return
+}
+
+// cumulusRealtime returns the realtime.txt of Cumulus 1, in the units of the
+// station. Fields of things gowx doesn't measure, such as solar radiation,
+// are zero, as Cumulus writes them for stations without those sensors.
+func cumulusRealtime(d compatData, station string) string {
+	tu, wu, pu, ru := cumulusUnits(stationUnits(station))
+	temperature := func(v float64) string {
+		t := units.NewTemperatureCelsius(v)
+		v, _ = t.Get(tu.Unit)
+		return formatFloat(v, 1)
+	}
+	// Trends are differences, which don't take the offset of the scale.
+	temperatureTrend := func(v float64) string {
+		t, zero := units.NewTemperatureCelsius(v), units.NewTemperatureCelsius(0)
+		v, _ = t.Get(tu.Unit)
+		z, _ := zero.Get(tu.Unit)
+		return formatFloat(v-z, 1)
+	}
+	speed := func(v float64) string {
+		s := units.NewSpeedMetersPerSecond(v)
+		v, _ = s.Get(wu.Unit)
+		return formatFloat(v, 1)
+	}
+	decimals := 1
+	if pu.Name == "in" {
+		decimals = 2
+	}
+	pressure := func(v float64) string {
+		p := units.NewPressureHectopascal(v)
+		v, _ = p.Get(pu.Unit)
+		return formatFloat(v, decimals)
+	}
+	rain := func(v float64) string {
+		r := units.NewDistanceMillimeters(v)
+		v, _ = r.Get(ru.Unit)
+		if ru.Name == "in" {
+			return formatFloat(v, 2)
+		}
+		return formatFloat(v, 1)
+	}
+	// The wind run is in the distance unit of the wind speed.
+	run := units.NewDistanceKilometers(d.WindRun)
+	windRun, _ := run.Get("km")
+	switch wu.Name {
+	case "mph":
+		windRun, _ = run.Get("mi")
+	case "kts":
+		windRun, _ = run.Get("nm")
+	}
+	signed := func(s string) string {
+		if strings.HasPrefix(s, "-") {
+			return s
+		}
+		return "+" + s
+	}
+	clock := func(e compatExtreme) string {
+		if e.Time.IsZero() {
+			return "00:00"
+		}
+		return e.Time.In(d.Location).Format("15:04")
+	}
+
+	local := d.Time.In(d.Location)
+	t, hasTemperature := d.Current["temperature"]
+	humidity := d.Current["humidity"]
+	wind, gust, dir := d.Current["wind"], d.Current["gust"], d.Current["winddir"]
+	dew, _ := d.dewPoint()
+	feels := []float64{0, 0, 0, 0}
+	cloudBase := 0.0
+	if hasTemperature {
+		feels = []float64{windChill(t, wind), heatIndex(t, humidity), humidex(t, dew), apparentTemperature(t, humidity, wind)}
+		cloudBase = (t - dew) * 125
+	}
+	cloudUnit := "m"
+	if tu.Name == "F" {
+		cloudUnit = "ft"
+		cloudBase *= 3.28084
+	}
+
+	fields := []string{
+		local.Format("02/01/06"),
+		local.Format("15:04:05"),
+		temperature(t),
+		formatFloat(humidity, 0),
+		temperature(dew),
+		speed(wind),
+		speed(gust),
+		formatFloat(dir, 0),
+		rain(d.Current["rain"]),
+		rain(d.RainToday),
+		pressure(d.Current["pressure"]),
+		compassPoint(dir),
+		strconv.Itoa(beaufort(wind)),
+		wu.Name,
+		tu.Name,
+		pu.Name,
+		ru.Name,
+		formatFloat(windRun, 1),
+		signed(pressure(d.Trend["pressure"])),
+		rain(d.RainMonth),
+		rain(d.RainYear),
+		rain(d.RainYesterday),
+		temperature(d.Current["indoortemperature"]),
+		formatFloat(d.Current["indoorhumidity"], 0),
+		temperature(feels[0]),
+		signed(temperatureTrend(d.Trend["temperature"])),
+		temperature(d.High["temperature"].Value),
+		clock(d.High["temperature"]),
+		temperature(d.Low["temperature"].Value),
+		clock(d.Low["temperature"]),
+		speed(d.High["wind"].Value),
+		clock(d.High["wind"]),
+		speed(d.High["gust"].Value),
+		clock(d.High["gust"]),
+		pressure(d.High["pressure"].Value),
+		clock(d.High["pressure"]),
+		pressure(d.Low["pressure"].Value),
+		clock(d.Low["pressure"]),
+		// Scripts check for the version and build of Cumulus 1.
+		"1.9.4",
+		"1099",
+		speed(gust),
+		temperature(feels[1]),
+		temperature(feels[2]),
+		"0",   // UV index
+		"0.0", // evapotranspiration
+		"0",   // solar radiation
+		formatFloat(dir, 0),
+		rain(d.RainHour),
+		"0", // forecast
+		"0", // daylight
+		"0", // sensor contact lost
+		compassPoint(dir),
+		formatFloat(cloudBase, 0),
+		cloudUnit,
+		temperature(feels[3]),
+		"0.0", // sunshine hours
+		"0",   // theoretical solar radiation
+		"0",   // sunny
+	}
+	return strings.Join(fields, " ") + "\n"
+}
+
+// clientrawVersion ends clientraw.txt, for scripts to check the file is
+// complete.
+const clientrawVersion = "!!C10.37S112!!"
+
+// weatherDisplayClientraw returns the clientraw.txt of Weather Display, which
+// is always in knots, °C, hPa and mm. The fields of things gowx doesn't
+// measure or keep, such as the readings of the last hours, are zero, and the
+// extremes of the derived temperatures are their current values.
+func weatherDisplayClientraw(d compatData, station string) string {
+	const knots = 1.943844 // m/s
+	fields := make([]string, 178)
+	for i := range fields {
+		fields[i] = "0"
+	}
+	set := func(i int, v float64, decimals int) {
+		fields[i] = formatFloat(v, decimals)
+	}
+	// Missing temperatures are written as -100, as Weather Display does for
+	// missing sensors.
+	for _, i := range []int{12, 14, 16, 20, 21, 22, 23, 24, 25, 26} {
+		fields[i] = "-100.0"
+	}
+
+	local := d.Time.In(d.Location)
+	name := station
+	if name == "" {
+		name = viper.GetString("station")
+	}
+	if name == "" {
+		name = "gowx"
+	}
+
+	t, hasTemperature := d.Current["temperature"]
+	humidity := d.Current["humidity"]
+	wind, gust, dir := d.Current["wind"], d.Current["gust"], d.Current["winddir"]
+	dew, _ := d.dewPoint()
+
+	fields[0] = "12345"
+	set(1, wind*knots, 1)
+	set(2, gust*knots, 1)
+	set(3, dir, 0)
+	set(4, t, 1)
+	set(5, humidity, 0)
+	set(6, d.Current["pressure"], 1)
+	set(7, d.RainToday, 1)
+	set(8, d.RainMonth, 1)
+	set(9, d.RainYear, 1)
+	set(10, d.Current["rain"]/60, 2)
+	set(11, d.High["rain"].Value/60, 2)
+	if v, ok := d.Current["indoortemperature"]; ok {
+		set(12, v, 1)
+	}
+	set(13, d.Current["indoorhumidity"], 0)
+	set(19, d.RainYesterday, 1)
+	set(29, float64(local.Hour()), 0)
+	set(30, float64(local.Minute()), 0)
+	set(31, float64(local.Second()), 0)
+	fields[32] = strings.Replace(name, " ", "_", -1) + "-" + local.Format("15:04:05")
+	set(35, float64(local.Day()), 0)
+	set(36, float64(local.Month()), 0)
+	for i := 37; i <= 43; i++ {
+		fields[i] = "100"
+	}
+	if hasTemperature {
+		chill, hx, hi := windChill(t, wind), humidex(t, dew), heatIndex(t, humidity)
+		for _, i := range []int{44, 77, 78} {
+			set(i, chill, 1)
+		}
+		for _, i := range []int{45, 75, 76} {
+			set(i, hx, 1)
+		}
+		for _, i := range []int{110, 111, 112} {
+			set(i, hi, 1)
+		}
+		set(72, dew, 1)
+		set(73, (t-dew)*125*3.28084, 0)
+		set(130, apparentTemperature(t, humidity, wind), 1)
+	}
+	set(46, d.High["temperature"].Value, 1)
+	set(47, d.Low["temperature"].Value, 1)
+	fields[49] = "-"
+	set(50, d.Trend["pressure"], 1)
+	set(71, d.High["gust"].Value*knots, 1)
+	fields[74] = local.Format("02/01/2006")
+	set(113, d.High["wind"].Value*knots, 1)
+	set(117, dir, 0)
+	fields[len(fields)-1] = clientrawVersion
+	return strings.Join(fields, " ") + "\n"
+}
+
+// compatHandler serves a compatibility file of the station of db.
+func compatHandler(w http.ResponseWriter, r *http.Request, db *data.Database, latest *stationLatest, format func(compatData, string) string) {
+	d, err := newCompatData(db, latest, time.Now())
+	if err != nil {
+		jww.ERROR.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(format(d, db.StationName())))
+}
@@ -0,0 +1,135 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"math"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/geoffholden/gowx/data"
+)
+
+func TestCompat(t *testing.T) {
+	viper.Set("dbDriver", "sqlite3")
+	viper.Set("database", filepath.Join(t.TempDir(), "gowx.db"))
+	db, err := data.OpenDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	defer viper.Set("units", viper.Get("units"))
+	viper.Set("units", map[string]string{"temperature": "F", "windspeed": "mph"})
+	defer viper.Set("timezone", viper.Get("timezone"))
+	viper.Set("timezone", "UTC")
+	viper.Set("current_data", map[string]interface{}{
+		"temperature": map[string]string{"id": "OS3:1D20", "type": "Temperature"},
+		"humidity":    map[string]string{"id": "OS3:1D20", "type": "Humidity"},
+		"pressure":    map[string]string{"type": "Pressure"},
+		"wind":        map[string]string{"type": "AverageWind"},
+		"winddir":     map[string]string{"type": "WindDir"},
+		"rain":        map[string]string{"type": "RainRate"},
+	})
+	defer viper.Set("current_data", nil)
+
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	latest := newStationLatest()
+	latest.Update(data.SensorData{TimeStamp: now, ID: "OS3:1D20", Channel: 1, Data: map[string]float64{"Temperature": 10, "Humidity": 50}})
+	latest.Update(data.SensorData{TimeStamp: now, ID: "WGR800", Data: map[string]float64{"AverageWind": 5, "CurrentWind": 8, "WindDir": 270}})
+	latest.Update(data.SensorData{TimeStamp: now, ID: "PCR800", Data: map[string]float64{"RainRate": 1.2, "RainTotal": 100}})
+	latest.Update(data.SensorData{TimeStamp: now, ID: "BTHR918", Data: map[string]float64{"Pressure": 1013.2}})
+
+	at := func(hour, min int) int64 {
+		return time.Date(2026, 10, 19, hour, min, 0, 0, time.UTC).Unix()
+	}
+	for _, s := range []data.DailySummary{
+		{Day: "2026-10-19", SensorKey: data.SensorKey{ID: "OS3:1D20", Channel: 1}, Key: "Temperature", High: 14, HighTime: at(9, 30), Low: 4, LowTime: at(6, 15), MeanSum: 9, MeanCount: 1},
+		{Day: "2026-10-19", SensorKey: data.SensorKey{ID: "WGR800"}, Key: "AverageWind", High: 7, HighTime: at(11, 0), MeanSum: 5, MeanCount: 1},
+		{Day: "2026-10-19", SensorKey: data.SensorKey{ID: "PCR800"}, Key: "RainTotal", MeanSum: 100, MeanCount: 1, Total: 2.5},
+		{Day: "2026-10-18", SensorKey: data.SensorKey{ID: "PCR800"}, Key: "RainTotal", MeanSum: 97, MeanCount: 1, Total: 1},
+		{Day: "2026-10-01", SensorKey: data.SensorKey{ID: "PCR800"}, Key: "RainTotal", MeanSum: 96, MeanCount: 1, Total: 3},
+		{Day: "2026-01-05", SensorKey: data.SensorKey{ID: "PCR800"}, Key: "RainTotal", MeanSum: 93, MeanCount: 1, Total: 4},
+	} {
+		s.HighDir = math.NaN()
+		if err := db.SaveDailySummary(s, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.InsertRow(at(10, 0), "BTHR918", 0, "", "Pressure", 1010, 1010, 1010)
+	db.InsertRow(at(11, 59), "BTHR918", 0, "", "Pressure", 1013, 1013, 1013)
+
+	d, err := newCompatData(db, latest, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Current["gust"] != 8 || d.Current["raintotal"] != 100 {
+		t.Error("Unexpected fallbacks", d.Current)
+	}
+	if d.RainToday != 2.5 || d.RainYesterday != 1 || d.RainMonth != 6.5 || d.RainYear != 10.5 {
+		t.Error("Unexpected rain", d)
+	}
+	if d.Trend["pressure"] != 1 || d.WindRun != 216 {
+		t.Error("Unexpected trend or wind run", d.Trend, d.WindRun)
+	}
+
+	realtime := strings.Fields(cumulusRealtime(d, ""))
+	if len(realtime) != 58 {
+		t.Fatal("Unexpected realtime.txt", realtime)
+	}
+	for i, want := range map[int]string{
+		0: "19/10/26", 1: "12:00:00", 2: "50.0", 3: "50", 5: "11.2", 6: "17.9", 7: "270",
+		9: "2.5", 11: "W", 12: "3", 13: "mph", 14: "F", 15: "hPa", 16: "mm", 17: "134.2",
+		18: "+1.0", 19: "6.5", 20: "10.5", 21: "1.0", 26: "57.2", 27: "09:30", 28: "39.2", 29: "06:15",
+		31: "11:00",
+	} {
+		if realtime[i] != want {
+			t.Errorf("Unexpected realtime.txt field %d: %s, not %s", i+1, realtime[i], want)
+		}
+	}
+
+	clientraw := strings.Fields(weatherDisplayClientraw(d, ""))
+	if len(clientraw) != 178 || clientraw[177] != clientrawVersion {
+		t.Fatal("Unexpected clientraw.txt", clientraw)
+	}
+	for i, want := range map[int]string{
+		0: "12345", 1: "9.7", 2: "15.6", 3: "270", 4: "10.0", 5: "50", 6: "1013.2", 7: "2.5",
+		8: "6.5", 9: "10.5", 10: "0.02", 12: "-100.0", 19: "1.0", 29: "12", 46: "14.0", 47: "4.0",
+		50: "1.0", 74: "19/10/2026", 113: "13.6",
+	} {
+		if clientraw[i] != want {
+			t.Errorf("Unexpected clientraw.txt field %d: %s, not %s", i, clientraw[i], want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	compatHandler(w, httptest.NewRequest("GET", "/realtime.txt", nil), db, latest, cumulusRealtime)
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") || len(strings.Fields(w.Body.String())) != 58 {
+		t.Error("Unexpected response", w.Header(), w.Body.String())
+	}
+}
+
+func TestCompatDerived(t *testing.T) {
+	if compassPoint(350) != "N" || compassPoint(22.5) != "NNE" || compassPoint(-90) != "W" {
+		t.Error("Unexpected compass points")
+	}
+	if beaufort(0) != 0 || beaufort(5) != 3 || beaufort(40) != 12 {
+		t.Error("Unexpected Beaufort forces")
+	}
+	if v := windChill(-10, 20/3.6); math.Abs(v-(-17.9)) > 0.05 {
+		t.Error("Unexpected wind chill", v)
+	}
+	if v := windChill(15, 10); v != 15 {
+		t.Error("Unexpected wind chill", v)
+	}
+	if v := heatIndex(32, 70); math.Abs(v-40.4) > 0.1 {
+		t.Error("Unexpected heat index", v)
+	}
+	if v := humidex(30, 15); math.Abs(v-34.0) > 0.1 {
+		t.Error("Unexpected humidex", v)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/units"
+)
+
+// currentValue is the latest value of a key, converted to the configured
+// unit.
+type currentValue struct {
+	Value     float64
+	Unit      string
+	TimeStamp time.Time
+	Age       int64 // seconds
+}
+
+type currentSensor struct {
+	data.SensorKey
+	Name     string `json:",omitempty"`
+	LastSeen time.Time
+	Age      int64 // seconds
+	Values   map[string]currentValue
+}
+
+// headline is the value picked by a selection in "current_data".
+type headline struct {
+	currentValue
+	Sensor data.SensorKey
+	Name   string `json:",omitempty"`
+	Key    string
+}
+
+type currentConditions struct {
+	Time     time.Time
+	Headline map[string]headline
+	Sensors  []currentSensor
+}
+
+// convertValueTo converts the value of a key to the unit in unitmap,
+// returning the unit it's in. Values that can't be converted, and every
+// value with a nil map, are left in the unit they were measured in.
+func convertValueTo(unitmap map[string]string, key string, value float64) (float64, string) {
+	convert := func(unit, base string, get func(string) (float64, error)) (float64, string) {
+		if v, err := get(unit); err == nil {
+			return v, unit
+		}
+		return value, base
+	}
+	switch {
+	case key == "Temperature":
+		t := units.NewTemperatureCelsius(value)
+		return convert(unitmap["temperature"], "C", t.Get)
+	case key == "Pressure":
+		p := units.NewPressureHectopascal(value)
+		return convert(unitmap["pressure"], "hPa", p.Get)
+	case key == "WindDir":
+		return value, "°"
+	case strings.HasSuffix(key, "Wind") || key == "WindSpeed":
+		s := units.NewSpeedMetersPerSecond(value)
+		return convert(unitmap["windspeed"], "m/s", s.Get)
+	case key == "RainRate":
+		d := units.NewDistanceMillimeters(value)
+		unit := unitmap["rain"]
+		return convert(unit, "mm/h", func(unit string) (float64, error) {
+			return d.Get(strings.TrimSuffix(unit, "/h"))
+		})
+	case key == "RainTotal":
+		d := units.NewDistanceMillimeters(value)
+		return convert(unitmap["rain"], "mm", d.Get)
+	case key == "Humidity":
+		return value, "%"
+	}
+	return value, ""
+}
+
+// newCurrentConditions returns the current conditions from the latest
+// values, converted to the units of unitmap.
+func newCurrentConditions(latest *data.Latest, reg *data.Registry, unitmap map[string]string, now time.Time) currentConditions {
+	age := func(t time.Time) int64 {
+		return int64(now.Sub(t) / time.Second)
+	}
+	c := currentConditions{Time: now.UTC(), Headline: make(map[string]headline), Sensors: []currentSensor{}}
+	for _, s := range latest.Snapshot() {
+		sensor := currentSensor{SensorKey: s.SensorKey, LastSeen: s.LastSeen, Age: age(s.LastSeen), Values: make(map[string]currentValue)}
+		sensor.Name, _ = reg.Name(s.SensorKey)
+		for key, v := range s.Values {
+			value, unit := convertValueTo(unitmap, key, v.Value)
+			sensor.Values[key] = currentValue{value, unit, v.TimeStamp, age(v.TimeStamp)}
+		}
+		c.Sensors = append(c.Sensors, sensor)
+	}
+
+	config := viper.Sub("current_data")
+	if config == nil {
+		return c
+	}
+	var names []string
+	for name := range config.AllSettings() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		query := config.GetStringMapString(name)
+		key := query["type"]
+		for _, s := range c.Sensors {
+			v, ok := s.Values[key]
+			if !ok || !sensorMatch(query, s.SensorKey, reg) {
+				continue
+			}
+			if h, ok := c.Headline[name]; !ok || v.TimeStamp.After(h.TimeStamp) {
+				c.Headline[name] = headline{v, s.SensorKey, s.Name, key}
+			}
+		}
+	}
+	return c
+}
+
+// legacyCurrentData is the current conditions in the format of
+// /currentdata.json, which the web page shows.
+type legacyCurrentData struct {
+	Temperature float64
+	Humidity    float64
+	Pressure    float64
+	Wind        float64
+	WindDir     float64
+	RainRate    float64
+}
+
+func (c currentConditions) legacy() legacyCurrentData {
+	return legacyCurrentData{
+		Temperature: c.Headline["temperature"].Value,
+		Humidity:    c.Headline["humidity"].Value,
+		Pressure:    c.Headline["pressure"].Value,
+		Wind:        c.Headline["wind"].Value,
+		WindDir:     c.Headline["winddir"].Value,
+		RainRate:    c.Headline["rain"].Value,
+	}
+}
+
+// stationLatest keeps the latest values of each station, and of every
+// station together under data.AllStations. It is safe for concurrent use.
+type stationLatest struct {
+	mu       sync.Mutex
+	stations map[string]*data.Latest
+}
+
+func newStationLatest() *stationLatest {
+	return &stationLatest{stations: map[string]*data.Latest{data.AllStations: data.NewLatest()}}
+}
+
+// Update stores the values of a sample, for its station and every station.
+func (s *stationLatest) Update(d data.SensorData) {
+	s.get(data.AllStations).Update(d)
+	s.mu.Lock()
+	key := strings.ToLower(d.Station)
+	latest, ok := s.stations[key]
+	if !ok {
+		latest = data.NewLatest()
+		s.stations[key] = latest
+	}
+	s.mu.Unlock()
+	latest.Update(d)
+}
+
+// get returns the latest values of a station, "" being the default one, or
+// of every station for data.AllStations.
+func (s *stationLatest) get(station string) *data.Latest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if latest, ok := s.stations[strings.ToLower(station)]; ok {
+		return latest
+	}
+	return data.NewLatest()
+}
+
+// stationCurrent returns the current conditions of a station, in its units.
+func stationCurrent(latest *stationLatest, db *data.Database, station string, now time.Time) currentConditions {
+	return newCurrentConditions(latest.get(station), db.Station(station).Registry(), stationUnits(station), now)
+}
+
+// currentHandler serves the current conditions of the station of the
+// request.
+func currentHandler(w http.ResponseWriter, r *http.Request, latest *stationLatest, db *data.Database) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stationCurrent(latest, db, requestStation(r), time.Now()))
+}
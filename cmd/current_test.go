@@ -0,0 +1,52 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/geoffholden/gowx/data"
+)
+
+func TestCurrentConditions(t *testing.T) {
+	viper.Set("dbDriver", "sqlite3")
+	viper.Set("database", filepath.Join(t.TempDir(), "gowx.db"))
+	db, err := data.OpenDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	defer viper.Set("units", viper.Get("units"))
+	viper.Set("units", map[string]string{"temperature": "F", "windspeed": "bogus"})
+	viper.Set("current_data", map[string]interface{}{
+		"temperature": map[string]string{"id": "OS3:1D20", "type": "Temperature"},
+	})
+	defer viper.Set("current_data", nil)
+
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	latest := data.NewLatest()
+	latest.Update(data.SensorData{TimeStamp: now.Add(-time.Minute), ID: "OS3:1D20", Channel: 1, Data: map[string]float64{"Temperature": 10}})
+	latest.Update(data.SensorData{TimeStamp: now, ID: "THGR810", Channel: 2, Data: map[string]float64{"Temperature": 20, "CurrentWind": 3}})
+
+	c := newCurrentConditions(latest, db.Registry(), viper.GetStringMapString("units"), now)
+	if len(c.Sensors) != 2 {
+		t.Fatal("Unexpected sensors", c.Sensors)
+	}
+	if v := c.Sensors[0].Values["Temperature"]; v.Value != 50 || v.Unit != "F" || v.Age != 60 {
+		t.Error("Unexpected temperature", v)
+	}
+	// A unit that can't be converted to leaves the value as it was.
+	if v := c.Sensors[1].Values["CurrentWind"]; v.Value != 3 || v.Unit != "m/s" {
+		t.Error("Unexpected wind", v)
+	}
+	if h := c.Headline["temperature"]; h.Value != 50 || h.Sensor.ID != "OS3:1D20" || h.Key != "Temperature" {
+		t.Error("Unexpected headline", h)
+	}
+	if legacy := c.legacy(); legacy.Temperature != 50 {
+		t.Error("Unexpected current data", legacy)
+	}
+}
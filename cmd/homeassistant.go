@@ -0,0 +1,65 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"github.com/geoffholden/gowx/bus"
+	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/homeassistant"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	viper.SetDefault("homeassistant.discovery", false)
+	viper.SetDefault("homeassistant.prefix", "homeassistant")
+}
+
+// newDiscovery returns the Home Assistant discovery publisher for the
+// aggregated values, or nil if discovery isn't enabled.
+func newDiscovery(b bus.Bus) *homeassistant.Discovery {
+	if !viper.GetBool("homeassistant.discovery") {
+		return nil
+	}
+	discovery := homeassistant.New(b, func(station string, sensor data.SensorKey, key string) string {
+		return newTopics(topicLevel(station, "")).aggregatedValue(sensor, key)
+	})
+	discovery.Prefix = viper.GetString("homeassistant.prefix")
+	discovery.AvailabilityTopic = stationTopics().status("aggregator")
+	discovery.Units = viper.GetStringMapString("units")
+	return discovery
+}
+
+// subscribeDiscovery publishes the configs again whenever Home Assistant
+// starts.
+func subscribeDiscovery(b bus.Bus, discovery *homeassistant.Discovery) {
+	if err := b.Subscribe(discovery.StatusTopic(), func(msg bus.Message) {
+		if string(msg.Payload) == "online" {
+			go republishDiscovery(discovery)
+		}
+	}); err != nil {
+		jww.ERROR.Println(err)
+	}
+}
+
+func republishDiscovery(discovery *homeassistant.Discovery) {
+	if err := discovery.Republish(); err != nil {
+		jww.ERROR.Println(err)
+	}
+}
+
+// discoverAggregates publishes the configs of the aggregated values, each
+// under the station of its samples, which is this process's station for
+// samples that don't name one.
+func discoverAggregates(discovery *homeassistant.Discovery, db *data.Database, res []aggdata) {
+	for _, d := range res {
+		station := d.Key.Station
+		if station == "" {
+			station = viper.GetString("station")
+		}
+		name, _ := db.Station(d.Key.Station).Registry().Name(d.Key.sensor())
+		if err := discovery.Observe(station, d.Key.sensor(), name, d.Key.Key); err != nil {
+			jww.ERROR.Println(err)
+		}
+	}
+}
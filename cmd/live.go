@@ -0,0 +1,200 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/live"
+	"github.com/geoffholden/gowx/metrics"
+)
+
+var liveDropped = metrics.NewCounter("gowx_live_dropped_total", "Number of live update clients dropped for falling behind.")
+
+func registerLiveMetrics(hub *live.Hub) {
+	metrics.NewGaugeFunc("gowx_live_clients", "Number of connected live update clients.", nil, func(emit func(float64, ...string)) {
+		emit(float64(hub.Clients()))
+	})
+	hub.OnDrop = func() { liveDropped.Inc() }
+}
+
+// stationEvent is the data of an event for one station. Clients get the
+// events of the station they ask for.
+type stationEvent struct {
+	Station string
+	Data    interface{}
+}
+
+// livePoint is a point of the series of a chart, as in /data.json.
+type livePoint struct {
+	Series   int
+	Data     []interface{}
+	Errorbar []interface{}
+}
+
+// liveHandler streams live updates as Server-Sent Events. The events are:
+//
+//	current    the current conditions, as /currentdata.json
+//	sample     raw samples, JSON data.SensorData
+//	aggregate  aggregated samples, JSON aggdata
+//	point      the aggregates as points of the chart series of "query"
+//
+// Everything is filtered by the station of the request, and samples and
+// aggregates by the "sensor", "id", "channel", "serial" and "key"
+// parameters. Points are chosen by the "query" and "type" parameters of
+// /data.json. The "events" parameter lists the events to send, all but
+// points by default and just points with a query.
+func liveHandler(w http.ResponseWriter, r *http.Request, hub *live.Hub, reg *data.Registry) {
+	filter, err := liveFilter(r, reg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hub.Serve(w, r, filter)
+}
+
+func liveFilter(r *http.Request, reg *data.Registry) (live.Filter, error) {
+	r.ParseForm()
+	query := make(map[string]string)
+	for _, param := range []string{"sensor", "id", "channel", "serial"} {
+		if v := r.Form.Get(param); v != "" {
+			query[param] = v
+		}
+	}
+	keys := make(map[string]bool)
+	for _, k := range r.Form["key"] {
+		for _, k := range strings.Split(k, ",") {
+			keys[k] = true
+		}
+	}
+
+	var queries []map[string]string
+	if q := r.Form.Get("query"); q != "" {
+		if err := json.Unmarshal([]byte(q), &queries); err != nil {
+			return nil, err
+		}
+	}
+	// The sensors of the charts are looked up once, rather than for every
+	// aggregate.
+	var charts []liveChart
+	for _, q := range queries {
+		charts = append(charts, newLiveChart(q, reg))
+	}
+	events := map[string]bool{"current": true, "sample": true, "aggregate": true}
+	if queries != nil {
+		events = map[string]bool{"point": true}
+	}
+	if e := r.Form.Get("events"); e != "" {
+		events = make(map[string]bool)
+		for _, name := range strings.Split(e, ",") {
+			events[name] = true
+		}
+	}
+	unitType := r.Form.Get("type")
+	station := requestStation(r)
+	unitmap := stationUnits(station)
+	fromStation := func(from string) bool {
+		return strings.EqualFold(station, from)
+	}
+
+	return func(e live.Event) []live.Event {
+		switch d := e.Data.(type) {
+		case stationEvent:
+			if !events[e.Name] || !fromStation(d.Station) {
+				return nil
+			}
+			return []live.Event{{Name: e.Name, Data: d.Data}}
+		case data.SensorData:
+			if !events["sample"] || !fromStation(d.Station) || !sensorMatch(query, d.Key(), reg) {
+				return nil
+			}
+			if len(keys) > 0 {
+				values := make(map[string]float64)
+				for k, v := range d.Data {
+					if keys[k] {
+						values[k] = v
+					}
+				}
+				if len(values) == 0 {
+					return nil
+				}
+				d.Data = values
+			}
+			return []live.Event{{Name: e.Name, Data: d}}
+		case aggdata:
+			if !fromStation(d.Key.Station) {
+				return nil
+			}
+			var result []live.Event
+			if events["aggregate"] && sensorMatch(query, d.Key.sensor(), reg) && (len(keys) == 0 || keys[d.Key.Key]) {
+				result = append(result, e)
+			}
+			if events["point"] {
+				for i, c := range charts {
+					if p, ok := c.point(unitType, unitmap, d); ok {
+						p.Series = i
+						result = append(result, live.Event{Name: "point", Data: p})
+					}
+				}
+			}
+			return result
+		}
+		if events[e.Name] {
+			return []live.Event{e}
+		}
+		return nil
+	}, nil
+}
+
+var chartColumn = regexp.MustCompile(`\[([^]]*)\]`)
+
+// liveChart is a chart series of a live client's query: the key, the column
+// of the aggregates and the sensors.
+type liveChart struct {
+	key     string
+	col     []string
+	sensors []data.SensorID
+}
+
+func newLiveChart(query map[string]string, reg *data.Registry) liveChart {
+	datatype := query["type"]
+	return liveChart{
+		key:     chartColumn.ReplaceAllString(datatype, ""),
+		col:     chartColumn.FindStringSubmatch(datatype),
+		sensors: querySensors(query, reg),
+	}
+}
+
+// point returns the point an aggregate adds to the chart series, converted
+// to the units of unitmap as in /data.json.
+func (c liveChart) point(unitType string, unitmap map[string]string, d aggdata) (livePoint, bool) {
+	if c.key != d.Key.Key {
+		return livePoint{}, false
+	}
+	matched := false
+	for _, id := range c.sensors {
+		if (id.ID == "%" || id.ID == d.Key.ID) && (id.Channel < 0 || id.Channel == d.Key.Channel) && (id.Serial == "" || id.Serial == d.Key.Serial) {
+			matched = true
+		}
+	}
+	if !matched {
+		return livePoint{}, false
+	}
+
+	row := data.Row{Timestamp: d.Timestamp, Min: d.Min, Max: d.Max, Avg: d.Avg}
+	_, off := time.Unix(row.Timestamp, 0).Zone()
+	t := (row.Timestamp + int64(off)) * 1000
+	value := rowValue(c.col, row)
+	if strings.HasSuffix(d.Key.Key, "Dir") {
+		return livePoint{Data: []interface{}{t, value}, Errorbar: []interface{}{t, row.Min, row.Max}}, true
+	}
+	return livePoint{
+		Data:     []interface{}{t, convertUnit(unitmap, unitType, value)},
+		Errorbar: []interface{}{t, convertUnit(unitmap, unitType, row.Min), convertUnit(unitmap, unitType, row.Max)},
+	}, true
+}
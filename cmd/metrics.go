@@ -0,0 +1,104 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+
+	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/metrics"
+)
+
+// The metrics are kept per process: the parser counts parse errors, every
+// component its MQTT connection and database queries, and the server its
+// requests, sensors and live clients. The server serves them on its own
+// /metrics, and every component, the server included, also serves them on the
+// address given by --metrics. "gowx all" runs every component in one process,
+// so its /metrics has all of them.
+
+var (
+	mqttConnected  = metrics.NewGauge("gowx_mqtt_connected", "Whether the component is connected to the MQTT broker.", "component")
+	mqttReconnects = metrics.NewCounter("gowx_mqtt_reconnects_total", "Number of times the component has lost its MQTT connection.", "component")
+	httpRequests   = metrics.NewCounter("gowx_http_requests_total", "Number of HTTP requests served.", "handler", "code")
+)
+
+var metricsOnce sync.Once
+
+// serveMetrics serves the metrics of the process on the address of
+// --metrics, if there is one. Only the first component of a process starts
+// the listener.
+func serveMetrics() {
+	address := viper.GetString("metrics")
+	if address == "" {
+		return
+	}
+	metricsOnce.Do(func() {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		go func() {
+			jww.INFO.Println("Serving metrics on", address)
+			if err := http.ListenAndServe(address, mux); err != nil {
+				jww.ERROR.Println(err)
+			}
+		}()
+	})
+}
+
+func mqttOnConnect(component string) {
+	mqttConnected.Set(1, component)
+}
+
+func mqttOnConnectionLost(component string) {
+	mqttConnected.Set(0, component)
+	mqttReconnects.Inc(component)
+}
+
+// registerSensorMetrics exposes the latest value and last-seen time of every
+// sensor of every station.
+func registerSensorMetrics(latest *data.Latest) {
+	labels := []string{"station", "id", "channel", "serial", "key"}
+	metrics.NewGaugeFunc("gowx_sensor_value", "Latest value received from a sensor.", labels, func(emit func(float64, ...string)) {
+		for _, s := range latest.Snapshot() {
+			for key, v := range s.Values {
+				emit(v.Value, s.Station, s.ID, strconv.Itoa(s.Channel), s.Serial, key)
+			}
+		}
+	})
+	metrics.NewGaugeFunc("gowx_sensor_last_seen_timestamp_seconds", "Time data was last received from a sensor.", labels[:4], func(emit func(float64, ...string)) {
+		for _, s := range latest.Snapshot() {
+			emit(float64(s.LastSeen.UnixNano())/1e9, s.Station, s.ID, strconv.Itoa(s.Channel), s.Serial)
+		}
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush lets handlers stream their responses.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// countRequests counts the requests served by each handler of mux.
+func countRequests(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		rec := &statusRecorder{w, http.StatusOK}
+		mux.ServeHTTP(rec, r)
+		httpRequests.Inc(pattern, strconv.Itoa(rec.status))
+	})
+}
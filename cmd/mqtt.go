@@ -0,0 +1,137 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+	"github.com/geoffholden/gowx/bus"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	viper.SetDefault("mqtt.qos", 0)
+	viper.SetDefault("mqtt.persistent", false)
+}
+
+// mqttQoS is the QoS used for all publishing and subscriptions.
+func mqttQoS() byte {
+	qos := viper.GetInt("mqtt.qos")
+	if qos < 0 || qos > 2 {
+		jww.ERROR.Println("Invalid MQTT QoS", qos)
+		return 0
+	}
+	return byte(qos)
+}
+
+func mqttTLSConfig() (*tls.Config, error) {
+	ca := viper.GetString("mqtt.ca")
+	cert := viper.GetString("mqtt.cert")
+	key := viper.GetString("mqtt.key")
+	insecure := viper.GetBool("mqtt.insecure")
+	if ca == "" && cert == "" && !insecure {
+		return nil, nil
+	}
+
+	config := &tls.Config{InsecureSkipVerify: insecure}
+	if ca != "" {
+		pem, err := ioutil.ReadFile(ca)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in " + ca)
+		}
+	}
+	if cert != "" {
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{pair}
+	}
+	return config, nil
+}
+
+// newMQTTBus creates the MQTT bus of a component from the "broker" and
+// "mqtt" settings. onConnect is called whenever the connection is
+// established.
+//
+// While connected, the component's status topic is "online", and the broker
+// sets it to "offline" through the last will if the connection is lost.
+// With persistent sessions, the client ID stays the same across restarts
+// and the broker queues messages of QoS 1 or more while it is disconnected.
+func newMQTTBus(component string, onConnect func()) (*bus.MQTT, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	persistent := viper.GetBool("mqtt.persistent")
+	clientid := fmt.Sprintf("gowx-%s-%s-%d", component, hostname, os.Getpid())
+	if persistent {
+		clientid = fmt.Sprintf("gowx-%s-%s", component, hostname)
+		if station := viper.GetString("station"); station != "" {
+			clientid += "-" + station
+		}
+	}
+
+	tlsConfig, err := mqttTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	status := stationTopics().status(component)
+	opts := MQTT.NewClientOptions().AddBroker(viper.GetString("broker")).SetClientID(clientid).SetCleanSession(!persistent)
+	opts.SetUsername(viper.GetString("mqtt.username"))
+	opts.SetPassword(viper.GetString("mqtt.password"))
+	opts.SetWill(status, "offline", mqttQoS(), true)
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	opts.OnConnect = func(c MQTT.Client) {
+		mqttOnConnect(component)
+		if token := c.Publish(status, mqttQoS(), true, "online"); token.Wait() && token.Error() != nil {
+			jww.ERROR.Println(token.Error())
+		}
+		if onConnect != nil {
+			onConnect()
+		}
+	}
+	opts.OnConnectionLost = func(c MQTT.Client, e error) {
+		jww.ERROR.Println("MQTT Connection Lost", e)
+		mqttOnConnectionLost(component)
+		connect(c)
+	}
+	opts.AutoReconnect = false
+
+	return bus.NewMQTT(opts, mqttQoS()), nil
+}
+
+// connect connects to the broker, retrying with a backoff until it succeeds.
+func connect(client MQTT.Client) {
+	timeout := 1 * time.Second
+
+	for {
+		if token := client.Connect(); token.Wait() && token.Error() != nil {
+			jww.ERROR.Println(token.Error())
+			jww.ERROR.Printf("Waiting %d seconds before reconnecting...", timeout/time.Second)
+			time.Sleep(timeout)
+			timeout *= 2
+			if timeout > 5*time.Minute {
+				timeout = 5 * time.Minute
+			}
+			continue
+		}
+		break
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+
+	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/notify"
+)
+
+// notifyCmd represents the notify command
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Notification channels",
+	Long: `The alerts command sends the alerts, and a summary of each day after
+midnight, to the channels in the "notify.channels" section, e.g.
+
+notify:
+  channels:
+    email:
+      type: smtp
+      server: smtp.example.com:587
+      username: station
+      password: secret
+      from: station@example.com
+      to: [me@example.com]
+      events: [alert, daily]
+    phone:
+      type: ntfy
+      url: https://ntfy.sh/mytopic
+      events: [alert]
+      limit: 5
+      per: 1h
+      title: '{{.Alert.Rule}}'
+      body: '{{.Alert.Message}}: {{printf "%.1f" .Alert.Value}}'
+    script:
+      type: exec
+      command: /usr/local/bin/notify.sh
+
+The types are smtp, ntfy, gotify (url and token), pushover (token and user)
+and exec, which runs a command with the body on its standard input and the
+message in GOWX_TITLE, GOWX_BODY and GOWX_PRIORITY. SMTP uses STARTTLS,
+unless "tls" is set for implicit TLS or "starttls" is false.
+
+The title and body are Go text/templates of the event, which has the Type,
+Time and Station, the Alert for alerts and the Day and its Records for daily
+summaries. The test event has the Type "test", with a sample Alert and Day.
+A channel sends at most "limit" messages "per" period.`,
+}
+
+var notifyTestCmd = &cobra.Command{
+	Use:   "test [channel...]",
+	Short: "Send a test notification",
+	Run: func(cmd *cobra.Command, args []string) {
+		n, err := notify.Open(args)
+		if err != nil {
+			fatal(err)
+		}
+		e := notify.TestEvent(viper.GetString("station"), time.Now())
+		for _, c := range n.Channels {
+			if err := c.Send(e); err != nil {
+				fatal(fmt.Errorf("%s: %s", c.Name, err.Error()))
+			}
+			fmt.Println("Sent to", c.Name)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(notifyCmd)
+	notifyCmd.AddCommand(notifyTestCmd)
+}
+
+// dailySummaries sends the records of each day, a few minutes after the
+// station's midnight so that the last interval has been aggregated.
+func dailySummaries(db *data.Database, n *notify.Notifiers) {
+	for {
+		now := time.Now().In(stationLocation())
+		midnight := bod(now).AddDate(0, 0, 1)
+		time.Sleep(midnight.Add(5 * time.Minute).Sub(now))
+
+		day := midnight.AddDate(0, 0, -1).Format("2006-01-02")
+		result, err := queryRecords(db, "day", day, "")
+		if err != nil {
+			jww.ERROR.Println(err)
+			continue
+		}
+		if len(result) > 0 {
+			n.Send(notify.Event{Type: notify.Daily, Station: viper.GetString("station"), Day: day, Records: result})
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+// openAPISpec describes the /api/v1 resources, and is served at
+// /api/v1/openapi.json.
+const openAPISpec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "gowx",
+    "description": "Weather station data recorded by gowx.",
+    "version": "1.0.0"
+  },
+  "servers": [
+    {"url": "/api/v1", "description": "The station of the server"},
+    {
+      "url": "/api/v1/stations/{station}",
+      "description": "One station, in its units",
+      "variables": {"station": {"default": "home", "description": "The name of a station from /stations."}}
+    }
+  ],
+  "paths": {
+    "/stations": {
+      "servers": [{"url": "/api/v1"}],
+      "get": {
+        "summary": "List the stations",
+        "responses": {
+          "200": {
+            "description": "The stations",
+            "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Station"}}}}
+          },
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/stations/{station}": {
+      "servers": [{"url": "/api/v1"}],
+      "get": {
+        "summary": "Get a station",
+        "parameters": [
+          {"name": "station", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "The station",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Station"}}}
+          },
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/sensors": {
+      "get": {
+        "summary": "List the logical sensors and the physical sensors with samples",
+        "responses": {
+          "200": {
+            "description": "The sensors",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Sensors"}}}
+          },
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/sensors/{name}": {
+      "get": {
+        "summary": "Get a logical sensor",
+        "parameters": [
+          {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "The sensor",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Sensor"}}}
+          },
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/series": {
+      "get": {
+        "summary": "Get the samples of a key over a time range",
+        "parameters": [
+          {"$ref": "#/components/parameters/key"},
+          {"$ref": "#/components/parameters/sensor"},
+          {"$ref": "#/components/parameters/id"},
+          {"$ref": "#/components/parameters/channel"},
+          {"$ref": "#/components/parameters/start"},
+          {"$ref": "#/components/parameters/end"},
+          {
+            "name": "resolution",
+            "in": "query",
+            "description": "The interval to combine samples into, as a duration like 30m, \"raw\" for the stored samples, or \"auto\" to pick one from the length of the range.",
+            "schema": {"type": "string", "default": "auto"}
+          },
+          {"$ref": "#/components/parameters/aggregate"},
+          {"$ref": "#/components/parameters/units"}
+        ],
+        "responses": {
+          "200": {
+            "description": "The series",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Series"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/windrose": {
+      "get": {
+        "summary": "Get a wind speed key by wind direction over a time range",
+        "parameters": [
+          {"$ref": "#/components/parameters/key"},
+          {"$ref": "#/components/parameters/sensor"},
+          {"$ref": "#/components/parameters/id"},
+          {"$ref": "#/components/parameters/channel"},
+          {"$ref": "#/components/parameters/start"},
+          {"$ref": "#/components/parameters/end"},
+          {"$ref": "#/components/parameters/aggregate"},
+          {"$ref": "#/components/parameters/units"}
+        ],
+        "responses": {
+          "200": {
+            "description": "The wind rose",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/WindRose"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/delta": {
+      "get": {
+        "summary": "Get the change in a key over a time range",
+        "parameters": [
+          {"$ref": "#/components/parameters/key"},
+          {"$ref": "#/components/parameters/sensor"},
+          {"$ref": "#/components/parameters/id"},
+          {"$ref": "#/components/parameters/channel"},
+          {"$ref": "#/components/parameters/start"},
+          {"$ref": "#/components/parameters/end"},
+          {"$ref": "#/components/parameters/units"}
+        ],
+        "responses": {
+          "200": {
+            "description": "The change",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Delta"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    }
+  },
+  "components": {
+    "parameters": {
+      "key": {"name": "key", "in": "query", "required": true, "description": "The key, e.g. Temperature.", "schema": {"type": "string"}},
+      "sensor": {"name": "sensor", "in": "query", "description": "The logical sensor name.", "schema": {"type": "string"}},
+      "id": {"name": "id", "in": "query", "description": "The physical sensor ID, or a logical sensor name. SQL LIKE patterns are allowed.", "schema": {"type": "string", "default": "%"}},
+      "channel": {"name": "channel", "in": "query", "description": "The sensor channel, any by default.", "schema": {"type": "integer"}},
+      "start": {"name": "start", "in": "query", "description": "The start of the range, 24 hours before the end by default.", "schema": {"type": "string", "format": "date-time"}},
+      "end": {"name": "end", "in": "query", "description": "The end of the range, now by default.", "schema": {"type": "string", "format": "date-time"}},
+      "aggregate": {"name": "aggregate", "in": "query", "description": "The column of combined samples to use.", "schema": {"type": "string", "enum": ["avg", "min", "max"], "default": "avg"}},
+      "units": {"name": "units", "in": "query", "description": "\"raw\" for the units values are stored in, the configured units otherwise.", "schema": {"type": "string", "enum": ["raw"]}}
+    },
+    "responses": {
+      "Error": {
+        "description": "An error",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}
+      }
+    },
+    "schemas": {
+      "Station": {
+        "type": "object",
+        "properties": {
+          "Name": {"type": "string"},
+          "Latitude": {"type": "number"},
+          "Longitude": {"type": "number"},
+          "Elevation": {"type": "number", "description": "Meters above sea level."},
+          "Timezone": {"type": "string"},
+          "Units": {"type": "object", "additionalProperties": {"type": "string"}}
+        }
+      },
+      "Error": {
+        "type": "object",
+        "properties": {
+          "Status": {"type": "integer"},
+          "Error": {"type": "string"}
+        }
+      },
+      "SensorID": {
+        "type": "object",
+        "properties": {
+          "ID": {"type": "string"},
+          "Channel": {"type": "integer"},
+          "Serial": {"type": "string"},
+          "Station": {"type": "string"}
+        }
+      },
+      "Sensor": {
+        "type": "object",
+        "properties": {
+          "Name": {"type": "string"},
+          "Location": {"type": "string"},
+          "Height": {"type": "number"},
+          "Retired": {"type": "integer", "description": "Unix time the sensor was retired, 0 if it is active."},
+          "IDs": {"type": "array", "items": {"$ref": "#/components/schemas/SensorID"}}
+        }
+      },
+      "Sensors": {
+        "type": "object",
+        "properties": {
+          "Sensors": {"type": "array", "items": {"$ref": "#/components/schemas/Sensor"}},
+          "Physical": {"type": "array", "items": {"$ref": "#/components/schemas/SensorID"}}
+        }
+      },
+      "Series": {
+        "type": "object",
+        "properties": {
+          "Start": {"type": "string", "format": "date-time"},
+          "End": {"type": "string", "format": "date-time"},
+          "Key": {"type": "string"},
+          "Unit": {"type": "string"},
+          "Aggregate": {"type": "string"},
+          "Resolution": {"type": "integer", "description": "Seconds, 0 for the stored samples."},
+          "Points": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "Time": {"type": "string", "format": "date-time"},
+                "Value": {"type": "number"},
+                "Min": {"type": "number"},
+                "Max": {"type": "number"}
+              }
+            }
+          }
+        }
+      },
+      "WindRose": {
+        "type": "object",
+        "properties": {
+          "Start": {"type": "string", "format": "date-time"},
+          "End": {"type": "string", "format": "date-time"},
+          "Key": {"type": "string"},
+          "Unit": {"type": "string"},
+          "Aggregate": {"type": "string"},
+          "Sectors": {"type": "array", "items": {"type": "number"}, "minItems": 32, "maxItems": 32, "description": "Clockwise from north."}
+        }
+      },
+      "Delta": {
+        "type": "object",
+        "properties": {
+          "Start": {"type": "string", "format": "date-time"},
+          "End": {"type": "string", "format": "date-time"},
+          "Key": {"type": "string"},
+          "Unit": {"type": "string"},
+          "First": {"type": "number"},
+          "Last": {"type": "number"},
+          "Change": {"type": "number"}
+        }
+      }
+    }
+  }
+}
+`
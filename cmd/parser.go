@@ -4,15 +4,13 @@ package cmd
 
 import (
 	"bufio"
-	"bytes"
 	"encoding/json"
-	"fmt"
 	"io"
 	"os"
 	"strings"
 	"time"
 
-	MQTT "github.com/eclipse/paho.mqtt.golang"
+	"github.com/geoffholden/gowx/bus"
 	"github.com/geoffholden/gowx/data"
 	"github.com/spf13/cobra"
 	jww "github.com/spf13/jwalterweatherman"
@@ -27,7 +25,7 @@ import (
 var parserCmd = &cobra.Command{
 	Use:   "parser",
 	Short: "Parse serial data",
-	Long:  `Parses the serial data coming from the WSDL sheild and sends it to the MQTT broker.`,
+	Long:  `Parses the serial data coming from the WSDL sheild and publishes it on the bus.`,
 	Run:   parser,
 }
 
@@ -45,7 +43,7 @@ func init() {
 	viper.BindPFlags(parserCmd.Flags())
 }
 
-func loop(reader io.Reader, client MQTT.Client) {
+func loop(reader io.Reader, b bus.Bus) {
 	channel := make(chan data.SensorData)
 	scanner := bufio.NewScanner(reader)
 	go func() {
@@ -61,20 +59,31 @@ func loop(reader io.Reader, client MQTT.Client) {
 		close(channel)
 	}()
 
+	// Samples are queued while they can't be published, and keep their
+	// timestamps, so the aggregator can still put them in the right interval.
+	station := viper.GetString("station")
+	topics := stationTopics()
+	samples := newForwarder("parser", func(payload []byte) error {
+		return b.Publish(topics.samples(), payload, false)
+	})
 	for data := range channel {
-		topic := "/gowx/sample"
-		buf := new(bytes.Buffer)
-		encoder := json.NewEncoder(buf)
-		encoder.Encode(data)
-		payload := buf.Bytes()
-		if token := client.Publish(topic, 0, false, payload); token.Wait() && token.Error() != nil {
-			jww.ERROR.Println("Failed to send message.", token.Error())
+		data.Station = station
+		payload, err := json.Marshal(data)
+		if err != nil {
+			jww.ERROR.Println(err)
+			continue
+		}
+		jww.DEBUG.Printf("Publishing %s -> %s\n", topics.samples(), payload)
+		if err := samples.Forward(payload); err != nil {
+			jww.ERROR.Println("Failed to queue message.", err)
+		}
+		for key, value := range data.Data {
+			publishValue(b, topics.value(data.Key(), key), value)
 		}
-		jww.DEBUG.Printf("Publishing %s -> %s\n", topic, buf.Bytes())
 	}
 }
 
-func serialLoop(client MQTT.Client) {
+func serialLoop(b bus.Bus) {
 	m := &serial.Mode{
 		BaudRate: viper.GetInt("baud"),
 	}
@@ -90,26 +99,22 @@ func serialLoop(client MQTT.Client) {
 	time.Sleep(1 * time.Second)
 	s.SetDTR(false)
 	s.SetRTS(false)
-	loop(s, client)
+	loop(s, b)
 }
 
 func parser(cmd *cobra.Command, args []string) {
 	if verbose {
 		jww.SetStdoutThreshold(jww.LevelTrace)
 	}
-	hostname, err := os.Hostname()
+	serveMetrics()
+	b, err := openBus("parser", nil)
 	if err != nil {
-		hostname = "unknown"
-	}
-	clientid := fmt.Sprintf("gowx-parser-%s-%d", hostname, os.Getpid())
-	opts := MQTT.NewClientOptions().AddBroker(viper.GetString("broker")).SetClientID(clientid).SetCleanSession(true)
-
-	client := MQTT.NewClient(opts)
-	if token := client.Connect(); token.Wait() && token.Error() != nil {
-		jww.FATAL.Println(token.Error())
-		panic(token.Error())
+		jww.FATAL.Println(err)
+		panic(err)
 	}
-	defer client.Disconnect(0)
+	defer closeBus(b, "parser")
+	// The pressure is reduced to sea level with the station's elevation.
+	sensors.SetElevation(viper.GetFloat64("elevation"))
 
 	fi, err := os.Stat(viper.GetString("port"))
 	if err != nil {
@@ -117,7 +122,7 @@ func parser(cmd *cobra.Command, args []string) {
 		panic(err)
 	}
 	if fi.Mode()&os.ModeType != 0 {
-		serialLoop(client)
+		serialLoop(b)
 	} else {
 		file, err := os.Open(viper.GetString("port"))
 		if err != nil {
@@ -125,6 +130,6 @@ func parser(cmd *cobra.Command, args []string) {
 			panic(err)
 		}
 		defer file.Close()
-		loop(file, client)
+		loop(file, b)
 	}
 }
@@ -0,0 +1,111 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/geoffholden/gowx/bus"
+	"github.com/geoffholden/gowx/data"
+	"github.com/spf13/viper"
+)
+
+// TestPipeline runs samples from the parser through the aggregator into the
+// database over a local bus.
+func TestPipeline(t *testing.T) {
+	viper.Set("dbDriver", "sqlite3")
+	viper.Set("database", filepath.Join(t.TempDir(), "gowx.db"))
+	viper.Set("queue.dir", filepath.Join(t.TempDir(), "queue"))
+	db, err := data.OpenDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	b := bus.NewLocal()
+	defer b.Close()
+	samples := make(chan data.SensorData, 4)
+	b.Subscribe(subscribeTopics().samples(), func(m bus.Message) {
+		var d data.SensorData
+		if err := json.NewDecoder(bytes.NewReader(m.Payload)).Decode(&d); err != nil {
+			t.Error(err)
+		}
+		samples <- d
+	})
+
+	loop(strings.NewReader("OS3:1D20485C480882835\nnoise\nOS3:1D2016B1091073A14\n"), b)
+
+	thedata := make(map[mapKey][]float64)
+	for i := 0; i < 2; i++ {
+		select {
+		case d := <-samples:
+			addData(&thedata, d)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for samples")
+		}
+	}
+	res := sumData(&thedata, time.Now().Unix())
+	publishData(res, db, b)
+
+	temperatures := make(map[float64]bool)
+	for row := range db.QueryRows(0, "Temperature", data.SensorID{ID: "OS3:1D20", Channel: -1}) {
+		if row.Min != row.Avg || row.Max != row.Avg {
+			t.Error("Unexpected row", row)
+		}
+		temperatures[row.Avg] = true
+	}
+	if len(temperatures) != 2 || !temperatures[-8.4] || !temperatures[19] {
+		t.Error("Unexpected temperatures", temperatures)
+	}
+
+	for _, d := range res {
+		m, ok := b.Retained(stationTopics().aggregatedValue(d.Key.sensor(), d.Key.Key))
+		if !ok || string(m.Payload) != strconv.FormatFloat(d.Avg, 'f', -1, 64) {
+			t.Error("Unexpected aggregated value of", d.Key, string(m.Payload))
+		}
+	}
+}
+
+func TestIntervalEnd(t *testing.T) {
+	ts := time.Date(2026, 10, 19, 12, 7, 30, 0, time.UTC)
+	if end := intervalEnd(ts, 5*time.Minute); end != time.Date(2026, 10, 19, 12, 10, 0, 0, time.UTC).Unix() {
+		t.Error("Unexpected interval end", time.Unix(end, 0).UTC())
+	}
+}
+
+func TestChartBreak(t *testing.T) {
+	ids := []data.SensorID{{ID: "OS3:1D20", Channel: 1}}
+	gaps := []data.Gap{{SensorKey: data.SensorKey{ID: "OS3:1D20", Channel: 1}, Start: 1000, End: 1700}}
+	if chartBreak(0, 300, 300, nil, ids) {
+		t.Error("Consecutive rows shouldn't break")
+	}
+	if !chartBreak(0, 900, 300, nil, ids) {
+		t.Error("Missing rows should break")
+	}
+	if !chartBreak(900, 1800, 600, gaps, ids) {
+		t.Error("A recorded gap should break")
+	}
+	if chartBreak(900, 1800, 900, gaps, ids) || chartBreak(900, 1800, 600, gaps, []data.SensorID{{ID: "VN1:6D27", Channel: -1}}) {
+		t.Error("Unexpected break")
+	}
+
+	// The sensor was last seen a little before one row, and back a little
+	// before the next, as is usual with aggregates at the end of their
+	// intervals.
+	gaps = []data.Gap{{SensorKey: data.SensorKey{ID: "OS3:1D20", Channel: 1, Serial: "A4"}, Start: 1130, End: 1480}}
+	if !chartBreak(1200, 1500, 300, gaps, ids) {
+		t.Error("A gap overlapping the rows should break")
+	}
+	if chartBreak(1500, 1800, 300, gaps, ids) || chartBreak(900, 1100, 300, gaps, ids) {
+		t.Error("A gap outside the rows shouldn't break")
+	}
+	if chartBreak(1200, 1500, 300, gaps, []data.SensorID{{ID: "OS3:1D20", Channel: 1, Serial: "B7"}}) {
+		t.Error("A gap of another serial shouldn't break")
+	}
+}
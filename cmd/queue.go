@@ -0,0 +1,46 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/geoffholden/gowx/queue"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	// The default directory is relative to the working directory.
+	viper.SetDefault("queue.dir", "gowx-queue")
+	viper.SetDefault("queue.size", 10000)
+	viper.SetDefault("queue.retry", 30)
+}
+
+// newForwarder returns a forwarder that queues what can't be sent under
+// the "queue.dir" directory, and retries every "queue.retry" seconds. With
+// an empty "queue.dir" nothing is queued. Each component has a directory of
+// its own, under a directory for the station if it's named, so the stations
+// sharing a "queue.dir" don't share queues.
+func newForwarder(name string, send func(item []byte) error) *queue.Forwarder {
+	f := &queue.Forwarder{
+		Send: send,
+		OnError: func(err error) {
+			jww.ERROR.Println("Failed to send, queueing.", err)
+		},
+	}
+	if dir := viper.GetString("queue.dir"); dir != "" {
+		q, err := queue.Open(filepath.Join(dir, topicLevel(viper.GetString("station"), ""), name), viper.GetInt("queue.size"))
+		if err != nil {
+			jww.ERROR.Println(err)
+		} else {
+			if n := q.Len(); n > 0 {
+				jww.INFO.Printf("%d queued messages for %s\n", n, name)
+			}
+			f.Queue = q
+			go f.Run(time.Duration(viper.GetInt("queue.retry"))*time.Second, nil)
+		}
+	}
+	return f
+}
@@ -0,0 +1,142 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/records"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// recordsCmd represents the records command
+var recordsCmd = &cobra.Command{
+	Use:   "records",
+	Short: "Show weather records",
+	Long: `Shows the highs, lows and totals for a day, month, year or all time,
+from the daily summaries maintained by the aggregator.`,
+	Run: recordsList,
+}
+
+func init() {
+	RootCmd.AddCommand(recordsCmd)
+	recordsCmd.Flags().String("period", "month", "One of day, month, year or all")
+	recordsCmd.Flags().String("date", "", "Day (2006-01-02), month (2006-01) or year (2006), default is the current one")
+	recordsCmd.Flags().String("key", "", "Only show records for this key")
+
+	viper.SetDefault("cumulative", []string{"RainTotal"})
+}
+
+func newRecordTracker(db *data.Database) *records.Tracker {
+	tracker := records.NewTracker(db, stationLocation())
+	tracker.StationLocation = stationLocationOf
+	tracker.Cumulative = cumulativeKeys()
+	return tracker
+}
+
+func cumulativeKeys() map[string]bool {
+	result := make(map[string]bool)
+	for _, key := range viper.GetStringSlice("cumulative") {
+		result[key] = true
+	}
+	return result
+}
+
+// recordAggregates converts aggregates for the tracker, naming each sensor
+// from the registry of its station.
+func recordAggregates(db *data.Database, res []aggdata) []records.Aggregate {
+	result := make([]records.Aggregate, len(res))
+	for i, d := range res {
+		name, _ := db.Station(d.Key.Station).Registry().Name(d.Key.sensor())
+		result[i] = records.Aggregate{
+			Timestamp: d.Timestamp,
+			Sensor:    d.Key.sensor(),
+			Key:       d.Key.Key,
+			Min:       d.Min,
+			Max:       d.Max,
+			Avg:       d.Avg,
+			Name:      name,
+			Station:   d.Key.Station,
+		}
+	}
+	return result
+}
+
+func queryRecords(db *data.Database, period string, date string, key string) ([]records.Record, error) {
+	first, last, err := records.Period(period, date, time.Now().In(stationLocationOf(db.StationName())))
+	if err != nil {
+		return nil, err
+	}
+	summaries, err := db.DailySummaries(first, last, key)
+	if err != nil {
+		return nil, err
+	}
+	result := records.Compute(summaries, cumulativeKeys())
+	for i := range result {
+		if result[i].Name == "" {
+			result[i].Name, _ = db.Station(result[i].Station).Registry().Name(result[i].SensorKey)
+		}
+	}
+	return result, nil
+}
+
+func recordsHandler(w http.ResponseWriter, r *http.Request, db *data.Database) {
+	period := r.FormValue("period")
+	if period == "" {
+		period = "month"
+	}
+	result, err := queryRecords(db, period, r.FormValue("date"), r.FormValue("key"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if result == nil {
+		result = []records.Record{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func recordsList(cmd *cobra.Command, args []string) {
+	db := openSensorDatabase()
+	defer db.Close()
+
+	period, _ := cmd.Flags().GetString("period")
+	date, _ := cmd.Flags().GetString("date")
+	key, _ := cmd.Flags().GetString("key")
+	result, err := queryRecords(db, period, date, key)
+	if err != nil {
+		fatal(err)
+	}
+
+	loc := stationLocation()
+	timestamp := func(t int64) string {
+		return time.Unix(t, 0).In(loc).Format("2006-01-02 15:04")
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "SENSOR\tKEY\tHIGH\tWHEN\tLOW\tWHEN\tMEAN\tTOTAL\tDAYS")
+	for _, r := range result {
+		sensor := r.Name
+		if sensor == "" {
+			sensor = fmt.Sprintf("%s/%d/%s", r.ID, r.Channel, r.Serial)
+		}
+		high := fmt.Sprintf("%.1f", r.High)
+		if r.HighDir != nil {
+			high += fmt.Sprintf(" (%.0f°)", *r.HighDir)
+		}
+		total := ""
+		if r.Total != 0 || r.HighTotalDay != "" {
+			total = fmt.Sprintf("%.1f", r.Total)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%.1f\t%s\t%.1f\t%s\t%d\n",
+			sensor, r.Key, high, timestamp(r.HighTime), r.Low, timestamp(r.LowTime), r.Mean, total, r.Days)
+	}
+	w.Flush()
+}
@@ -0,0 +1,155 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/reports"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Generate reports",
+	Long:  `Generates reports from the daily summaries maintained by the aggregator.`,
+}
+
+var reportNOAACmd = &cobra.Command{
+	Use:   "noaa",
+	Short: "NOAA-style climatological summary",
+	Long: `Writes a NOAA-style monthly or yearly climatological summary, with the
+daily temperature, degree days, rain and wind.
+
+The sensors used are selected in the "noaa" section of the configuration,
+e.g. "noaa: {temperature: {sensor: outdoor}}", with the keys temperature,
+rain, wind, gust and direction. The temperature sensor defaults to the one
+selected in "current_data", and one of them must name a sensor.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		month, _ := cmd.Flags().GetString("month")
+		year, _ := cmd.Flags().GetString("year")
+
+		db := openSensorDatabase()
+		defer db.Close()
+		if err := renderNOAA(os.Stdout, db, month, year); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(reportCmd)
+	reportCmd.AddCommand(reportNOAACmd)
+	reportNOAACmd.Flags().String("month", "", "Month of the report (2006-01), default is the current month")
+	reportNOAACmd.Flags().String("year", "", "Year of a yearly report (2006)")
+
+	viper.SetDefault("noaa.rain", map[string]string{"type": "RainTotal"})
+	viper.SetDefault("noaa.wind", map[string]string{"type": "AverageWind"})
+	viper.SetDefault("noaa.gust", map[string]string{"type": "CurrentWind"})
+	viper.SetDefault("noaa.direction", map[string]string{"type": "WindDir"})
+}
+
+// newNOAA returns the NOAA report of a station, with its name, elevation,
+// time zone and units.
+func newNOAA(reg *data.Registry, station string) *reports.NOAA {
+	n := reports.NewNOAA(stationLocationOf(station))
+	n.Elevation = settingFloat(stationSetting(station, "elevation"))
+	noaa, _ := settingMap(stationSetting(station, "noaa"))
+	if name, ok := noaa["name"]; ok && name != nil {
+		n.Name = fmt.Sprint(name)
+	}
+	if base, ok := noaa["heating_base"]; ok {
+		n.HeatingBase = settingFloat(base)
+	}
+	if base, ok := noaa["cooling_base"]; ok {
+		n.CoolingBase = settingFloat(base)
+	}
+
+	unitmap := stationUnits(station)
+	if u, ok := unitmap["temperature"]; ok {
+		n.Units.Temperature = u
+	}
+	if u, ok := unitmap["rain"]; ok {
+		n.Units.Rain = u
+	}
+	if u, ok := unitmap["windspeed"]; ok {
+		n.Units.Wind = u
+	}
+
+	for name, series := range map[string]*reports.Series{
+		"temperature": &n.Temperature,
+		"rain":        &n.Rain,
+		"wind":        &n.Wind,
+		"gust":        &n.Gust,
+		"direction":   &n.Direction,
+	} {
+		query := viper.GetStringMapString("noaa." + name)
+		if name == "temperature" {
+			if query = noaaTemperature(); query == nil {
+				continue
+			}
+		}
+		if t, ok := query["type"]; ok {
+			series.Key = t
+		}
+		series.Sensors = querySensors(query, reg)
+	}
+	return n
+}
+
+// noaaTemperature returns the selection of the temperature sensor, or nil
+// if neither "noaa" nor "current_data" names one. Matching any sensor would
+// mix the indoor and outdoor temperatures.
+func noaaTemperature() map[string]string {
+	for _, key := range []string{"noaa.temperature", "current_data.temperature"} {
+		query := viper.GetStringMapString(key)
+		if _, ok := query["sensor"]; ok {
+			return query
+		}
+		if _, ok := query["id"]; ok {
+			return query
+		}
+	}
+	return nil
+}
+
+// renderNOAA writes the yearly report if year is given, otherwise the
+// monthly report for month, or the current month if it is empty.
+func renderNOAA(w io.Writer, db *data.Database, month string, year string) error {
+	n := newNOAA(db.Registry(), db.StationName())
+	if year != "" {
+		y, err := strconv.Atoi(year)
+		if err != nil {
+			return errors.New("invalid year " + strconv.Quote(year))
+		}
+		return n.Year(w, db, y)
+	}
+	m := time.Now().In(n.Location)
+	if month != "" {
+		var err error
+		if m, err = time.ParseInLocation("2006-01", month, n.Location); err != nil {
+			return err
+		}
+	}
+	return n.Month(w, db, m)
+}
+
+func noaaHandler(w http.ResponseWriter, r *http.Request, db *data.Database) {
+	var buf bytes.Buffer
+	if err := renderNOAA(&buf, db, r.FormValue("month"), r.FormValue("year")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	buf.WriteTo(w)
+}
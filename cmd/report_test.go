@@ -0,0 +1,82 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/geoffholden/gowx/data"
+)
+
+func TestNOAATemperatureSensor(t *testing.T) {
+	viper.Set("dbDriver", "sqlite3")
+	viper.Set("database", filepath.Join(t.TempDir(), "gowx.db"))
+	db, err := data.OpenDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	defer viper.Set("timezone", viper.Get("timezone"))
+	viper.Set("timezone", "UTC")
+
+	indoor := data.SensorKey{ID: "BMP", Channel: 0, Serial: "0"}
+	outdoor := data.SensorKey{ID: "OS3:1D20", Channel: 1, Serial: "A4"}
+	for _, s := range []data.DailySummary{
+		{Day: "2026-09-01", SensorKey: indoor, Key: "Temperature", High: 22, Low: 21, MeanSum: 21.5, MeanCount: 1},
+		{Day: "2026-09-01", SensorKey: outdoor, Key: "Temperature", High: 18, Low: 6, MeanSum: 12, MeanCount: 1},
+	} {
+		s.HighDir = math.NaN()
+		if err := db.SaveDailySummary(s, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := renderNOAA(&buf, db, "2026-09", ""); err == nil {
+		t.Error("The default configuration should not pick a temperature sensor", buf.String())
+	}
+
+	viper.Set("current_data", map[string]interface{}{
+		"temperature": map[string]string{"id": "OS3:1D20", "type": "Temperature"},
+	})
+	defer viper.Set("current_data", nil)
+	n := newNOAA(db.Registry(), "")
+	days, err := n.Days(db, time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil || len(days) != 1 || days[0].Temperature == nil || days[0].Temperature.SensorKey != outdoor {
+		t.Error("Unexpected temperature", days, err)
+	}
+}
+
+func TestNOAAStation(t *testing.T) {
+	viper.Set("dbDriver", "sqlite3")
+	viper.Set("database", filepath.Join(t.TempDir(), "gowx.db"))
+	db, err := data.OpenDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	defer viper.Set("stations", nil)
+	defer viper.Set("elevation", viper.Get("elevation"))
+	defer viper.Set("noaa.name", viper.Get("noaa.name"))
+	viper.Set("elevation", 100)
+	viper.Set("noaa.name", "Home")
+	viper.Set("stations", map[string]interface{}{
+		"cottage": map[string]interface{}{
+			"elevation": 320.5,
+			"noaa":      map[string]interface{}{"name": "Cottage"},
+		},
+	})
+
+	if n := newNOAA(db.Registry(), "cottage"); n.Name != "Cottage" || n.Elevation != 320.5 {
+		t.Error("Unexpected station", n.Name, n.Elevation)
+	}
+	if n := newNOAA(db.Registry(), ""); n.Name != "Home" || n.Elevation != 100 {
+		t.Error("Unexpected station", n.Name, n.Elevation)
+	}
+}
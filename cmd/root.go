@@ -5,6 +5,7 @@ package cmd
 import (
 	"os"
 	"strings"
+	"time"
 
 	"github.com/geoffholden/gowx/data"
 	"github.com/spf13/cobra"
@@ -47,7 +48,13 @@ func init() {
 
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is gowx.yaml)")
 	RootCmd.PersistentFlags().String("broker", "tcp://localhost:1883", "MQTT Server")
+	RootCmd.PersistentFlags().String("transport", "", "Message transport, mqtt or local (default is local for all, mqtt otherwise)")
+	RootCmd.PersistentFlags().String("topicPrefix", "gowx", "Prefix of the MQTT topics")
+	RootCmd.PersistentFlags().String("station", "", "Station name, used to separate stations sharing a broker")
+	RootCmd.PersistentFlags().String("subscribe", "", "Station to receive samples from, or + for all stations (default is --station)")
 	RootCmd.PersistentFlags().String("database", "gowx.db", "Database")
+	RootCmd.PersistentFlags().String("timezone", "", "Station time zone, e.g. America/Toronto (default is the system time zone)")
+	RootCmd.PersistentFlags().String("metrics", "", "Address to serve the Prometheus metrics of the process on, e.g. :9100 (default is none)")
 	RootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
 
 	dbdrivers := data.DBDrivers()
@@ -77,4 +84,23 @@ func initConfig() {
 	if err := viper.ReadInConfig(); err == nil {
 		jww.DEBUG.Println("Using config file:", viper.ConfigFileUsed())
 	}
+	useStationConfig()
+}
+
+// stationLocation returns the time zone used for the station's day boundary.
+func stationLocation() *time.Location {
+	return loadLocation(viper.GetString("timezone"))
+}
+
+// loadLocation returns the named time zone, or the system's if it's empty
+// or unknown.
+func loadLocation(name string) *time.Location {
+	if name != "" {
+		loc, err := time.LoadLocation(name)
+		if err == nil {
+			return loc
+		}
+		jww.ERROR.Println(err)
+	}
+	return time.Local
 }
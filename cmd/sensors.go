@@ -0,0 +1,268 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/geoffholden/gowx/data"
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// sensorsCmd represents the sensors command
+var sensorsCmd = &cobra.Command{
+	Use:   "sensors",
+	Short: "Manage the sensor registry",
+	Long: `Manages the sensor registry, which maps physical sensor IDs to stable
+logical names with a location and mounting height.
+
+Logical names can be used anywhere a sensor is selected in the
+configuration, either as "sensor: name" or in place of the "id".`,
+	Run: sensorsList,
+}
+
+var sensorsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List registered sensors",
+	Run:   sensorsList,
+}
+
+var sensorsAliasCmd = &cobra.Command{
+	Use:   "alias NAME ID CHANNEL [SERIAL]",
+	Short: "Register a physical sensor under a logical name",
+	Long: `Registers a physical sensor under a logical name, creating the logical
+sensor if it doesn't exist. Without a serial number, any serial number on the
+given ID and channel matches. The sensor is registered for the station in
+the "station" setting, and only matches that station's samples.`,
+	Args: cobra.RangeArgs(3, 4),
+	Run: func(cmd *cobra.Command, args []string) {
+		channel, err := strconv.Atoi(args[2])
+		if err != nil {
+			fatal(err)
+		}
+		db := openSensorDatabase()
+		defer db.Close()
+		id := data.SensorID{ID: args[1], Channel: channel, Station: db.StationName()}
+		if len(args) > 3 {
+			id.Serial = args[3]
+		}
+		if err := db.AliasSensor(args[0], id); err != nil {
+			fatal(err)
+		}
+		setSensorFlags(cmd, db, args[0])
+	},
+}
+
+var sensorsSetCmd = &cobra.Command{
+	Use:   "set NAME",
+	Short: "Set the location and height of a sensor",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := openSensorDatabase()
+		defer db.Close()
+		if _, ok := db.Registry().Lookup(args[0]); !ok {
+			fatal(fmt.Errorf("unknown sensor %q", args[0]))
+		}
+		setSensorFlags(cmd, db, args[0])
+	},
+}
+
+var sensorsRenameCmd = &cobra.Command{
+	Use:   "rename OLD NEW",
+	Short: "Rename a logical sensor",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := openSensorDatabase()
+		defer db.Close()
+		if err := db.RenameSensor(args[0], args[1]); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+var sensorsMergeCmd = &cobra.Command{
+	Use:   "merge FROM INTO",
+	Short: "Merge one logical sensor into another",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := openSensorDatabase()
+		defer db.Close()
+		if err := db.MergeSensors(args[0], args[1]); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+var sensorsRetireCmd = &cobra.Command{
+	Use:   "retire NAME",
+	Short: "Mark a logical sensor as out of service",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := openSensorDatabase()
+		defer db.Close()
+		if err := db.RetireSensor(args[0], time.Now()); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(sensorsCmd)
+	sensorsCmd.AddCommand(sensorsListCmd, sensorsAliasCmd, sensorsSetCmd, sensorsRenameCmd, sensorsMergeCmd, sensorsRetireCmd)
+
+	for _, c := range []*cobra.Command{sensorsAliasCmd, sensorsSetCmd} {
+		c.Flags().String("location", "", "Location of the sensor, e.g. indoor, outdoor or greenhouse")
+		c.Flags().Float64("height", 0, "Mounting height of the sensor, in meters")
+	}
+	sensorsListCmd.Flags().Bool("all", false, "Include physical sensors that aren't registered")
+	sensorsCmd.Flags().AddFlagSet(sensorsListCmd.Flags())
+}
+
+func fatal(err error) {
+	jww.FATAL.Println(err)
+	os.Exit(-1)
+}
+
+func openSensorDatabase() *data.Database {
+	db, err := data.OpenDatabase()
+	if err != nil {
+		fatal(err)
+	}
+	return db
+}
+
+func setSensorFlags(cmd *cobra.Command, db *data.Database, name string) {
+	if !cmd.Flags().Changed("location") && !cmd.Flags().Changed("height") {
+		return
+	}
+	s, _ := db.Registry().Lookup(name)
+	if cmd.Flags().Changed("location") {
+		s.Location, _ = cmd.Flags().GetString("location")
+	}
+	if cmd.Flags().Changed("height") {
+		s.Height, _ = cmd.Flags().GetFloat64("height")
+	}
+	if err := db.SetSensor(name, s.Location, s.Height); err != nil {
+		fatal(err)
+	}
+}
+
+func sensorsList(cmd *cobra.Command, args []string) {
+	db := openSensorDatabase()
+	defer db.Close()
+
+	sensors, err := db.Sensors()
+	if err != nil {
+		fatal(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tLOCATION\tHEIGHT\tSTATUS\tSENSORS")
+	for _, s := range sensors {
+		status := "active"
+		if s.Retired != 0 {
+			status = "retired " + time.Unix(s.Retired, 0).Format("2006-01-02")
+		}
+		ids := make([]string, len(s.IDs))
+		for i, id := range s.IDs {
+			ids[i] = id.String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%g\t%s\t%s\n", s.Name, s.Location, s.Height, status, strings.Join(ids, ", "))
+	}
+
+	if all, _ := cmd.Flags().GetBool("all"); all {
+		physical, err := db.Station(data.AllStations).PhysicalSensors()
+		if err != nil {
+			fatal(err)
+		}
+		for _, id := range physical {
+			if _, ok := db.Station(id.Station).Registry().Name(data.SensorKey{ID: id.ID, Channel: id.Channel, Serial: id.Serial}); !ok {
+				fmt.Fprintf(w, "-\t\t\tunregistered\t%s\n", id.String())
+			}
+		}
+	}
+	w.Flush()
+}
+
+// querySensors resolves the sensor selection of a chart or upload query into
+// the sensors to query. The sensor can be given by logical name, either as
+// "sensor" or in place of the "id". A negative channel matches any channel.
+func querySensors(querymap map[string]string, reg *data.Registry) []data.SensorID {
+	name, ok := querymap["sensor"]
+	if !ok {
+		if _, found := reg.Lookup(querymap["id"]); found {
+			name, ok = querymap["id"], true
+		}
+	}
+	if ok {
+		// Each physical sensor is queried with the serial number it was
+		// registered with, so other sensors that happen to share its ID and
+		// channel aren't included.
+		s, _ := reg.Lookup(name)
+		return s.IDs
+	}
+
+	id := data.SensorID{ID: "%", Channel: -1}
+	if x, ok := querymap["id"]; ok {
+		id.ID = x
+	}
+	if x, ok := querymap["channel"]; ok {
+		if channel, err := strconv.Atoi(x); err == nil {
+			id.Channel = channel
+		}
+	}
+	return []data.SensorID{id}
+}
+
+// sensorMatch reports whether a physical sensor is selected by a query.
+func sensorMatch(query map[string]string, key data.SensorKey, reg *data.Registry) bool {
+	if name, ok := query["sensor"]; ok {
+		return reg.Matches(name, key)
+	}
+	if t, ok := query["id"]; ok {
+		if _, found := reg.Lookup(t); found {
+			return reg.Matches(t, key)
+		}
+		if key.ID != t {
+			return false
+		}
+	}
+	if t, ok := query["channel"]; ok {
+		x, err := strconv.Atoi(t)
+		if err != nil || key.Channel != x {
+			return false
+		}
+	}
+	if t, ok := query["serial"]; ok {
+		if key.Serial != t {
+			return false
+		}
+	}
+	return true
+}
+
+// querySeries returns the rows for a key from every sensor between start and
+// end, merged in time order. An end of zero means now.
+func querySeries(db *data.Database, start int64, end int64, key string, ids []data.SensorID, interval int64) ([]data.Row, error) {
+	var rows []data.Row
+	for _, id := range ids {
+		r, err := db.Series(start, end, key, id, interval)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, r...)
+	}
+	if len(ids) > 1 {
+		sort.SliceStable(rows, func(i, j int) bool {
+			return rows[i].Timestamp < rows[j].Timestamp
+		})
+	}
+	return rows, nil
+}
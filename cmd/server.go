@@ -5,18 +5,19 @@ package cmd
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"html/template"
 	"net"
 	"net/http"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
-	MQTT "github.com/eclipse/paho.mqtt.golang"
+	"github.com/geoffholden/gowx/bus"
 	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/live"
+	"github.com/geoffholden/gowx/metrics"
 	"github.com/geoffholden/gowx/units"
 	"github.com/spf13/cobra"
 	jww "github.com/spf13/jwalterweatherman"
@@ -34,7 +35,8 @@ var serverCmd = &cobra.Command{
 
 func serverInit() {
 	if !serverCmd.Flags().HasFlags() {
-		serverCmd.Flags().String("webroot", "web", "Root directory for the web server.")
+		serverCmd.Flags().String("webroot", "", "Directory of web files to use in place of the built-in ones.")
+		serverCmd.Flags().Bool("dev", false, "Reload the templates in the webroot when they change.")
 		serverCmd.Flags().String("address", ":0", "Address and port to listen on.")
 	}
 }
@@ -62,105 +64,68 @@ func server(cmd *cobra.Command, args []string) {
 	if verbose {
 		jww.SetStdoutThreshold(jww.LevelTrace)
 	}
-	var currentData struct {
-		Temperature float64
-		Humidity    float64
-		Pressure    float64
-		Wind        float64
-		WindDir     float64
-		RainRate    float64
-	}
-
+	serveMetrics()
 	sensordata := make(chan data.SensorData, 1)
+	latest := newStationLatest()
+	registerSensorMetrics(latest.get(data.AllStations))
+	hub := live.NewHub()
+	registerLiveMetrics(hub)
 
-	hostname, err := os.Hostname()
+	b, err := openBus("server", nil)
 	if err != nil {
-		hostname = "unknown"
+		jww.FATAL.Println(err)
+		panic(err)
 	}
-	clientid := fmt.Sprintf("gowx-server-%s-%d", hostname, os.Getpid())
-
-	opts := MQTT.NewClientOptions().AddBroker(viper.GetString("broker")).SetClientID(clientid).SetCleanSession(true)
-	opts.OnConnect = func(c MQTT.Client) {
-		if token := c.Subscribe("/gowx/sample", 0, func(client MQTT.Client, msg MQTT.Message) {
-			r := bytes.NewReader(msg.Payload())
-			decoder := json.NewDecoder(r)
-			var data data.SensorData
-			err := decoder.Decode(&data)
-			if err != nil {
-				jww.ERROR.Println(err)
-				return
-			}
+	defer closeBus(b, "server")
+	if err := b.Subscribe(subscribeTopics().samples(), func(msg bus.Message) {
+		r := bytes.NewReader(msg.Payload)
+		decoder := json.NewDecoder(r)
+		var data data.SensorData
+		err := decoder.Decode(&data)
+		if err != nil {
+			jww.ERROR.Println(err)
+			return
+		}
 
-			sensordata <- data
-		}); token.Wait() && token.Error() != nil {
-			jww.FATAL.Println(token.Error())
-			panic(token.Error())
+		sensordata <- data
+	}); err != nil {
+		jww.FATAL.Println(err)
+		panic(err)
+	}
+	if err := b.Subscribe(subscribeTopics().aggregated(), func(msg bus.Message) {
+		var d aggdata
+		if err := json.Unmarshal(msg.Payload, &d); err != nil {
+			jww.ERROR.Println(err)
+			return
 		}
+		hub.Publish(live.Event{Name: "aggregate", Data: d})
+	}); err != nil {
+		jww.FATAL.Println(err)
+		panic(err)
 	}
 
-	opts.OnConnectionLost = func(c MQTT.Client, e error) {
-		jww.ERROR.Println("MQTT Connection Lost", e)
-		connect(c)
+	db, err := data.OpenDatabase()
+	if err != nil {
+		jww.FATAL.Println(err)
+		panic(err)
 	}
-
-	opts.AutoReconnect = false
-
-	client := MQTT.NewClient(opts)
-	connect(client)
-	defer client.Disconnect(0)
+	reg := db.Registry()
 
 	go func() {
 		for {
 			select {
 			case data := <-sensordata:
-				var err error
-				if value, ok := dataMatch("temperature", data); ok {
-					temp := units.NewTemperatureCelsius(value)
-					currentData.Temperature, err = temp.Get(viper.GetStringMapString("units")["temperature"])
-					if err != nil {
-						jww.ERROR.Println(err)
-						return
-					}
-				}
-				if value, ok := dataMatch("humidity", data); ok {
-					currentData.Humidity = value
-				}
-				if value, ok := dataMatch("pressure", data); ok {
-					pres := units.NewPressureHectopascal(value)
-					currentData.Pressure, err = pres.Get(viper.GetStringMapString("units")["pressure"])
-					if err != nil {
-						jww.ERROR.Println(err)
-						return
-					}
-				}
-				if value, ok := dataMatch("wind", data); ok {
-					speed := units.NewSpeedMetersPerSecond(value)
-					currentData.Wind, err = speed.Get(viper.GetStringMapString("units")["windspeed"])
-					if err != nil {
-						jww.ERROR.Println(err)
-						return
-					}
-				}
-				if value, ok := dataMatch("winddir", data); ok {
-					currentData.WindDir = value
-				}
-				if value, ok := dataMatch("rain", data); ok {
-					rate := units.NewDistanceMillimeters(value)
-					currentData.RainRate, err = rate.Get(viper.GetStringMapString("units")["rain"])
-				}
+				latest.Update(data)
+				now := time.Now()
+				hub.Publish(live.Event{Name: "sample", Data: data})
+				hub.Publish(live.Event{Name: "current", Data: stationEvent{data.Station, stationCurrent(latest, db, data.Station, now).legacy()}})
 			case <-time.After(5 * time.Minute):
 				jww.ERROR.Println("No data in 5 minutes, reconnecting")
-				connect(client)
+				reconnect(b)
 			}
 		}
 	}()
 
-	db, err := data.OpenDatabase()
-	if err != nil {
-		jww.FATAL.Println(err)
-		panic(err)
-	}
-
 	var d templateData
 
 	d.Units = viper.GetStringMapString("units")
@@ -185,27 +150,72 @@ func server(cmd *cobra.Command, args []string) {
 		d.Rain = string(bytes)
 	}
 
-	staticServer := http.FileServer(http.Dir(viper.GetString("webroot")))
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		serveTemplate(w, r, staticServer, d)
-	})
+	assets := webAssets()
+	templates, err := newTemplateSet(assets, viper.GetBool("dev"))
+	if err != nil {
+		jww.ERROR.Println(err)
+	}
+	staticServer := http.FileServer(http.FS(assets))
+	http.Handle("/", cacheable(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveTemplate(w, r, staticServer, templates, d.forStation(r.FormValue("station")))
+	})))
 
-	http.HandleFunc("/data.json", func(w http.ResponseWriter, r *http.Request) {
-		dataHandler(w, r, db)
-	})
+	// The data of the web page is of the station in the "station"
+	// parameter, or of the station of this process without one.
+	http.Handle("/data.json", cacheable(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dataHandler(w, r, db.Station(requestStation(r)))
+	})))
 
-	http.HandleFunc("/change.json", func(w http.ResponseWriter, r *http.Request) {
-		changeHandler(w, r, db)
-	})
+	http.Handle("/change.json", cacheable(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		changeHandler(w, r, db.Station(requestStation(r)))
+	})))
 
-	http.HandleFunc("/wind.json", func(w http.ResponseWriter, r *http.Request) {
-		windHandler(w, r, db)
-	})
+	http.Handle("/wind.json", cacheable(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		windHandler(w, r, db.Station(requestStation(r)))
+	})))
+
+	charts := newChartCache(64)
+	http.Handle("/chart/", cacheable(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chartHandler(w, r, db.Station(requestStation(r)), charts)
+	})))
+
+	http.Handle("/currentdata.json", cacheable(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(stationCurrent(latest, db, requestStation(r), time.Now()).legacy())
+	})))
 
-	http.HandleFunc("/currentdata.json", func(w http.ResponseWriter, r *http.Request) {
-		json.NewEncoder(w).Encode(currentData)
+	http.Handle("/api/current", cacheable(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		currentHandler(w, r, latest, db)
+	})))
+
+	http.Handle("/api/v1/", cacheable(apiHandler(db)))
+
+	http.Handle("/status.json", cacheable(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		station := requestStation(r)
+		statusHandler(w, r, db.Station(station), latest.get(station))
+	})))
+
+	http.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		liveHandler(w, r, hub, reg)
 	})
 
+	http.Handle("/records.json", cacheable(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recordsHandler(w, r, db.Station(requestStation(r)))
+	})))
+	http.Handle("/report/noaa.txt", cacheable(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		noaaHandler(w, r, db.Station(requestStation(r)))
+	})))
+
+	// Website templates and widgets made for Cumulus and Weather Display
+	// read these instead.
+	http.Handle("/realtime.txt", cacheable(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		compatHandler(w, r, db.Station(requestStation(r)), latest, cumulusRealtime)
+	})))
+	http.Handle("/clientraw.txt", cacheable(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		compatHandler(w, r, db.Station(requestStation(r)), latest, weatherDisplayClientraw)
+	})))
+
+	http.Handle("/metrics", metrics.Handler())
+
 	listener, err := net.Listen("tcp", viper.GetString("address"))
 	if err != nil {
 		jww.FATAL.Println(err)
@@ -214,51 +224,7 @@ func server(cmd *cobra.Command, args []string) {
 	addr := listener.Addr()
 	jww.INFO.Println("Listening on", addr.String())
 
-	http.Serve(listener, nil)
-}
-
-func connect(client MQTT.Client) {
-	timeout := 1 * time.Second
-
-	for {
-		if token := client.Connect(); token.Wait() && token.Error() != nil {
-			jww.ERROR.Println(token.Error())
-			jww.ERROR.Printf("Waiting %d seconds before reconnecting...", timeout/time.Second)
-			time.Sleep(timeout)
-			timeout *= 2
-			if timeout > 5*time.Minute {
-				timeout = 5 * time.Minute
-			}
-			continue
-		}
-		break
-	}
-}
-
-func dataMatch(name string, data data.SensorData) (float64, bool) {
-	query := viper.Sub("current_data").GetStringMapString(name)
-	if t, ok := query["id"]; ok {
-		if data.ID != t {
-			return 0, false
-		}
-	}
-	if t, ok := query["channel"]; ok {
-		x, err := strconv.Atoi(t)
-		if err != nil || data.Channel != x {
-			return 0, false
-		}
-	}
-	if t, ok := query["serial"]; ok {
-		if data.Serial != t {
-			return 0, false
-		}
-	}
-	if t, ok := query["type"]; ok {
-		if v, ok := data.Data[t]; ok {
-			return v, true
-		}
-	}
-	return 0, false
+	http.Serve(listener, countRequests(http.DefaultServeMux))
 }
 
 func convertToStringMap(name string) []map[string]string {
@@ -284,6 +250,7 @@ func convertToStringMap(name string) []map[string]string {
 }
 
 type templateData struct {
+	Station     string
 	Units       map[string]string
 	Temperature string
 	Pressure    string
@@ -292,27 +259,62 @@ type templateData struct {
 	Rain        string
 }
 
-func serveTemplate(w http.ResponseWriter, r *http.Request, static http.Handler, thedata templateData) {
-	temp, err := template.ParseGlob(viper.GetString("webroot") + "/*.html")
-	if err != nil {
-		jww.FATAL.Println(err)
-		panic(err)
+// forStation returns the data of the page showing a station, in its units.
+func (d templateData) forStation(station string) templateData {
+	if station == "" {
+		return d
 	}
-	name := r.URL.Path
-	if name == "/" {
+	d.Station = station
+	d.Units = stationUnits(station)
+	return d
+}
+
+// serveTemplate serves the templates of the web page by name, and the other
+// files as they are.
+func serveTemplate(w http.ResponseWriter, r *http.Request, static http.Handler, templates *templateSet, thedata templateData) {
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	if name == "" {
 		name = "index.html"
 	}
-	if temp.Lookup(name) != nil {
-		err = temp.ExecuteTemplate(w, name, thedata)
-	} else {
+	if !strings.HasSuffix(name, ".html") {
 		static.ServeHTTP(w, r)
+		return
 	}
+	var temp *template.Template
+	err := errors.New("no templates")
+	if templates != nil {
+		temp, err = templates.get()
+	}
+	if err != nil {
+		jww.ERROR.Println(err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+	if temp.Lookup(name) == nil {
+		static.ServeHTTP(w, r)
+		return
+	}
+	// The page is rendered in full first, so an error doesn't leave half of
+	// it sent.
+	var buf bytes.Buffer
+	if err := temp.ExecuteTemplate(&buf, name, thedata); err != nil {
+		jww.ERROR.Println(err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteTo(w)
 }
 
 func computeTime(timestr string) (int64, int64) {
 	t := time.Now().UTC().Unix()
-	interval := int64(1)
+	td := timeSpan(timestr)
+	return t - td, autoInterval(td)
+}
 
+// timeSpan returns the seconds a "time" parameter such as "48h" or "7d"
+// spans, 24 hours if it isn't valid.
+func timeSpan(timestr string) int64 {
 	rxp := regexp.MustCompile(`^([0-9]+)([hd])$`)
 	if !rxp.MatchString(timestr) {
 		timestr = "24h"
@@ -330,29 +332,46 @@ func computeTime(timestr string) (int64, int64) {
 		mult = 60 * 60 * 24
 	}
 
-	td := val * mult
-
-	if td > 60*60*24*30 {
-		interval = 12 * 60 * 60
-	} else if td > 60*60*24*7 {
-		interval = 2 * 60 * 60
-	} else if td > 60*60*24 {
-		interval = 30 * 60
-	}
+	return val * mult
+}
 
-	return t - td, interval
+// autoInterval returns the interval, in seconds, to combine samples into for
+// a chart spanning td seconds.
+func autoInterval(td int64) int64 {
+	switch {
+	case td > 60*60*24*30:
+		return 12 * 60 * 60
+	case td > 60*60*24*7:
+		return 2 * 60 * 60
+	case td > 60*60*24:
+		return 30 * 60
+	}
+	return 1
 }
 
 func dataHandler(w http.ResponseWriter, r *http.Request, db *data.Database) {
 	var queries []map[string]string
 	err := json.Unmarshal([]byte(r.FormValue("query")), &queries)
 	if err != nil {
-		jww.ERROR.Println(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	//channel := r.FormValue("channel")
+	// A channel parameter applies to the queries that don't give their own.
+	if channel := r.FormValue("channel"); channel != "" {
+		for _, querymap := range queries {
+			if _, ok := querymap["channel"]; !ok {
+				querymap["channel"] = channel
+			}
+		}
+	}
 
 	t, interval := computeTime(r.FormValue("time"))
+	labels, series, err := chartSeries(db, queries, r.FormValue("type"), t, interval)
+	if err != nil {
+		jww.ERROR.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	var result struct {
 		Data      [][]interface{}
@@ -361,79 +380,109 @@ func dataHandler(w http.ResponseWriter, r *http.Request, db *data.Database) {
 	}
 	result.Data = make([][]interface{}, len(queries))
 	result.Errorbars = make([][]interface{}, len(queries))
-	result.Label = make([]string, len(queries))
+	result.Label = labels
+
+	for index, points := range series {
+		for _, p := range points {
+			// Highcharts shows times in UTC, so they're shifted to local time.
+			_, off := time.Unix(p.Timestamp, 0).Zone()
+			t := (p.Timestamp + int64(off)) * 1000
+			// Gaps in the data are sent as null points, which break the lines.
+			if p.Gap {
+				result.Data[index] = append(result.Data[index], []interface{}{t, nil})
+				result.Errorbars[index] = append(result.Errorbars[index], []interface{}{t, nil, nil})
+				continue
+			}
+			result.Data[index] = append(result.Data[index], []interface{}{t, p.Value})
+			result.Errorbars[index] = append(result.Errorbars[index], []interface{}{t, p.Min, p.Max})
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// seriesPoint is a point of a chart series, converted to the units of the
+// chart. A Gap point breaks the line.
+type seriesPoint struct {
+	Timestamp       int64
+	Value, Min, Max float64
+	Gap             bool
+}
+
+// chartSeries returns the labels and points of the series of a chart since
+// start, combined into intervals of interval seconds. The values are
+// converted to the station's units of unitType, one of the chart types of
+// /data.json.
+func chartSeries(db *data.Database, queries []map[string]string, unitType string, start int64, interval int64) ([]string, [][]seriesPoint, error) {
+	labels := make([]string, len(queries))
+	series := make([][]seriesPoint, len(queries))
+
+	step := interval
+	if aggregated := viper.GetInt64("interval"); aggregated > step {
+		step = aggregated
+	}
+	gaps, err := db.Gaps(start)
+	if err != nil {
+		jww.ERROR.Println(err)
+	}
 
+	unitmap := stationUnits(db.StationName())
 	rxp := regexp.MustCompile(`\[([^]]*)\]`)
+	direction := regexp.MustCompile(`Dir$`)
 	for index, querymap := range queries {
-		id := "%"
-		if _, ok := querymap["id"]; ok {
-			id = querymap["id"]
-		}
+		ids := querySensors(querymap, db.Registry())
 		datatype := "%"
 		if _, ok := querymap["type"]; ok {
 			datatype = querymap["type"]
 		}
 		if _, ok := querymap["label"]; ok {
-			result.Label[index] = querymap["label"]
+			labels[index] = querymap["label"]
 		} else {
-			result.Label[index] = "Unknown"
+			labels[index] = "Unknown"
 		}
 
 		key := rxp.ReplaceAllString(datatype, "")
-		var rows <-chan data.Row
-		if interval > 1 {
-			rows = db.QueryRowsInterval(t, key, id, interval)
-		} else {
-			rows = db.QueryRows(t, key, id)
+		rows, err := querySeries(db, start, 0, key, ids, interval)
+		if err != nil {
+			return nil, nil, err
 		}
 
 		col := rxp.FindStringSubmatch(datatype)
-
-		unitmap := viper.GetStringMapString("units")
-		direction := regexp.MustCompile(`Dir$`)
-		for row := range rows {
-			_, off := time.Unix(row.Timestamp, 0).Zone()
-			t := (time.Unix(row.Timestamp, 0).Unix() + int64(off)) * 1000
-			sub := make([]interface{}, 2)
-			sub[0] = t
-			var value float64
-			if len(col) > 1 {
-				switch col[1] {
-				case "min":
-					value = row.Min
-				case "max":
-					value = row.Max
-				case "avg":
-					value = row.Avg
-				default:
-					value = row.Avg
-				}
-			} else {
-				value = row.Avg
-			}
-			switch {
-			case direction.MatchString(key):
-				sub[1] = value
-			default:
-				sub[1] = convertUnit(unitmap, r.FormValue("type"), value)
+		convert := func(v float64) float64 {
+			if direction.MatchString(key) {
+				return v
 			}
-			result.Data[index] = append(result.Data[index], sub)
-
-			sub = make([]interface{}, 3)
-			sub[0] = t
-			switch {
-			case direction.MatchString(key):
-				sub[1] = row.Min
-				sub[1] = row.Max
-			default:
-				sub[1] = convertUnit(unitmap, r.FormValue("type"), row.Min)
-				sub[2] = convertUnit(unitmap, r.FormValue("type"), row.Max)
+			return convertUnit(unitmap, unitType, v)
+		}
+		var prev int64
+		for _, row := range rows {
+			if prev != 0 && chartBreak(prev, row.Timestamp, step, gaps, ids) {
+				series[index] = append(series[index], seriesPoint{Timestamp: prev + step, Gap: true})
 			}
-			result.Errorbars[index] = append(result.Errorbars[index], sub)
+			prev = row.Timestamp
+			series[index] = append(series[index], seriesPoint{
+				Timestamp: row.Timestamp,
+				Value:     convert(rowValue(col, row)),
+				Min:       convert(row.Min),
+				Max:       convert(row.Max),
+			})
 		}
-		index++
 	}
-	json.NewEncoder(w).Encode(result)
+	return labels, series, nil
+}
+
+// rowValue returns the column of a row selected by the suffix of a type,
+// e.g. "[max]", the average by default.
+func rowValue(col []string, row data.Row) float64 {
+	if len(col) > 1 {
+		switch col[1] {
+		case "min":
+			return row.Min
+		case "max":
+			return row.Max
+		}
+	}
+	return row.Avg
 }
 
 func convertUnit(unitmap map[string]string, datatype string, input float64) float64 {
@@ -477,7 +526,7 @@ func windHandler(w http.ResponseWriter, r *http.Request, db *data.Database) {
 	var queries []map[string]string
 	err := json.Unmarshal([]byte(r.FormValue("query")), &queries)
 	if err != nil {
-		jww.ERROR.Println(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -485,27 +534,34 @@ func windHandler(w http.ResponseWriter, r *http.Request, db *data.Database) {
 		Data  [][]float64
 		Label []string
 	}
+	result.Label, result.Data, err = windSeries(db, queries, t)
+	if err != nil {
+		jww.ERROR.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	result.Data = make([][]float64, len(queries))
-	result.Label = make([]string, len(queries))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
 
+// windSeries returns the labels and the 32 sectors of the wind roses of a
+// chart since start, converted to the wind speed unit of the station.
+func windSeries(db *data.Database, queries []map[string]string, start int64) ([]string, [][]float64, error) {
+	labels := make([]string, len(queries))
+	series := make([][]float64, len(queries))
+
+	unitmap := stationUnits(db.StationName())
 	rxp := regexp.MustCompile(`\[([^]]*)\]`)
 	for index, querymap := range queries {
-		result.Label[index] = querymap["label"]
-		result.Data[index] = make([]float64, 32)
-
-		id := "%"
-		if _, ok := querymap["id"]; ok {
-			id = querymap["id"]
-		}
 		datatype := "%"
 		if _, ok := querymap["type"]; ok {
 			datatype = querymap["type"]
 		}
 		if _, ok := querymap["label"]; ok {
-			result.Label[index] = querymap["label"]
+			labels[index] = querymap["label"]
 		} else {
-			result.Label[index] = "Unknown"
+			labels[index] = "Unknown"
 		}
 		cols := rxp.FindStringSubmatch(datatype)
 		var col string
@@ -517,17 +573,16 @@ func windHandler(w http.ResponseWriter, r *http.Request, db *data.Database) {
 
 		key := rxp.ReplaceAllString(datatype, "")
 
-		for row := range db.QueryWind(t, key, col, id, 0) {
-			speed := units.NewSpeedMetersPerSecond(row.Value)
-			result.Data[index][int(row.Dir)], err = speed.Get(viper.GetStringMapString("units")["windspeed"])
-			if err != nil {
-				jww.ERROR.Println(err)
-				return
-			}
+		sectors, err := queryWindRose(db, start, 0, key, col, querySensors(querymap, db.Registry()))
+		if err != nil {
+			return nil, nil, err
 		}
+		for dir, value := range sectors {
+			sectors[dir] = convertUnit(unitmap, "wind", value)
+		}
+		series[index] = sectors
 	}
-
-	json.NewEncoder(w).Encode(result)
+	return labels, series, nil
 }
 
 func changeHandler(w http.ResponseWriter, r *http.Request, db *data.Database) {
@@ -544,29 +599,23 @@ func changeHandler(w http.ResponseWriter, r *http.Request, db *data.Database) {
 		Change []float64
 	}
 
-	unitmap := viper.GetStringMapString("units")
-	index := 0
+	unitmap := stationUnits(db.StationName())
 	for _, datatype := range datatypes {
 		for _, id := range ids {
 			if id == "" {
 				id = "%"
 			}
-			old, err := db.QueryFirst(t, datatype, id, channel)
-			if err != nil {
-			}
-
-			now, err := db.QueryLast(t, datatype, id, channel)
+			old, now, _, err := db.Change(t, 0, datatype, data.SensorID{ID: id, Channel: channel})
 			if err != nil {
+				jww.ERROR.Println(err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
 			}
 
-			value := now - old
-			var delta float64
-
-			delta = convertUnit(unitmap, r.FormValue("type"), value)
+			delta := convertUnit(unitmap, r.FormValue("type"), now-old)
 			result.Change = append(result.Change, delta)
-
-			index++
 		}
 	}
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
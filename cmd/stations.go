@@ -0,0 +1,220 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/geoffholden/gowx/data"
+)
+
+// Settings can be given per station, in a section of "stations" named after
+// the station, e.g.
+//
+//	stations:
+//	  cottage:
+//	    latitude: 45.1
+//	    longitude: -78.3
+//	    elevation: 320
+//	    timezone: America/Toronto
+//	    units:
+//	      temperature: F
+//	    upload:
+//	      services:
+//	        wunderground:
+//	          id: KXXXX2
+//	          key: secret
+//
+// A station's setting replaces the top-level setting of the same name, with
+// sections merged key by key, so the station above keeps the top-level
+// units other than temperature.
+
+// baseSettings are the top-level settings replaced by those of the station
+// named by --station, for the other stations to start from.
+var baseSettings = make(map[string]interface{})
+
+// useStationConfig applies the section of the station named by --station
+// over the top-level settings, so the commands serving one station see its
+// settings as their own.
+func useStationConfig() {
+	station := viper.GetString("station")
+	if station == "" {
+		return
+	}
+	section, ok := settingMap(viper.Get("stations." + station))
+	if !ok {
+		return
+	}
+	for key := range section {
+		if _, ok := baseSettings[key]; !ok {
+			baseSettings[key] = viper.Get(key)
+		}
+		viper.Set(key, stationSetting(station, key))
+	}
+}
+
+// stationSetting returns a setting for a station, which is the top-level
+// setting merged with the station's own. The empty name is the station of
+// this process.
+func stationSetting(station string, key string) interface{} {
+	base, ok := baseSettings[key]
+	if !ok || station == "" {
+		base = viper.Get(key)
+	}
+	if station == "" {
+		return base
+	}
+	return mergeSettings(base, viper.Get("stations."+station+"."+key))
+}
+
+// mergeSettings returns over in place of base, or the two merged key by key
+// if they're both sections.
+func mergeSettings(base, over interface{}) interface{} {
+	if over == nil {
+		return base
+	}
+	b, okb := settingMap(base)
+	o, oko := settingMap(over)
+	if !okb || !oko {
+		return over
+	}
+	result := make(map[string]interface{}, len(b)+len(o))
+	for k, v := range b {
+		result[k] = v
+	}
+	for k, v := range o {
+		result[k] = mergeSettings(result[k], v)
+	}
+	return result
+}
+
+// settingMap returns a section of the configuration as a map with lower
+// case keys, as viper gives them.
+func settingMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			result[strings.ToLower(k)] = v
+		}
+		return result, true
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			result[strings.ToLower(fmt.Sprint(k))] = v
+		}
+		return result, true
+	case map[string]string:
+		result := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			result[strings.ToLower(k)] = v
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+// settingFloat returns a numeric setting, or 0 if it isn't a number.
+func settingFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	}
+	return 0
+}
+
+// stationUnits returns the units the values of a station are shown in.
+func stationUnits(station string) map[string]string {
+	if station == "" {
+		return viper.GetStringMapString("units")
+	}
+	m, _ := settingMap(stationSetting(station, "units"))
+	result := make(map[string]string, len(m))
+	for k, v := range m {
+		result[k] = fmt.Sprint(v)
+	}
+	return result
+}
+
+// stationLocationOf returns the time zone of a station's day boundary.
+func stationLocationOf(station string) *time.Location {
+	if station == "" {
+		return stationLocation()
+	}
+	name, _ := stationSetting(station, "timezone").(string)
+	return loadLocation(name)
+}
+
+// stationNames returns the stations that have a section of their own or
+// stored samples, sorted. Samples from before stations were named belong to
+// the default station, which isn't listed.
+func stationNames(db *data.Database) ([]string, error) {
+	stored, err := db.Stations()
+	if err != nil {
+		return nil, err
+	}
+	// Configured names are lower case, as viper gives them, so they're
+	// matched to the stored ones ignoring case.
+	names := make(map[string]string)
+	for _, name := range stored {
+		if name != "" {
+			names[strings.ToLower(name)] = name
+		}
+	}
+	for name := range viper.GetStringMap("stations") {
+		if _, ok := names[name]; !ok {
+			names[name] = name
+		}
+	}
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// requestStation returns the station named by the "station" parameter of a
+// request, or the station of this process without one. The default page
+// and its data are of that station alone.
+func requestStation(r *http.Request) string {
+	if station := r.FormValue("station"); station != "" {
+		return station
+	}
+	return viper.GetString("station")
+}
+
+// sameStation reports whether data from a station is this station's. Data
+// from an unnamed station, or for an unnamed station, always is.
+func sameStation(station string, from string) bool {
+	return station == "" || from == "" || strings.EqualFold(station, from)
+}
+
+// lookupStation returns the name of a station as stationNames has it,
+// ignoring case, and false if there's no such station.
+func lookupStation(db *data.Database, station string) (string, bool, error) {
+	names, err := stationNames(db)
+	if err != nil {
+		return "", false, err
+	}
+	for _, name := range names {
+		if strings.EqualFold(name, station) {
+			return name, true, nil
+		}
+	}
+	return "", false, nil
+}
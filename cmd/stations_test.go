@@ -0,0 +1,108 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/geoffholden/gowx/data"
+)
+
+func TestStationSetting(t *testing.T) {
+	defer viper.Set("units", viper.Get("units"))
+	defer viper.Set("stations", nil)
+	viper.Set("units", map[string]string{"temperature": "C", "windspeed": "km/h"})
+	viper.Set("stations", map[string]interface{}{
+		"cottage": map[string]interface{}{
+			"units":     map[string]interface{}{"temperature": "F"},
+			"timezone":  "America/Vancouver",
+			"elevation": 320,
+		},
+	})
+
+	units := stationUnits("cottage")
+	if units["temperature"] != "F" || units["windspeed"] != "km/h" {
+		t.Error("Unexpected units", units)
+	}
+	if units := stationUnits("home"); units["temperature"] != "C" {
+		t.Error("Unexpected units", units)
+	}
+	if loc := stationLocationOf("Cottage"); loc.String() != "America/Vancouver" {
+		t.Error("Unexpected time zone", loc)
+	}
+	if e := settingFloat(stationSetting("cottage", "elevation")); e != 320 {
+		t.Error("Unexpected elevation", e)
+	}
+	if !sameStation("", "cottage") || !sameStation("cottage", "") || sameStation("home", "cottage") {
+		t.Error("Unexpected station match")
+	}
+}
+
+func TestAPIStations(t *testing.T) {
+	viper.Set("dbDriver", "sqlite3")
+	viper.Set("database", filepath.Join(t.TempDir(), "gowx.db"))
+	db, err := data.OpenDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	defer viper.Set("units", viper.Get("units"))
+	defer viper.Set("stations", nil)
+	viper.Set("units", map[string]string{"temperature": "C"})
+	viper.Set("stations", map[string]interface{}{
+		"cottage": map[string]interface{}{"units": map[string]interface{}{"temperature": "F"}},
+	})
+
+	ts := time.Date(2026, 10, 19, 1, 0, 0, 0, time.UTC).Unix()
+	db.Station("Home").InsertRow(ts, "OS3:1D20", 1, "", "Temperature", 10, 10, 10)
+	db.Station("cottage").InsertRow(ts, "OS3:1D20", 1, "", "Temperature", 20, 20, 20)
+	db.InsertRow(ts, "OS3:1D20", 1, "", "Temperature", 5, 5, 5)
+
+	get := func(url string, v interface{}) int {
+		w := httptest.NewRecorder()
+		apiHandler(db).ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+			t.Error(url, err)
+		}
+		return w.Code
+	}
+
+	var stations []apiStation
+	if code := get("/api/v1/stations", &stations); code != http.StatusOK || len(stations) != 2 {
+		t.Fatal("Unexpected stations", code, stations)
+	}
+	if stations[0].Name != "Home" || stations[1].Name != "cottage" || stations[1].Units["temperature"] != "F" {
+		t.Error("Unexpected stations", stations)
+	}
+
+	const query = "series?key=Temperature&start=2026-10-19T00:00:00Z&end=2026-10-19T02:00:00Z"
+	var series apiSeries
+	if code := get("/api/v1/stations/cottage/"+query, &series); code != http.StatusOK {
+		t.Fatal("Unexpected status", code)
+	}
+	if series.Unit != "F" || len(series.Points) != 1 || series.Points[0].Value != 68 {
+		t.Error("Unexpected cottage series", series)
+	}
+	// Station names match ignoring case.
+	if get("/api/v1/stations/home/"+query, &series); series.Unit != "C" || len(series.Points) != 1 || series.Points[0].Value != 10 {
+		t.Error("Unexpected home series", series)
+	}
+	// The top-level routes are of the default station alone.
+	if get("/api/v1/"+query, &series); len(series.Points) != 1 || series.Points[0].Value != 5 {
+		t.Error("Unexpected series of the default station", series)
+	}
+
+	for _, url := range []string{"/api/v1/stations/attic", "/api/v1/stations/attic/" + query, "/api/v1/stations/home/stations"} {
+		var e apiError
+		if code := get(url, &e); code != http.StatusNotFound {
+			t.Error("Unexpected status", url, code)
+		}
+	}
+}
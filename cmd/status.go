@@ -0,0 +1,183 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+
+	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/health"
+)
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the health of every sensor",
+	Long: `Lists every sensor with the time it was last heard from, its expected
+reporting interval and whether it's stale, followed by the recent gaps in its
+data.
+
+The aggregator learns the expected interval of every sensor from its samples
+and keeps it, with the time the sensor was last seen, in the database. A
+sensor is stale once nothing has been heard from it for "health.factor" times
+its expected interval, and the time until its next sample is recorded as a
+gap. Charts show the gaps as breaks in the lines. Sensors with a fixed
+interval can have it configured instead, in seconds, by registry name or ID:
+
+health:
+  factor: 3
+  intervals:
+    outdoor: 48
+    VN1:6D27: 10
+
+The same list is served as /status.json by "gowx server".`,
+	Run: status,
+}
+
+func init() {
+	RootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().Duration("gaps", 7*24*time.Hour, "How far back to list gaps")
+
+	viper.SetDefault("health.factor", 3)
+}
+
+// newHealthMonitor creates a monitor from the last-seen table, with the
+// configured intervals.
+func newHealthMonitor(db *data.Database) (*health.Monitor, error) {
+	statuses, err := db.SensorStatuses()
+	if err != nil {
+		return nil, err
+	}
+	m := health.NewMonitor(statuses)
+	m.Factor = viper.GetFloat64("health.factor")
+	intervals := viper.GetStringMap("health.intervals")
+	reg := db.Registry()
+	m.Configured = func(key data.SensorKey) time.Duration {
+		for name := range intervals {
+			if strings.EqualFold(name, key.ID) || reg.Matches(name, key) {
+				return time.Duration(viper.GetFloat64("health.intervals."+name) * float64(time.Second))
+			}
+		}
+		return 0
+	}
+	return m, nil
+}
+
+// sensorHealth returns the health of every sensor of the station of db,
+// named from the registry. The samples in latest, if any, are that
+// station's, and newer than the last-seen table.
+func sensorHealth(db *data.Database, latest *data.Latest, now time.Time) ([]health.Status, error) {
+	m, err := newHealthMonitor(db)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil {
+		for _, s := range latest.Snapshot() {
+			m.Observe(db.StationName(), s.SensorKey, s.LastSeen)
+		}
+	}
+	statuses := m.Statuses(now)
+	for i := range statuses {
+		statuses[i].Name, _ = db.Registry().Name(statuses[i].SensorKey)
+	}
+	return statuses, nil
+}
+
+func status(cmd *cobra.Command, args []string) {
+	db := openSensorDatabase()
+	now := time.Now()
+	statuses, err := sensorHealth(db, nil, now)
+	if err != nil {
+		fatal(err)
+	}
+	since, _ := cmd.Flags().GetDuration("gaps")
+	gaps, err := db.Gaps(now.Add(-since).Unix())
+	if err != nil {
+		fatal(err)
+	}
+
+	loc := stationLocation()
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "SENSOR\tNAME\tSTATE\tLAST SEEN\tEXPECTED")
+	for _, s := range statuses {
+		expected := "-"
+		if s.Expected > 0 {
+			expected = (time.Duration(s.Expected) * time.Second).String()
+		}
+		fmt.Fprintf(w, "%s/%d/%s\t%s\t%s\t%s\t%s\n", s.ID, s.Channel, s.Serial, s.Name, s.State,
+			s.LastSeen.In(loc).Format("2006-01-02 15:04:05"), expected)
+	}
+	w.Flush()
+
+	if len(gaps) == 0 {
+		return
+	}
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "SENSOR\tNAME\tGAP START\tGAP END\tDURATION")
+	for _, g := range gaps {
+		name, _ := db.Registry().Name(g.SensorKey)
+		start, end := time.Unix(g.Start, 0), time.Unix(g.End, 0)
+		fmt.Fprintf(w, "%s/%d/%s\t%s\t%s\t%s\t%s\n", g.ID, g.Channel, g.Serial, name,
+			start.In(loc).Format("2006-01-02 15:04:05"), end.In(loc).Format("2006-01-02 15:04:05"), end.Sub(start))
+	}
+	w.Flush()
+}
+
+func statusHandler(w http.ResponseWriter, r *http.Request, db *data.Database, latest *data.Latest) {
+	now := time.Now()
+	statuses, err := sensorHealth(db, latest, now)
+	if err != nil {
+		jww.ERROR.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	gaps, err := db.Gaps(now.Add(-24 * time.Hour).Unix())
+	if err != nil {
+		jww.ERROR.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var result struct {
+		Sensors []health.Status
+		Gaps    []data.Gap
+	}
+	result.Sensors, result.Gaps = statuses, gaps
+	if result.Gaps == nil {
+		result.Gaps = []data.Gap{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// chartBreak returns whether a chart line should break between two rows of
+// the sensors in ids: when they're further apart than two steps, or when
+// one of the sensors has a recorded gap longer than a step overlapping the
+// time between them. Gaps are in sample times, which rarely line up with
+// the rows.
+func chartBreak(prev, next, step int64, gaps []data.Gap, ids []data.SensorID) bool {
+	if next-prev > 2*step {
+		return true
+	}
+	for _, g := range gaps {
+		if g.Start >= next || g.End <= prev || g.End-g.Start <= step {
+			continue
+		}
+		for _, id := range ids {
+			if (id.ID == "%" || id.ID == g.ID) && (id.Channel < 0 || id.Channel == g.Channel) && (id.Serial == "" || id.Serial == g.Serial) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/geoffholden/gowx/bus"
+	"github.com/geoffholden/gowx/data"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+)
+
+// topics builds the MQTT topics. Every topic is under a prefix, which is
+// "<topicPrefix>/<station>", or just "<topicPrefix>" if the station isn't
+// named:
+//
+//	<prefix>/sample                           raw samples, JSON data.SensorData
+//	<prefix>/sample/aggregated                aggregated samples, JSON aggdata
+//	<prefix>/<id>/<channel>/<key>             latest raw value, retained
+//	<prefix>/<id>/<channel>/<key>/aggregated  latest aggregated average, retained
+//	<prefix>/status/<component>               "online" or "offline", retained
+//	<prefix>/alert                            alerts firing and clearing, JSON alert.Alert
+//
+// Subscribers use the same layout with the station taken from the
+// "subscribe" setting instead, which may be the wildcard "+" to receive the
+// samples of every station sharing the broker.
+type topics struct {
+	prefix string
+}
+
+func newTopics(station string) topics {
+	prefix := strings.TrimSuffix(viper.GetString("topicPrefix"), "/")
+	if station != "" {
+		prefix += "/" + station
+	}
+	return topics{prefix}
+}
+
+// stationTopics returns the topics this station publishes to.
+func stationTopics() topics {
+	return newTopics(topicLevel(viper.GetString("station"), ""))
+}
+
+// subscribeTopics returns the topic filters subscribers use.
+func subscribeTopics() topics {
+	if station := viper.GetString("subscribe"); station == "+" {
+		return newTopics(station)
+	} else if station != "" {
+		return newTopics(topicLevel(station, ""))
+	}
+	return stationTopics()
+}
+
+// topicLevel makes s safe to use as a single topic level.
+func topicLevel(s string, empty string) string {
+	if s == "" {
+		return empty
+	}
+	return strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace(s)
+}
+
+func (t topics) samples() string {
+	return t.prefix + "/sample"
+}
+
+func (t topics) aggregated() string {
+	return t.prefix + "/sample/aggregated"
+}
+
+func (t topics) value(sensor data.SensorKey, key string) string {
+	return t.prefix + "/" + topicLevel(sensor.ID, "_") + "/" + strconv.Itoa(sensor.Channel) + "/" + topicLevel(key, "_")
+}
+
+func (t topics) aggregatedValue(sensor data.SensorKey, key string) string {
+	return t.value(sensor, key) + "/aggregated"
+}
+
+func (t topics) alerts() string {
+	return t.prefix + "/alert"
+}
+
+func (t topics) status(component string) string {
+	return t.prefix + "/status/" + component
+}
+
+func publishJSON(b bus.Bus, topic string, v interface{}) {
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	encoder.Encode(v)
+	payload := buf.Bytes()
+	if err := b.Publish(topic, payload, false); err != nil {
+		jww.ERROR.Println("Failed to send message.", err)
+	}
+	jww.DEBUG.Printf("Publishing %s -> %s\n", topic, payload)
+}
+
+func publishValue(b bus.Bus, topic string, value float64) {
+	payload := strconv.FormatFloat(value, 'f', -1, 64)
+	if err := b.Publish(topic, []byte(payload), true); err != nil {
+		jww.ERROR.Println("Failed to send message.", err)
+	}
+}
@@ -0,0 +1,364 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+
+	"github.com/geoffholden/gowx/bus"
+	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/upload"
+)
+
+// uploadCmd represents the upload command
+var uploadCmd = &cobra.Command{
+	Use:     "upload [service...]",
+	Aliases: []string{"wu"},
+	Short:   "Upload observations to weather services",
+	Long: `Uploads the aggregated observations to the services in the
+"upload.services" section, or only to the named ones. The "upload.fields"
+section maps the observation fields to sensors, e.g.
+
+upload:
+  fields:
+    temperature:
+      type: Temperature
+      sensor: outdoor
+  services:
+    wunderground:
+      id: KXXXX1
+      key: secret
+    cwop:
+      callsign: CW0001
+
+The type of a service defaults to its name, and is one of wunderground, cwop,
+wow, pwsweather, windy and openweathermap. The station's position is set with
+"latitude" and "longitude".
+
+Observations that couldn't be sent are queued, and dropped once they're
+older than the "max_age" of the service, 900 seconds by default. Set it to 0
+for services that accept historical observations.
+
+Only the observations of the station named by --station are uploaded, with
+the services and position in its section of "stations" if it has one.`,
+	Run: uploadRun,
+}
+
+func uploadInit() {
+	if !uploadCmd.Flags().HasFlags() {
+		uploadCmd.Flags().Bool("dry-run", false, "Print the requests instead of sending them")
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(uploadCmd)
+	uploadInit()
+	viper.BindPFlags(uploadCmd.Flags())
+}
+
+// legacyUploadConfig turns the settings of the old wu command into a
+// wunderground service.
+func legacyUploadConfig() {
+	if !viper.IsSet("upload.services") && viper.GetString("wu_id") != "" {
+		viper.Set("upload.services.wunderground", map[string]interface{}{
+			"id":  viper.GetString("wu_id"),
+			"key": viper.GetString("wu_key"),
+		})
+	}
+}
+
+// uploadFields returns the sensor query of each observation field. Without
+// an "upload.fields" section, the "wunderground" section of the old wu
+// command is used.
+func uploadFields() map[string]map[string]string {
+	fields := make(map[string]map[string]string)
+	if viper.IsSet("upload.fields") {
+		for field := range viper.GetStringMap("upload.fields") {
+			fields[field] = viper.GetStringMapString("upload.fields." + field)
+		}
+		return fields
+	}
+	for param, field := range upload.WundergroundFields {
+		key := "wunderground." + strings.ToLower(param)
+		if viper.IsSet(key) {
+			fields[field] = viper.GetStringMapString(key)
+		}
+	}
+	return fields
+}
+
+func uploadRun(cmd *cobra.Command, args []string) {
+	if verbose {
+		jww.SetStdoutThreshold(jww.LevelTrace)
+	}
+
+	serveMetrics()
+	legacyUploadConfig()
+	runners, err := upload.Open(args)
+	if err != nil {
+		jww.FATAL.Println(err)
+		panic(err)
+	}
+
+	db, err := data.OpenDatabase()
+	if err != nil {
+		jww.FATAL.Println(err)
+		panic(err)
+	}
+	fields := uploadFields()
+
+	for _, r := range runners {
+		go r.Run(uploadHandler(r))
+	}
+
+	station := viper.GetString("station")
+	dataChannel := make(chan aggdata)
+	sampleChannel := make(chan data.SensorData)
+	b, err := openBus("upload", nil)
+	if err != nil {
+		jww.FATAL.Println(err)
+		panic(err)
+	}
+	defer closeBus(b, "upload")
+	if err := b.Subscribe(subscribeTopics().aggregated(), func(msg bus.Message) {
+		r := bytes.NewReader(msg.Payload)
+		decoder := json.NewDecoder(r)
+		var data aggdata
+		err := decoder.Decode(&data)
+		if err != nil {
+			jww.ERROR.Println(err)
+			return
+		}
+		if !sameStation(station, data.Key.Station) {
+			return
+		}
+		dataChannel <- data
+	}); err != nil {
+		jww.FATAL.Println(err)
+		panic(err)
+	}
+	// The samples give the realtime observations, and the rolling wind
+	// averages and gusts.
+	if err := b.Subscribe(subscribeTopics().samples(), func(msg bus.Message) {
+		r := bytes.NewReader(msg.Payload)
+		decoder := json.NewDecoder(r)
+		var data data.SensorData
+		err := decoder.Decode(&data)
+		if err != nil {
+			jww.ERROR.Println(err)
+			return
+		}
+		if !sameStation(station, data.Station) {
+			return
+		}
+		sampleChannel <- data
+	}); err != nil {
+		jww.FATAL.Println(err)
+		panic(err)
+	}
+
+	// The aggregated values arrive one by one, so the observation is sent
+	// once they stop for a few seconds.
+	timer := time.NewTimer(5 * time.Second)
+	timer.Stop()
+
+	// Values are kept until they are replaced, so that sensors reporting
+	// less often are still included.
+	obs := upload.Observation{Values: make(map[string]float64)}
+	realtime := upload.Observation{Values: make(map[string]float64)}
+	var wind upload.Wind
+	submit := func(obs upload.Observation, rt bool) {
+		if len(obs.Values) == 0 {
+			return
+		}
+		o := completeObservation(obs, &wind)
+		for _, r := range runners {
+			if r.Realtime == rt {
+				r.Submit(o)
+			}
+		}
+	}
+	for {
+		select {
+		case <-timer.C:
+			submit(obs, false)
+		case d := <-dataChannel:
+			uploadObserve(&obs, d, fields, db)
+			timer.Stop()
+			timer.Reset(5 * time.Second)
+		case d := <-sampleChannel:
+			for _, a := range sampleAggregates(d) {
+				set := uploadObserve(&realtime, a, fields, db)
+				if set[upload.WindDir] {
+					wind.AddDir(realtime.Time, realtime.Values[upload.WindDir])
+				}
+				if set[upload.WindSpeed] {
+					wind.AddSpeed(realtime.Time, realtime.Values[upload.WindSpeed])
+				}
+			}
+			submit(realtime, true)
+		case <-time.After(30 * time.Minute):
+			jww.ERROR.Println("No data in 30 minutes, reconnecting")
+			reconnect(b)
+		}
+	}
+}
+
+// completeObservation returns a copy of the observation, with the wind
+// averages and gusts and the dew point added.
+func completeObservation(obs upload.Observation, wind *upload.Wind) upload.Observation {
+	o := upload.Observation{Time: obs.Time, Values: make(map[string]float64)}
+	for field, value := range obs.Values {
+		o.Values[field] = value
+	}
+	wind.Fill(&o)
+	if _, ok := o.Values[upload.DewPoint]; !ok {
+		t, okt := o.Values[upload.Temperature]
+		h, okh := o.Values[upload.Humidity]
+		if okt && okh && h > 0 {
+			o.Values[upload.DewPoint] = upload.DewPointOf(t, h)
+		}
+	}
+	return o
+}
+
+// sampleAggregates turns a sample into aggregates of one value, so that the
+// fields are mapped the same way for samples and aggregates.
+func sampleAggregates(d data.SensorData) []aggdata {
+	var result []aggdata
+	// Directions first, to pair with the speeds of the same sample.
+	keys := make([]string, 0, len(d.Data))
+	for key := range d.Data {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		di, dj := strings.HasSuffix(keys[i], "Dir"), strings.HasSuffix(keys[j], "Dir")
+		if di != dj {
+			return di
+		}
+		return keys[i] < keys[j]
+	})
+	for _, key := range keys {
+		v := d.Data[key]
+		result = append(result, aggdata{
+			Timestamp: d.TimeStamp.Unix(),
+			Key:       mapKey{Station: d.Station, ID: d.ID, Channel: d.Channel, Serial: d.Serial, Key: key},
+			Min:       v,
+			Max:       v,
+			Avg:       v,
+		})
+	}
+	return result
+}
+
+// uploadHandler sends the observations of a runner. Unless it's a dry run or
+// a realtime runner, observations that can't be sent are queued.
+func uploadHandler(r *upload.Runner) func(o upload.Observation) {
+	if viper.GetBool("dry-run") || r.Realtime {
+		if viper.GetBool("dry-run") {
+			r.DryRun = os.Stdout
+		}
+		return func(o upload.Observation) {
+			if err := r.Send(o); err != nil {
+				jww.ERROR.Printf("Upload %s: %s\n", r.Name, err.Error())
+			}
+		}
+	}
+
+	f := newForwarder("upload-"+r.Name, func(item []byte) error {
+		var o upload.Observation
+		if err := json.Unmarshal(item, &o); err != nil {
+			jww.ERROR.Println(err)
+			return nil
+		}
+		if r.Stale(o, time.Now()) {
+			jww.DEBUG.Printf("Upload %s: dropping the observation from %s\n", r.Name, o.Time)
+			return nil
+		}
+		err := r.Send(o)
+		if upload.IsPermanent(err) {
+			jww.ERROR.Printf("Upload %s: %s\n", r.Name, err.Error())
+			return nil
+		}
+		return err
+	})
+	return func(o upload.Observation) {
+		item, err := json.Marshal(o)
+		if err != nil {
+			jww.ERROR.Println(err)
+			return
+		}
+		if err := f.Forward(item); err != nil {
+			jww.ERROR.Println(err)
+		}
+	}
+}
+
+// uploadObserve adds an aggregated value to the observation, for each field
+// it is mapped to, and returns the fields set.
+func uploadObserve(obs *upload.Observation, d aggdata, fields map[string]map[string]string, db *data.Database) map[string]bool {
+	set := make(map[string]bool)
+	rxp := regexp.MustCompile(`\[([^]]*)\]`)
+	ts := time.Unix(d.Timestamp, 0)
+
+	for field, query := range fields {
+		value := d.Avg
+		if x, ok := query["type"]; ok {
+			if d.Key.Key != rxp.ReplaceAllString(x, "") {
+				continue
+			}
+			col := rxp.FindStringSubmatch(x)
+			if len(col) > 1 {
+				switch col[1] {
+				case "min":
+					value = d.Min
+				case "max":
+					value = d.Max
+				}
+			}
+		}
+
+		if !sensorMatch(query, d.Key.sensor(), db.Registry()) {
+			continue
+		}
+
+		// Rain fields come from a rain counter.
+		var since time.Time
+		switch field {
+		case upload.RainHour:
+			since = ts.Add(-time.Hour)
+		case upload.Rain24Hours:
+			since = ts.Add(-24 * time.Hour)
+		case upload.RainDay:
+			since = bod(ts.In(stationLocation()))
+		}
+		if !since.IsZero() {
+			old, err := db.Station(d.Key.Station).QueryFirst(since.Unix(), d.Key.Key, data.SensorID{ID: d.Key.ID, Channel: d.Key.Channel, Serial: d.Key.Serial})
+			if err != nil {
+				jww.ERROR.Println(err)
+				continue
+			}
+			value -= old
+		}
+
+		obs.Values[field] = value
+		set[field] = true
+	}
+	obs.Time = ts.UTC()
+	return set
+}
+
+func bod(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
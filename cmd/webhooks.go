@@ -0,0 +1,158 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/records"
+	"github.com/geoffholden/gowx/webhook"
+)
+
+// webhooksCmd represents the webhooks command
+var webhooksCmd = &cobra.Command{
+	Use:   "webhooks",
+	Short: "Show the webhook delivery log",
+	Long: `Shows the latest deliveries of the webhooks, which the aggregator sends
+to the endpoints in the "webhooks.hooks" section, e.g.
+
+webhooks:
+  log: gowx-webhooks.log
+  hooks:
+    home:
+      url: https://example.com/weather
+      events: [aggregate, record, status]
+      ids: [outdoor]
+      keys: [Temperature, Humidity]
+      secret: secret
+      template: '{{range .Values}}{{.Key}}={{.Avg}} {{end}}'
+
+Events are "aggregate" for the aggregates of every interval, "record" for a
+new daily high or low and "status" for a sensor going offline or coming back;
+all of them without "events". The "ids" (IDs or registry names) and "keys"
+restrict the data sent. The body is the event as JSON, or the output of the
+Go text/template in "template" or "template_file". With a "secret", the
+X-Gowx-Signature header is "sha256=" and the hex HMAC-SHA256 of the
+X-Gowx-Timestamp header, a dot and the body.
+
+A sensor is offline once it's stale, see "gowx help status".`,
+	Run: webhooksLog,
+}
+
+var webhooksTestCmd = &cobra.Command{
+	Use:   "test NAME",
+	Short: "Send a test event to a webhook",
+	Args:  cobra.ExactArgs(1),
+	Run:   webhooksTest,
+}
+
+func init() {
+	RootCmd.AddCommand(webhooksCmd)
+	webhooksCmd.AddCommand(webhooksTestCmd)
+	webhooksCmd.Flags().Int("count", 20, "Number of deliveries to show, 0 for all")
+
+	viper.SetDefault("webhooks.log", "gowx-webhooks.log")
+}
+
+func webhookSensor(registry *data.Registry, key data.SensorKey) webhook.Sensor {
+	s := webhook.Sensor{ID: key.ID, Channel: key.Channel, Serial: key.Serial}
+	if registry != nil {
+		s.Name, _ = registry.Name(key)
+	}
+	return s
+}
+
+// webhookAggregates returns an aggregate event for each station of the
+// aggregates, in the order the stations first appear.
+func webhookAggregates(db *data.Database, res []aggdata) []webhook.Event {
+	var events []webhook.Event
+	index := make(map[string]int)
+	for _, d := range res {
+		i, ok := index[d.Key.Station]
+		if !ok {
+			i = len(events)
+			index[d.Key.Station] = i
+			events = append(events, webhook.Event{Type: webhook.Aggregate, Station: d.Key.Station})
+		}
+		e := &events[i]
+		e.Values = append(e.Values, webhook.Value{
+			Sensor: webhookSensor(db.Station(d.Key.Station).Registry(), d.Key.sensor()),
+			Key:    d.Key.Key,
+			Min:    d.Min,
+			Max:    d.Max,
+			Avg:    d.Avg,
+		})
+		if t := time.Unix(d.Timestamp, 0).UTC(); t.After(e.Time) {
+			e.Time = t
+		}
+	}
+	return events
+}
+
+func webhookRecord(registry *data.Registry, c records.Change) webhook.Event {
+	return webhook.Event{
+		Type:    webhook.Record,
+		Time:    time.Unix(c.Timestamp, 0).UTC(),
+		Station: c.Station,
+		Record: &webhook.RecordChange{
+			Sensor:   webhookSensor(registry, c.Sensor),
+			Key:      c.Key,
+			Day:      c.Day,
+			Kind:     c.Kind,
+			Value:    c.Value,
+			Previous: c.Previous,
+		},
+	}
+}
+
+func webhookStatus(registry *data.Registry, station string, key data.SensorKey, status string, lastSeen time.Time, now time.Time) webhook.Event {
+	return webhook.Event{
+		Type:    webhook.Status,
+		Time:    now.UTC(),
+		Station: station,
+		Status: &webhook.StatusChange{
+			Sensor:   webhookSensor(registry, key),
+			Status:   status,
+			LastSeen: lastSeen.UTC(),
+		},
+	}
+}
+
+func webhooksLog(cmd *cobra.Command, args []string) {
+	count, _ := cmd.Flags().GetInt("count")
+	deliveries, err := webhook.ReadLog(viper.GetString("webhooks.log"), count)
+	if err != nil {
+		fatal(err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tHOOK\tEVENT\tSTATUS\tATTEMPTS\tDURATION\tERROR")
+	for _, d := range deliveries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\t%s\n", d.Time.In(stationLocation()).Format("2006-01-02 15:04:05"),
+			d.Hook, d.Event, d.Status, d.Attempts, d.Duration.Round(time.Millisecond), d.Error)
+	}
+	w.Flush()
+}
+
+func webhooksTest(cmd *cobra.Command, args []string) {
+	config := viper.Sub("webhooks.hooks." + args[0])
+	if config == nil {
+		fatal(fmt.Errorf("unknown webhook %q", args[0]))
+	}
+	h, err := webhook.NewHook(args[0], config)
+	if err != nil {
+		fatal(err)
+	}
+	d := h.Deliver(webhook.Event{Type: "test", Time: time.Now().UTC(), Station: viper.GetString("station")})
+	webhook.NewLog(viper.GetString("webhooks.log")).Add(d)
+	if d.Error != "" {
+		fatal(fmt.Errorf("%s", d.Error))
+	}
+	fmt.Printf("Delivered to %s: %d\n", h.URL, d.Status)
+}
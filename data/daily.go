@@ -0,0 +1,147 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package data
+
+import (
+	"database/sql"
+	"math"
+)
+
+// DailySummary holds the extremes of one key of one sensor over a day in the
+// station's local time.
+type DailySummary struct {
+	Day     string // YYYY-MM-DD
+	Station string
+	// Name is the logical sensor the summary is for, or "" for an
+	// unregistered sensor. A named summary is shared by all the physical
+	// sensors of the logical one, and SensorKey is the last one seen.
+	Name string
+	SensorKey
+	Key string
+
+	High     float64
+	HighTime int64
+	HighDir  float64 // NaN if no direction was recorded with the high
+	Low      float64
+	LowTime  int64
+
+	MeanSum   float64
+	MeanCount int64
+
+	// Total is the increase over the day of a cumulative value such as
+	// RainTotal, and Latest is the last value seen.
+	Total  float64
+	Latest float64
+}
+
+func (s DailySummary) Mean() float64 {
+	if s.MeanCount == 0 {
+		return math.NaN()
+	}
+	return s.MeanSum / float64(s.MeanCount)
+}
+
+func (database *Database) createDailyTables() error {
+	if _, err := database.db.Exec(`
+	CREATE TABLE IF NOT EXISTS daily_summary (
+		day         varchar(10),
+		id          varchar(128),
+		channel     integer,
+		serial      varchar(128),
+		datakey     varchar(128),
+		high        double precision,
+		high_time   bigint,
+		high_dir    double precision,
+		low         double precision,
+		low_time    bigint,
+		mean_sum    double precision,
+		mean_count  bigint,
+		total       double precision,
+		latest      double precision
+	)`); err != nil {
+		return err
+	}
+	if err := database.addColumn("daily_summary", "sensor", "varchar(128) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	return database.addColumn("daily_summary", "station", "varchar(128) NOT NULL DEFAULT ''")
+}
+
+const dailyColumns = `day, station, sensor, id, channel, serial, datakey, high, high_time, high_dir, low, low_time, mean_sum, mean_count, total, latest`
+
+func scanDaily(scanner interface{ Scan(...interface{}) error }) (DailySummary, error) {
+	var s DailySummary
+	var dir sql.NullFloat64
+	err := scanner.Scan(&s.Day, &s.Station, &s.Name, &s.ID, &s.Channel, &s.Serial, &s.Key, &s.High, &s.HighTime, &dir, &s.Low, &s.LowTime, &s.MeanSum, &s.MeanCount, &s.Total, &s.Latest)
+	s.HighDir = math.NaN()
+	if dir.Valid {
+		s.HighDir = dir.Float64
+	}
+	return s, err
+}
+
+// dailyWhere selects the summary of a logical sensor by name, or of an
+// unregistered one by its physical sensor, at a station.
+const dailyWhere = `day = ? AND station = ? AND sensor = ? AND datakey = ? AND (sensor <> '' OR (id = ? AND channel = ? AND serial = ?))`
+
+// DailySummary loads the summary for one key of a sensor of a station on a
+// day. The sensor is the logical one named, or the physical one if name is
+// "".
+func (database *Database) DailySummary(day string, station string, name string, sensor SensorKey, key string) (DailySummary, bool, error) {
+	row := database.db.QueryRow(database.driver.Rebind(`SELECT `+dailyColumns+` FROM daily_summary WHERE `+dailyWhere),
+		day, station, name, key, sensor.ID, sensor.Channel, sensor.Serial)
+	s, err := scanDaily(row)
+	if err == sql.ErrNoRows {
+		return DailySummary{}, false, nil
+	}
+	return s, err == nil, err
+}
+
+// SaveDailySummary stores a summary, replacing the existing row if exists is
+// set.
+func (database *Database) SaveDailySummary(s DailySummary, exists bool) error {
+	var dir interface{}
+	if !math.IsNaN(s.HighDir) {
+		dir = s.HighDir
+	}
+	if exists {
+		_, err := database.exec(`UPDATE daily_summary SET
+			id = ?, channel = ?, serial = ?,
+			high = ?, high_time = ?, high_dir = ?, low = ?, low_time = ?,
+			mean_sum = ?, mean_count = ?, total = ?, latest = ?
+			WHERE `+dailyWhere,
+			s.ID, s.Channel, s.Serial,
+			s.High, s.HighTime, dir, s.Low, s.LowTime, s.MeanSum, s.MeanCount, s.Total, s.Latest,
+			s.Day, s.Station, s.Name, s.Key, s.ID, s.Channel, s.Serial)
+		return err
+	}
+	_, err := database.exec(`INSERT INTO daily_summary (`+dailyColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.Day, s.Station, s.Name, s.ID, s.Channel, s.Serial, s.Key, s.High, s.HighTime, dir, s.Low, s.LowTime, s.MeanSum, s.MeanCount, s.Total, s.Latest)
+	return err
+}
+
+// DailySummaries returns the summaries of the station of the database for
+// days from first to last inclusive, optionally limited to one key ("" or
+// "%" for all keys).
+func (database *Database) DailySummaries(first string, last string, key string) ([]DailySummary, error) {
+	if key == "" {
+		key = "%"
+	}
+	rows, err := database.query(`SELECT `+dailyColumns+` FROM daily_summary
+		WHERE day >= ? AND day <= ? AND datakey LIKE ? AND (? = '*' OR station = ?)
+		ORDER BY day, station, sensor, id, channel, serial, datakey`, first, last, key, database.station, database.station)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []DailySummary
+	for rows.Next() {
+		s, err := scanDaily(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
@@ -4,14 +4,26 @@ package data
 
 import (
 	"database/sql"
+	"time"
+
+	"github.com/geoffholden/gowx/metrics"
 	"github.com/spf13/viper"
 )
 
 type Database struct {
-	db     *sql.DB
-	driver DBdriver
+	db       *sql.DB
+	driver   DBdriver
+	registry *registryCache
+	// station is the station samples are stored for and queried from,
+	// "" for the default station or AllStations for every station.
+	station string
 }
 
+// AllStations is the name of the station of a database querying every
+// station. The default station is named "", and has the samples stored
+// before there could be more than one station.
+const AllStations = "*"
+
 type Row struct {
 	Timestamp     int64
 	Min, Max, Avg float64
@@ -24,15 +36,26 @@ type WindRow struct {
 
 var drivers map[string]DBdriver
 
+var queryDuration = metrics.NewHistogram("gowx_db_query_duration_seconds", "Time taken by database queries.", metrics.DefBuckets, "query")
+
+func observe(query string, start time.Time) {
+	queryDuration.Observe(time.Since(start).Seconds(), query)
+}
+
 type DBdriver interface {
 	OpenDatabase(db *sql.DB) error
 	Close(db *sql.DB)
-	InsertRow(db *sql.DB, timestamp int64, id string, channel int, serial string, key string, min float64, max float64, avg float64) error
-	QueryWind(db *sql.DB, start int64, key string, col string, id string, channel int) (*sql.Rows, error)
-	QueryFirst(db *sql.DB, start int64, key string, id string, channel int) (float64, error)
-	QueryLast(db *sql.DB, start int64, key string, id string, channel int) (float64, error)
-	QueryRows(db *sql.DB, start int64, key string, id string) (*sql.Rows, error)
-	QueryRowsInterval(db *sql.DB, start int64, key string, id string, interval int64) (*sql.Rows, error)
+	// The station of the queries is matched exactly, or any station if
+	// it's AllStations.
+	InsertRow(db *sql.DB, station string, timestamp int64, id string, channel int, serial string, key string, min float64, max float64, avg float64) error
+	// The queries return the samples after start, up to and including end
+	// unless it's zero, of the sensors selected as in Database.Series.
+	QueryWind(db *sql.DB, station string, start int64, end int64, key string, col string, sensor SensorID) (*sql.Rows, error)
+	QueryFirst(db *sql.DB, station string, start int64, end int64, key string, sensor SensorID) (float64, error)
+	QueryLast(db *sql.DB, station string, start int64, end int64, key string, sensor SensorID) (float64, error)
+	QueryRows(db *sql.DB, station string, start int64, end int64, key string, sensor SensorID) (*sql.Rows, error)
+	QueryRowsInterval(db *sql.DB, station string, start int64, end int64, key string, sensor SensorID, interval int64) (*sql.Rows, error)
+	Rebind(stmt string) string
 }
 
 func init() {
@@ -62,7 +85,66 @@ func OpenDatabase() (*Database, error) {
 	driver := drivers[viper.GetString("dbDriver")]
 	driver.OpenDatabase(db)
 
-	return &Database{db, driver}, nil
+	database := &Database{db: db, driver: driver, station: viper.GetString("station")}
+	database.registry = &registryCache{MaxAge: time.Minute, db: database}
+	for _, create := range []func() error{database.addStationColumn, database.createRegistryTables, database.createDailyTables, database.createHealthTables} {
+		if err := create(); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return database, nil
+}
+
+// addStationColumn adds the station column to the samples of databases
+// made before there could be more than one station. Their samples are all
+// from the default station, "".
+func (database *Database) addStationColumn() error {
+	return database.addColumn("samples", "station", "varchar(128) NOT NULL DEFAULT ''")
+}
+
+// addColumn adds a column to a table made by an older version, if it's
+// missing.
+func (database *Database) addColumn(table string, column string, definition string) error {
+	rows, err := database.db.Query(`SELECT ` + column + ` FROM ` + table + ` WHERE 1 = 0`)
+	if err == nil {
+		return rows.Close()
+	}
+	_, err = database.db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition)
+	return err
+}
+
+// Station returns the database with samples stored for, and queries
+// limited to, the named station. The sensor tables are shared, but its
+// registry matches the station's sensors. An empty name is the default
+// station, and AllStations queries every station.
+func (database *Database) Station(name string) *Database {
+	scoped := *database
+	scoped.station = name
+	return &scoped
+}
+
+// StationName returns the station the database is limited to.
+func (database *Database) StationName() string {
+	return database.station
+}
+
+// Stations returns the names of the stations with stored samples, sorted.
+func (database *Database) Stations() ([]string, error) {
+	rows, err := database.query(`SELECT DISTINCT station FROM samples ORDER BY station`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		result = append(result, name)
+	}
+	return result, rows.Err()
 }
 
 func (database *Database) Close() {
@@ -71,11 +153,14 @@ func (database *Database) Close() {
 }
 
 func (database *Database) InsertRow(timestamp int64, id string, channel int, serial string, key string, min float64, max float64, avg float64) error {
-	return database.driver.InsertRow(database.db, timestamp, id, channel, serial, key, min, max, avg)
+	defer observe("insert", time.Now())
+	return database.driver.InsertRow(database.db, database.station, timestamp, id, channel, serial, key, min, max, avg)
 }
 
-func (database *Database) QueryWind(start int64, key string, col string, id string, channel int) <-chan WindRow {
-	rows, err := database.driver.QueryWind(database.db, start, key, col, id, channel)
+func (database *Database) QueryWind(start int64, key string, col string, sensor SensorID) <-chan WindRow {
+	begin := time.Now()
+	rows, err := database.driver.QueryWind(database.db, database.station, start, 0, key, col, sensor)
+	observe("wind", begin)
 	if err != nil {
 		return nil
 	}
@@ -96,16 +181,22 @@ func (database *Database) QueryWind(start int64, key string, col string, id stri
 	return ch
 }
 
-func (database *Database) QueryFirst(start int64, key string, id string, channel int) (float64, error) {
-	return database.driver.QueryFirst(database.db, start, key, id, channel)
+func (database *Database) QueryFirst(start int64, key string, sensor SensorID) (float64, error) {
+	defer observe("first", time.Now())
+	return database.driver.QueryFirst(database.db, database.station, start, 0, key, sensor)
 }
 
-func (database *Database) QueryLast(start int64, key string, id string, channel int) (float64, error) {
-	return database.driver.QueryLast(database.db, start, key, id, channel)
+func (database *Database) QueryLast(start int64, key string, sensor SensorID) (float64, error) {
+	defer observe("last", time.Now())
+	return database.driver.QueryLast(database.db, database.station, start, 0, key, sensor)
 }
 
-func (database *Database) QueryRows(start int64, key string, id string) <-chan Row {
-	rows, err := database.driver.QueryRows(database.db, start, key, id)
+// QueryRows returns the samples for a key of a sensor since start. A
+// negative channel matches any channel, and an empty serial any serial.
+func (database *Database) QueryRows(start int64, key string, sensor SensorID) <-chan Row {
+	begin := time.Now()
+	rows, err := database.driver.QueryRows(database.db, database.station, start, 0, key, sensor)
+	observe("rows", begin)
 	if err != nil {
 		return nil
 	}
@@ -129,8 +220,10 @@ func (database *Database) QueryRows(start int64, key string, id string) <-chan R
 	return ch
 }
 
-func (database *Database) QueryRowsInterval(start int64, key string, id string, interval int64) <-chan Row {
-	rows, err := database.driver.QueryRowsInterval(database.db, start, key, id, interval)
+func (database *Database) QueryRowsInterval(start int64, key string, sensor SensorID, interval int64) <-chan Row {
+	begin := time.Now()
+	rows, err := database.driver.QueryRowsInterval(database.db, database.station, start, 0, key, sensor, interval)
+	observe("rows_interval", begin)
 	if err != nil {
 		return nil
 	}
@@ -153,3 +246,71 @@ func (database *Database) QueryRowsInterval(start int64, key string, id string,
 
 	return ch
 }
+
+// Series returns the samples of a key after start and up to end, or all of
+// them if end is zero. With an interval of more than a second they're
+// combined into intervals of that many seconds. The ID of the sensor is a
+// LIKE pattern, a negative channel matches any channel and an empty serial
+// any serial.
+func (database *Database) Series(start int64, end int64, key string, sensor SensorID, interval int64) ([]Row, error) {
+	begin := time.Now()
+	var rows *sql.Rows
+	var err error
+	if interval > 1 {
+		rows, err = database.driver.QueryRowsInterval(database.db, database.station, start, end, key, sensor, interval)
+		observe("rows_interval", begin)
+	} else {
+		rows, err = database.driver.QueryRows(database.db, database.station, start, end, key, sensor)
+		observe("rows", begin)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []Row
+	for rows.Next() {
+		var r Row
+		if err := rows.Scan(&r.Timestamp, &r.Min, &r.Max, &r.Avg); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// WindRose returns the column of a key by wind direction, in 32 sectors,
+// between start and end, of the sensors selected as in Series.
+func (database *Database) WindRose(start int64, end int64, key string, col string, sensor SensorID) ([]WindRow, error) {
+	defer observe("wind", time.Now())
+	rows, err := database.driver.QueryWind(database.db, database.station, start, end, key, col, sensor)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []WindRow
+	for rows.Next() {
+		var r WindRow
+		if err := rows.Scan(&r.Dir, &r.Value); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// Change returns the first and last averages of a key between start and
+// end, of the sensors selected as in Series, and false if there are none.
+func (database *Database) Change(start int64, end int64, key string, sensor SensorID) (float64, float64, bool, error) {
+	defer observe("change", time.Now())
+	first, err := database.driver.QueryFirst(database.db, database.station, start, end, key, sensor)
+	if err == sql.ErrNoRows {
+		return 0, 0, false, nil
+	} else if err != nil {
+		return 0, 0, false, err
+	}
+	last, err := database.driver.QueryLast(database.db, database.station, start, end, key, sensor)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return first, last, true, nil
+}
@@ -0,0 +1,141 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package data
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestStation(t *testing.T) {
+	db := openTestDatabase(t)
+	home, cottage := db.Station("home"), db.Station("cottage")
+	for i, temp := range []float64{10, 12} {
+		home.InsertRow(int64(100+i), "OS3:1D20", 1, "", "Temperature", temp, temp, temp)
+		cottage.InsertRow(int64(100+i), "OS3:1D20", 1, "", "Temperature", temp+10, temp+10, temp+10)
+	}
+	cottage.InsertRow(100, "THGR810", 2, "", "Humidity", 50, 50, 50)
+
+	if rows, err := home.Series(0, 0, "Temperature", SensorID{ID: "%", Channel: -1}, 1); err != nil || len(rows) != 2 || rows[0].Avg != 10 {
+		t.Error("Unexpected home samples", rows, err)
+	}
+	if rows, err := db.Station(AllStations).Series(0, 0, "Temperature", SensorID{ID: "%", Channel: -1}, 1); err != nil || len(rows) != 4 {
+		t.Error("Unexpected samples of every station", rows, err)
+	}
+	// The default station has only its own samples.
+	db.InsertRow(100, "OS3:1D20", 1, "", "Temperature", 5, 5, 5)
+	if rows, err := db.Series(0, 0, "Temperature", SensorID{ID: "%", Channel: -1}, 1); err != nil || len(rows) != 1 || rows[0].Avg != 5 {
+		t.Error("Unexpected samples of the default station", rows, err)
+	}
+	if first, last, found, err := cottage.Change(0, 0, "Temperature", SensorID{ID: "%", Channel: -1}); err != nil || !found || first != 20 || last != 22 {
+		t.Error("Unexpected cottage change", first, last, found, err)
+	}
+	if ids, err := home.PhysicalSensors(); err != nil || len(ids) != 1 {
+		t.Error("Unexpected home sensors", ids, err)
+	}
+	if names, err := db.Stations(); err != nil || strings.Join(names, ",") != ",cottage,home" {
+		t.Error("Unexpected stations", names, err)
+	}
+	if db.StationName() != "" || home.StationName() != "home" {
+		t.Error("Unexpected station names")
+	}
+}
+
+func TestStationColumnAdded(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "gowx.db")
+	old, err := sql.Open("sqlite3", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Exec(`CREATE TABLE samples (timestamp integer, id text, channel integer, serial text, key text, min real, max real, avg real)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Exec(`INSERT INTO samples VALUES (100, 'OS3:1D20', 1, '', 'Temperature', 5, 5, 5)`); err != nil {
+		t.Fatal(err)
+	}
+	old.Close()
+
+	viper.Set("dbDriver", "sqlite3")
+	viper.Set("database", file)
+	db, err := OpenDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if names, err := db.Stations(); err != nil || len(names) != 1 || names[0] != "" {
+		t.Error("Old samples should belong to no station", names, err)
+	}
+	if rows, err := db.Series(0, 0, "Temperature", SensorID{ID: "%", Channel: -1}, 1); err != nil || len(rows) != 1 {
+		t.Error("Unexpected samples", rows, err)
+	}
+	if rows, err := db.Station("home").Series(0, 0, "Temperature", SensorID{ID: "%", Channel: -1}, 1); err != nil || len(rows) != 0 {
+		t.Error("Unexpected home samples", rows, err)
+	}
+}
+
+func TestSeriesSerial(t *testing.T) {
+	db := openTestDatabase(t)
+	db.InsertRow(100, "OS3:1D20", 1, "A1", "Temperature", 10, 10, 10)
+	db.InsertRow(101, "OS3:1D20", 1, "B2", "Temperature", 20, 20, 20)
+
+	if rows, err := db.Series(0, 0, "Temperature", SensorID{ID: "OS3:1D20", Channel: 1, Serial: "B2"}, 1); err != nil || len(rows) != 1 || rows[0].Avg != 20 {
+		t.Error("Unexpected samples of one serial", rows, err)
+	}
+	if rows, err := db.Series(0, 0, "Temperature", SensorID{ID: "OS3:1D20", Channel: 1}, 1); err != nil || len(rows) != 2 {
+		t.Error("Unexpected samples of any serial", rows, err)
+	}
+}
+
+func TestDailySummaryName(t *testing.T) {
+	db := openTestDatabase(t)
+	old := SensorKey{ID: "OS3:1D20", Channel: 1, Serial: "A1"}
+	s := DailySummary{Day: "2026-09-01", Name: "outdoor", SensorKey: old, Key: "Temperature", High: 12, Low: 10, MeanSum: 11, MeanCount: 1}
+	if err := db.SaveDailySummary(s, false); err != nil {
+		t.Fatal(err)
+	}
+	unnamed := DailySummary{Day: "2026-09-01", SensorKey: SensorKey{ID: "THGR810", Channel: 2}, Key: "Temperature", High: 20, Low: 20, MeanSum: 20, MeanCount: 1}
+	if err := db.SaveDailySummary(unnamed, false); err != nil {
+		t.Fatal(err)
+	}
+
+	replaced := SensorKey{ID: "OS3:1D20", Channel: 1, Serial: "B2"}
+	s, found, err := db.DailySummary("2026-09-01", "", "outdoor", replaced, "Temperature")
+	if err != nil || !found || s.High != 12 || s.SensorKey != old {
+		t.Fatal("Unexpected summary of the logical sensor", s, found, err)
+	}
+	s.SensorKey, s.Low = replaced, 8
+	if err := db.SaveDailySummary(s, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := db.DailySummary("2026-09-01", "", "", replaced, "Temperature"); found {
+		t.Error("The physical sensor should have no summary of its own")
+	}
+	summaries, err := db.DailySummaries("2026-09-01", "2026-09-01", "Temperature")
+	if err != nil || len(summaries) != 2 || summaries[1].Name != "outdoor" || summaries[1].SensorKey != replaced || summaries[1].Low != 8 {
+		t.Error("Unexpected summaries", summaries, err)
+	}
+}
+
+func TestDailySummaryStation(t *testing.T) {
+	db := openTestDatabase(t)
+	outdoor := SensorKey{ID: "OS3:1D20", Channel: 1}
+	for i, station := range []string{"", "cottage"} {
+		s := DailySummary{Day: "2026-09-01", Station: station, Name: "outdoor", SensorKey: outdoor, Key: "Temperature", High: float64(10 * i), MeanCount: 1}
+		if err := db.SaveDailySummary(s, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s, found, err := db.DailySummary("2026-09-01", "cottage", "outdoor", outdoor, "Temperature"); err != nil || !found || s.High != 10 {
+		t.Error("Unexpected summary of the cottage", s, found, err)
+	}
+	if summaries, err := db.DailySummaries("2026-09-01", "2026-09-01", ""); err != nil || len(summaries) != 1 || summaries[0].Station != "" {
+		t.Error("Unexpected summaries of the default station", summaries, err)
+	}
+	if summaries, err := db.Station(AllStations).DailySummaries("2026-09-01", "2026-09-01", ""); err != nil || len(summaries) != 2 {
+		t.Error("Unexpected summaries of every station", summaries, err)
+	}
+}
@@ -6,10 +6,17 @@ import "time"
 
 type SensorData struct {
 	TimeStamp time.Time
-	ID        string
-	Channel   int
-	Serial    string
-	Data      map[string]float64
+	// Station is the station the sensor belongs to, "" if it isn't named.
+	Station string `json:",omitempty"`
+	ID      string
+	Channel int
+	Serial  string
+	Data    map[string]float64
+}
+
+// Key returns the physical sensor the data came from.
+func (d SensorData) Key() SensorKey {
+	return SensorKey{d.ID, d.Channel, d.Serial}
 }
 
 type SensorParser interface {
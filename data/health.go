@@ -0,0 +1,117 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package data
+
+import "database/sql"
+
+// SensorStatus is the last-seen entry of a physical sensor of a station.
+type SensorStatus struct {
+	Station string `json:",omitempty"`
+	SensorKey
+	LastSeen int64
+	// Interval is the expected time between samples in seconds, as learned
+	// from the samples.
+	Interval float64
+}
+
+// Gap is an outage of a sensor, from its last sample before the outage to
+// its first sample after.
+type Gap struct {
+	Station string `json:",omitempty"`
+	SensorKey
+	Start int64
+	End   int64
+}
+
+func (database *Database) createHealthTables() error {
+	if _, err := database.db.Exec(`
+	CREATE TABLE IF NOT EXISTS sensor_status (
+		id          varchar(128),
+		channel     integer,
+		serial      varchar(128),
+		last_seen   bigint,
+		expected    double precision
+	)`); err != nil {
+		return err
+	}
+	if _, err := database.db.Exec(`
+	CREATE TABLE IF NOT EXISTS gaps (
+		id          varchar(128),
+		channel     integer,
+		serial      varchar(128),
+		gap_start   bigint,
+		gap_end     bigint
+	)`); err != nil {
+		return err
+	}
+	for _, table := range []string{"sensor_status", "gaps"} {
+		if err := database.addColumn(table, "station", "varchar(128) NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SensorStatuses returns the last-seen table of the station of the
+// database.
+func (database *Database) SensorStatuses() ([]SensorStatus, error) {
+	rows, err := database.query(`SELECT station, id, channel, serial, last_seen, expected FROM sensor_status
+		WHERE (? = '*' OR station = ?)
+		ORDER BY station, id, channel, serial`, database.station, database.station)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []SensorStatus
+	for rows.Next() {
+		var s SensorStatus
+		var expected sql.NullFloat64
+		if err := rows.Scan(&s.Station, &s.ID, &s.Channel, &s.Serial, &s.LastSeen, &expected); err != nil {
+			return nil, err
+		}
+		s.Interval = expected.Float64
+		result = append(result, s)
+	}
+	return result, rows.Err()
+}
+
+// SaveSensorStatus stores the entry of a sensor, replacing the existing row
+// if exists is set.
+func (database *Database) SaveSensorStatus(s SensorStatus, exists bool) error {
+	if exists {
+		_, err := database.exec(`UPDATE sensor_status SET last_seen = ?, expected = ?
+			WHERE station = ? AND id = ? AND channel = ? AND serial = ?`,
+			s.LastSeen, s.Interval, s.Station, s.ID, s.Channel, s.Serial)
+		return err
+	}
+	_, err := database.exec(`INSERT INTO sensor_status (station, id, channel, serial, last_seen, expected)
+		VALUES (?, ?, ?, ?, ?, ?)`, s.Station, s.ID, s.Channel, s.Serial, s.LastSeen, s.Interval)
+	return err
+}
+
+// InsertGap records an outage.
+func (database *Database) InsertGap(g Gap) error {
+	_, err := database.exec(`INSERT INTO gaps (station, id, channel, serial, gap_start, gap_end)
+		VALUES (?, ?, ?, ?, ?, ?)`, g.Station, g.ID, g.Channel, g.Serial, g.Start, g.End)
+	return err
+}
+
+// Gaps returns the outages at the station of the database that ended after
+// start, in time order.
+func (database *Database) Gaps(start int64) ([]Gap, error) {
+	rows, err := database.query(`SELECT station, id, channel, serial, gap_start, gap_end FROM gaps
+		WHERE gap_end > ? AND (? = '*' OR station = ?) ORDER BY gap_start`, start, database.station, database.station)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []Gap
+	for rows.Next() {
+		var g Gap
+		if err := rows.Scan(&g.Station, &g.ID, &g.Channel, &g.Serial, &g.Start, &g.End); err != nil {
+			return nil, err
+		}
+		result = append(result, g)
+	}
+	return result, rows.Err()
+}
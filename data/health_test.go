@@ -0,0 +1,49 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package data
+
+import "testing"
+
+func TestSensorStatuses(t *testing.T) {
+	db := openTestDatabase(t)
+	outdoor := SensorKey{"OS3:1D20", 1, "A4"}
+	if err := db.SaveSensorStatus(SensorStatus{"", outdoor, 1000, 39}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveSensorStatus(SensorStatus{"", outdoor, 1039, 39.5}, true); err != nil {
+		t.Fatal(err)
+	}
+	// The same sensor at another station has an entry of its own.
+	if err := db.SaveSensorStatus(SensorStatus{"cottage", outdoor, 900, 60}, false); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := db.SensorStatuses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0] != (SensorStatus{"", outdoor, 1039, 39.5}) {
+		t.Error("Unexpected statuses", statuses)
+	}
+
+	for _, g := range []Gap{{"", outdoor, 100, 200}, {"", outdoor, 1000, 2000}, {"cottage", outdoor, 1000, 2000}} {
+		if err := db.InsertGap(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gaps, err := db.Gaps(500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gaps) != 1 || gaps[0] != (Gap{"", outdoor, 1000, 2000}) {
+		t.Error("Unexpected gaps", gaps)
+	}
+
+	statuses, err = db.Station(AllStations).SensorStatuses()
+	if err != nil || len(statuses) != 2 || statuses[1] != (SensorStatus{"cottage", outdoor, 900, 60}) {
+		t.Error("Unexpected statuses of every station", statuses, err)
+	}
+	gaps, err = db.Station("cottage").Gaps(500)
+	if err != nil || len(gaps) != 1 || gaps[0].Station != "cottage" {
+		t.Error("Unexpected gaps of the station", gaps, err)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package data
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SensorKey identifies a physical sensor.
+type SensorKey struct {
+	ID      string
+	Channel int
+	Serial  string
+}
+
+// LatestValue is a value and the time it was measured.
+type LatestValue struct {
+	Value     float64
+	TimeStamp time.Time
+}
+
+// SensorState is the most recent data received from a sensor.
+type SensorState struct {
+	// Station is the station the sensor belongs to, "" for the default.
+	Station string
+	SensorKey
+	LastSeen time.Time
+	Values   map[string]LatestValue
+}
+
+// stationSensor identifies a sensor of a station, as the same sensor IDs
+// can be received by more than one station.
+type stationSensor struct {
+	Station string
+	SensorKey
+}
+
+// Latest keeps the most recent value of every key of every sensor of every
+// station. It is safe for concurrent use.
+type Latest struct {
+	mu      sync.RWMutex
+	sensors map[stationSensor]*SensorState
+}
+
+// NewLatest creates an empty store.
+func NewLatest() *Latest {
+	return &Latest{sensors: make(map[stationSensor]*SensorState)}
+}
+
+// Update stores the values of a sample.
+func (l *Latest) Update(d SensorData) {
+	if d.ID == "" {
+		return
+	}
+	timestamp := d.TimeStamp
+	if timestamp.IsZero() {
+		timestamp = time.Now().UTC()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := stationSensor{d.Station, SensorKey{d.ID, d.Channel, d.Serial}}
+	state, ok := l.sensors[key]
+	if !ok {
+		state = &SensorState{Station: key.Station, SensorKey: key.SensorKey, Values: make(map[string]LatestValue)}
+		l.sensors[key] = state
+	}
+	if timestamp.After(state.LastSeen) {
+		state.LastSeen = timestamp
+	}
+	for k, v := range d.Data {
+		// Samples replayed late don't replace newer values.
+		if old, ok := state.Values[k]; ok && timestamp.Before(old.TimeStamp) {
+			continue
+		}
+		state.Values[k] = LatestValue{v, timestamp}
+	}
+}
+
+// Snapshot returns a copy of the state of every sensor, sorted by station,
+// ID, channel and serial.
+func (l *Latest) Snapshot() []SensorState {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	result := make([]SensorState, 0, len(l.sensors))
+	for _, state := range l.sensors {
+		s := SensorState{Station: state.Station, SensorKey: state.SensorKey, LastSeen: state.LastSeen, Values: make(map[string]LatestValue, len(state.Values))}
+		for k, v := range state.Values {
+			s.Values[k] = v
+		}
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Station != result[j].Station {
+			return result[i].Station < result[j].Station
+		}
+		a, b := result[i].SensorKey, result[j].SensorKey
+		if a.ID != b.ID {
+			return a.ID < b.ID
+		}
+		if a.Channel != b.Channel {
+			return a.Channel < b.Channel
+		}
+		return a.Serial < b.Serial
+	})
+	return result
+}
@@ -0,0 +1,43 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatest(t *testing.T) {
+	l := NewLatest()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	l.Update(SensorData{TimeStamp: now, ID: "OS3:1D20", Channel: 1, Data: map[string]float64{"Temperature": 12.5, "Humidity": 80}})
+	l.Update(SensorData{TimeStamp: now.Add(-time.Minute), ID: "OS3:1D20", Channel: 1, Data: map[string]float64{"Temperature": 11}})
+	l.Update(SensorData{TimeStamp: now.Add(time.Minute), ID: "OS3:1D20", Channel: 1, Data: map[string]float64{"Humidity": 82}})
+	l.Update(SensorData{TimeStamp: now, ID: "BHTR968", Data: map[string]float64{"Pressure": 1012}})
+
+	s := l.Snapshot()
+	if len(s) != 2 || s[0].ID != "BHTR968" || s[1].ID != "OS3:1D20" {
+		t.Fatal("Unexpected sensors", s)
+	}
+	if v := s[1].Values["Temperature"]; v.Value != 12.5 || !v.TimeStamp.Equal(now) {
+		t.Error("A late sample shouldn't replace a newer value", v)
+	}
+	if v := s[1].Values["Humidity"]; v.Value != 82 || !s[1].LastSeen.Equal(now.Add(time.Minute)) {
+		t.Error("Unexpected humidity", v, s[1].LastSeen)
+	}
+}
+
+func TestLatestStations(t *testing.T) {
+	l := NewLatest()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	l.Update(SensorData{TimeStamp: now, Station: "cabin", ID: "BHTR968", Data: map[string]float64{"Pressure": 1002}})
+	l.Update(SensorData{TimeStamp: now, ID: "BHTR968", Data: map[string]float64{"Pressure": 1012}})
+
+	s := l.Snapshot()
+	if len(s) != 2 || s[0].Station != "" || s[1].Station != "cabin" {
+		t.Fatal("Unexpected sensors", s)
+	}
+	if s[0].Values["Pressure"].Value != 1012 || s[1].Values["Pressure"].Value != 1002 {
+		t.Error("Stations should be kept apart", s)
+	}
+}
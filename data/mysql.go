@@ -20,6 +20,7 @@ func (mysql mysql_driver) OpenDatabase(db *sql.DB) error {
 	if _, err := db.Exec(`
 	CREATE TABLE IF NOT EXISTS samples (
 		timestamp   timestamp,
+		station     varchar(128) NOT NULL DEFAULT '',
 		id          varchar(128),
 		channel     integer,
 		serial      varchar(128),
@@ -65,21 +66,22 @@ func (mysql mysql_driver) OpenDatabase(db *sql.DB) error {
 func (mysql mysql_driver) Close(db *sql.DB) {
 }
 
-func (mysql mysql_driver) InsertRow(db *sql.DB, timestamp int64, id string, channel int, serial string, key string, min float64, max float64, avg float64) error {
+func (mysql mysql_driver) InsertRow(db *sql.DB, station string, timestamp int64, id string, channel int, serial string, key string, min float64, max float64, avg float64) error {
 	stmt := `INSERT INTO samples (
 		timestamp,
+		station,
 		id,
 		channel,
 		serial,
 		key_,
 		min, max, avg
-	) VALUES (FROM_UNIXTIME(?), ?, ?, ?, ?, ?, ?, ?)`
+	) VALUES (FROM_UNIXTIME(?), ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	_, err := db.Exec(stmt, timestamp, id, channel, serial, key, min, max, avg)
+	_, err := db.Exec(stmt, timestamp, station, id, channel, serial, key, min, max, avg)
 	return err
 }
 
-func (mysql mysql_driver) QueryWind(db *sql.DB, start int64, key string, col string, id string, channel int) (*sql.Rows, error) {
+func (mysql mysql_driver) QueryWind(db *sql.DB, station string, start int64, end int64, key string, col string, sensor SensorID) (*sql.Rows, error) {
 	var column string
 	switch col {
 	case "avg":
@@ -97,60 +99,77 @@ func (mysql mysql_driver) QueryWind(db *sql.DB, start int64, key string, col str
 		` FROM samples dir
 		INNER JOIN samples d
 			ON d.timestamp = dir.timestamp
+			AND d.station = dir.station
 		WHERE dir.key_ = 'WindDir'
 			AND d.key_ = ?
 			AND dir.id LIKE ?
 			AND d.id LIKE ?
+			AND (dir.channel = ? OR ? < 0)
+			AND (d.channel = ? OR ? < 0)
 			AND dir.timestamp > FROM_UNIXTIME(?)
+			AND (? = 0 OR dir.timestamp <= FROM_UNIXTIME(?))
+			AND (? = '*' OR dir.station = ?)
+			AND (? = '' OR dir.serial = ?)
+			AND (? = '' OR d.serial = ?)
 		GROUP BY dir;`
-	return db.Query(stmt, key, id, id, start)
+	return db.Query(stmt, key, sensor.ID, sensor.ID, sensor.Channel, sensor.Channel, sensor.Channel, sensor.Channel, start, end, end, station, station, sensor.Serial, sensor.Serial, sensor.Serial, sensor.Serial)
 }
 
-func (mysql mysql_driver) QueryFirst(db *sql.DB, start int64, key string, id string, channel int) (float64, error) {
+func (mysql mysql_driver) QueryFirst(db *sql.DB, station string, start int64, end int64, key string, sensor SensorID) (float64, error) {
 	stmt := `SELECT
 		avg FROM samples
 		WHERE
 			key_ = ? AND
 			id LIKE ? AND
-			channel = ? AND
-			timestamp > FROM_UNIXTIME(?)
+			(channel = ? OR ? < 0) AND
+			timestamp > FROM_UNIXTIME(?) AND
+			(? = 0 OR timestamp <= FROM_UNIXTIME(?)) AND
+			(? = '*' OR station = ?) AND
+			(? = '' OR serial = ?)
 		ORDER BY timestamp
 		LIMIT 1`
-	row := db.QueryRow(stmt, key, id, channel, start)
+	row := db.QueryRow(stmt, key, sensor.ID, sensor.Channel, sensor.Channel, start, end, end, station, station, sensor.Serial, sensor.Serial)
 	var result float64
 	err := row.Scan(&result)
 
 	return result, err
 }
 
-func (mysql mysql_driver) QueryLast(db *sql.DB, start int64, key string, id string, channel int) (float64, error) {
+func (mysql mysql_driver) QueryLast(db *sql.DB, station string, start int64, end int64, key string, sensor SensorID) (float64, error) {
 	stmt := `SELECT
 		avg FROM samples
 		WHERE
 			key_ = ? AND
 			id LIKE ? AND
-			channel = ? AND
-			timestamp > FROM_UNIXTIME(?)
+			(channel = ? OR ? < 0) AND
+			timestamp > FROM_UNIXTIME(?) AND
+			(? = 0 OR timestamp <= FROM_UNIXTIME(?)) AND
+			(? = '*' OR station = ?) AND
+			(? = '' OR serial = ?)
 		ORDER BY timestamp DESC
 		LIMIT 1`
-	row := db.QueryRow(stmt, key, id, channel, start)
+	row := db.QueryRow(stmt, key, sensor.ID, sensor.Channel, sensor.Channel, start, end, end, station, station, sensor.Serial, sensor.Serial)
 	var result float64
 	err := row.Scan(&result)
 
 	return result, err
 }
 
-func (mysql mysql_driver) QueryRows(db *sql.DB, start int64, key string, id string) (*sql.Rows, error) {
+func (mysql mysql_driver) QueryRows(db *sql.DB, station string, start int64, end int64, key string, sensor SensorID) (*sql.Rows, error) {
 	stmt := `SELECT UNIX_TIMESTAMP(timestamp),min,max,avg FROM samples
 		WHERE
 			key_ = ? AND
 			id LIKE ? AND
-			timestamp > FROM_UNIXTIME(?)
+			(channel = ? OR ? < 0) AND
+			timestamp > FROM_UNIXTIME(?) AND
+			(? = 0 OR timestamp <= FROM_UNIXTIME(?)) AND
+			(? = '*' OR station = ?) AND
+			(? = '' OR serial = ?)
 		ORDER BY timestamp`
-	return db.Query(stmt, key, id, start)
+	return db.Query(stmt, key, sensor.ID, sensor.Channel, sensor.Channel, start, end, end, station, station, sensor.Serial, sensor.Serial)
 }
 
-func (mysql mysql_driver) QueryRowsInterval(db *sql.DB, start int64, key string, id string, interval int64) (*sql.Rows, error) {
+func (mysql mysql_driver) QueryRowsInterval(db *sql.DB, station string, start int64, end int64, key string, sensor SensorID, interval int64) (*sql.Rows, error) {
 	stmt := `SELECT
 			CAST(UNIX_TIMESTAMP(timestamp)/? as UNSIGNED) * ? as ts,
 			MIN(min),
@@ -160,8 +179,16 @@ func (mysql mysql_driver) QueryRowsInterval(db *sql.DB, start int64, key string,
 		WHERE
 			key_ = ? AND
 			id LIKE ? AND
-			timestamp > FROM_UNIXTIME(?)
+			(channel = ? OR ? < 0) AND
+			timestamp > FROM_UNIXTIME(?) AND
+			(? = 0 OR timestamp <= FROM_UNIXTIME(?)) AND
+			(? = '*' OR station = ?) AND
+			(? = '' OR serial = ?)
 		GROUP BY ts
 		ORDER BY ts`
-	return db.Query(stmt, interval, interval, key, id, start)
+	return db.Query(stmt, interval, interval, key, sensor.ID, sensor.Channel, sensor.Channel, start, end, end, station, station, sensor.Serial, sensor.Serial)
+}
+
+func (mysql mysql_driver) Rebind(stmt string) string {
+	return stmt
 }
@@ -4,6 +4,8 @@ package data
 
 import (
 	"database/sql"
+	"strconv"
+	"strings"
 
 	_ "github.com/lib/pq"
 )
@@ -19,6 +21,7 @@ func (postgres postgres_driver) OpenDatabase(db *sql.DB) error {
 	if _, err := db.Exec(`
 	CREATE TABLE IF NOT EXISTS samples (
 		timestamp   timestamp,
+		station     text NOT NULL DEFAULT '',
 		id          text,
 		channel     integer,
 		serial      text,
@@ -49,21 +52,22 @@ func (postgres postgres_driver) OpenDatabase(db *sql.DB) error {
 func (postgres postgres_driver) Close(db *sql.DB) {
 }
 
-func (postgres postgres_driver) InsertRow(db *sql.DB, timestamp int64, id string, channel int, serial string, key string, min float64, max float64, avg float64) error {
+func (postgres postgres_driver) InsertRow(db *sql.DB, station string, timestamp int64, id string, channel int, serial string, key string, min float64, max float64, avg float64) error {
 	stmt := `INSERT INTO samples (
 		timestamp,
+		station,
 		id,
 		channel,
 		serial,
 		key,
 		min, max, avg
-	) VALUES (to_timestamp($1), $2, $3, $4, $5, $6, $7, $8)`
+	) VALUES (to_timestamp($1), $2, $3, $4, $5, $6, $7, $8, $9)`
 
-	_, err := db.Exec(stmt, timestamp, id, channel, serial, key, min, max, avg)
+	_, err := db.Exec(stmt, timestamp, station, id, channel, serial, key, min, max, avg)
 	return err
 }
 
-func (postgres postgres_driver) QueryWind(db *sql.DB, start int64, key string, col string, id string, channel int) (*sql.Rows, error) {
+func (postgres postgres_driver) QueryWind(db *sql.DB, station string, start int64, end int64, key string, col string, sensor SensorID) (*sql.Rows, error) {
 	var column string
 	switch col {
 	case "avg":
@@ -81,60 +85,77 @@ func (postgres postgres_driver) QueryWind(db *sql.DB, start int64, key string, c
 		` FROM samples dir
 		INNER JOIN samples d
 			ON d.timestamp = dir.timestamp
+			AND d.station = dir.station
 		WHERE dir.key = 'WindDir'
 			AND d.key = $1
 			AND dir.id LIKE $2
 			AND d.id LIKE $3
-			AND dir.timestamp > to_timestamp($4)
+			AND (dir.channel = $4 OR $4 < 0)
+			AND (d.channel = $4 OR $4 < 0)
+			AND dir.timestamp > to_timestamp($5)
+			AND ($6 = 0 OR dir.timestamp <= to_timestamp($6))
+			AND ($7 = '*' OR dir.station = $7)
+			AND ($8 = '' OR dir.serial = $8)
+			AND ($8 = '' OR d.serial = $8)
 		GROUP BY dir;`
-	return db.Query(stmt, key, id, id, start)
+	return db.Query(stmt, key, sensor.ID, sensor.ID, sensor.Channel, start, end, station, sensor.Serial)
 }
 
-func (postgres postgres_driver) QueryFirst(db *sql.DB, start int64, key string, id string, channel int) (float64, error) {
+func (postgres postgres_driver) QueryFirst(db *sql.DB, station string, start int64, end int64, key string, sensor SensorID) (float64, error) {
 	stmt := `SELECT
 		avg FROM samples
 		WHERE
 			key = $1 AND
 			id LIKE $2 AND
-			channel = $3 AND
-			timestamp > to_timestamp($4)
+			(channel = $3 OR $3 < 0) AND
+			timestamp > to_timestamp($4) AND
+			($5 = 0 OR timestamp <= to_timestamp($5)) AND
+			($6 = '*' OR station = $6) AND
+			($7 = '' OR serial = $7)
 		ORDER BY timestamp
 		LIMIT 1`
-	row := db.QueryRow(stmt, key, id, channel, start)
+	row := db.QueryRow(stmt, key, sensor.ID, sensor.Channel, start, end, station, sensor.Serial)
 	var result float64
 	err := row.Scan(&result)
 
 	return result, err
 }
 
-func (postgres postgres_driver) QueryLast(db *sql.DB, start int64, key string, id string, channel int) (float64, error) {
+func (postgres postgres_driver) QueryLast(db *sql.DB, station string, start int64, end int64, key string, sensor SensorID) (float64, error) {
 	stmt := `SELECT
 		avg FROM samples
 		WHERE
 			key = $1 AND
 			id LIKE $2 AND
-			channel = $3 AND
-			timestamp > to_timestamp($4)
+			(channel = $3 OR $3 < 0) AND
+			timestamp > to_timestamp($4) AND
+			($5 = 0 OR timestamp <= to_timestamp($5)) AND
+			($6 = '*' OR station = $6) AND
+			($7 = '' OR serial = $7)
 		ORDER BY timestamp DESC
 		LIMIT 1`
-	row := db.QueryRow(stmt, key, id, channel, start)
+	row := db.QueryRow(stmt, key, sensor.ID, sensor.Channel, start, end, station, sensor.Serial)
 	var result float64
 	err := row.Scan(&result)
 
 	return result, err
 }
 
-func (postgres postgres_driver) QueryRows(db *sql.DB, start int64, key string, id string) (*sql.Rows, error) {
+func (postgres postgres_driver) QueryRows(db *sql.DB, station string, start int64, end int64, key string, sensor SensorID) (*sql.Rows, error) {
 	stmt := `SELECT cast(extract(epoch from timestamp) as bigint),min,max,avg FROM samples
 		WHERE
 			key = $1 AND
 			id LIKE $2 AND
-			timestamp > to_timestamp($3)
+			(channel = $3 OR $3 < 0) AND
+			timestamp > to_timestamp($4) AND
+			($5 = 0 OR timestamp <= to_timestamp($5)) AND
+			($6 = '*' OR station = $6) AND
+			($7 = '' OR serial = $7)
 		ORDER BY timestamp`
-	return db.Query(stmt, key, id, start)
+	return db.Query(stmt, key, sensor.ID, sensor.Channel, start, end, station, sensor.Serial)
 }
 
-func (postgres postgres_driver) QueryRowsInterval(db *sql.DB, start int64, key string, id string, interval int64) (*sql.Rows, error) {
+func (postgres postgres_driver) QueryRowsInterval(db *sql.DB, station string, start int64, end int64, key string, sensor SensorID, interval int64) (*sql.Rows, error) {
 	stmt := `SELECT
 			CAST(extract(epoch from timestamp)/$1 as bigint) * $2 as ts,
 			MIN(min),
@@ -144,8 +165,27 @@ func (postgres postgres_driver) QueryRowsInterval(db *sql.DB, start int64, key s
 		WHERE
 			key = $3 AND
 			id LIKE $4 AND
-			timestamp > to_timestamp($5)
+			(channel = $5 OR $5 < 0) AND
+			timestamp > to_timestamp($6) AND
+			($7 = 0 OR timestamp <= to_timestamp($7)) AND
+			($8 = '*' OR station = $8) AND
+			($9 = '' OR serial = $9)
 		GROUP BY ts
 		ORDER BY ts`
-	return db.Query(stmt, interval, interval, key, id, start)
+	return db.Query(stmt, interval, interval, key, sensor.ID, sensor.Channel, start, end, station, sensor.Serial)
+}
+
+// Rebind converts ? placeholders into the $n form used by PostgreSQL.
+func (postgres postgres_driver) Rebind(stmt string) string {
+	var result strings.Builder
+	n := 0
+	for _, c := range stmt {
+		if c == '?' {
+			n++
+			result.WriteString("$" + strconv.Itoa(n))
+		} else {
+			result.WriteRune(c)
+		}
+	}
+	return result.String()
 }
@@ -0,0 +1,395 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SensorID is a physical sensor registered under a logical name. An empty
+// Serial matches any serial number. Station is the station receiving the
+// sensor, "" for the default station.
+type SensorID struct {
+	ID      string
+	Channel int
+	Serial  string
+	Station string `json:",omitempty"`
+}
+
+func (id SensorID) String() string {
+	s := fmt.Sprintf("%s/%d", id.ID, id.Channel)
+	if id.Serial != "" {
+		s += "/" + id.Serial
+	}
+	if id.Station != "" {
+		s += "@" + id.Station
+	}
+	return s
+}
+
+// Sensor is a logical sensor, with the physical sensors it is made up of.
+type Sensor struct {
+	Name     string
+	Location string
+	Height   float64
+	Retired  int64
+	IDs      []SensorID
+}
+
+func (database *Database) createRegistryTables() error {
+	if _, err := database.db.Exec(`
+	CREATE TABLE IF NOT EXISTS sensor_names (
+		name        varchar(128) PRIMARY KEY,
+		location    varchar(128),
+		height      double precision,
+		retired     bigint
+	)`); err != nil {
+		return err
+	}
+
+	if _, err := database.db.Exec(`
+	CREATE TABLE IF NOT EXISTS sensor_ids (
+		name        varchar(128),
+		id          varchar(128),
+		channel     integer,
+		serial      varchar(128)
+	)`); err != nil {
+		return err
+	}
+	// Sensors registered before there could be more than one station are
+	// the default station's.
+	return database.addColumn("sensor_ids", "station", "varchar(128) NOT NULL DEFAULT ''")
+}
+
+func (database *Database) exec(stmt string, args ...interface{}) (sql.Result, error) {
+	return database.db.Exec(database.driver.Rebind(stmt), args...)
+}
+
+func (database *Database) query(stmt string, args ...interface{}) (*sql.Rows, error) {
+	return database.db.Query(database.driver.Rebind(stmt), args...)
+}
+
+// Sensors returns every registered logical sensor, sorted by name.
+func (database *Database) Sensors() ([]Sensor, error) {
+	rows, err := database.query(`SELECT name, location, height, retired FROM sensor_names ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	var result []Sensor
+	index := make(map[string]int)
+	for rows.Next() {
+		var s Sensor
+		if err := rows.Scan(&s.Name, &s.Location, &s.Height, &s.Retired); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[s.Name] = len(result)
+		result = append(result, s)
+	}
+	rows.Close()
+
+	rows, err = database.query(`SELECT name, id, channel, serial, station FROM sensor_ids ORDER BY id, channel, serial, station`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var id SensorID
+		if err := rows.Scan(&name, &id.ID, &id.Channel, &id.Serial, &id.Station); err != nil {
+			return nil, err
+		}
+		if i, ok := index[name]; ok {
+			result[i].IDs = append(result[i].IDs, id)
+		}
+	}
+	return result, nil
+}
+
+// PhysicalSensors returns every sensor that has stored samples, at the
+// station of the database.
+func (database *Database) PhysicalSensors() ([]SensorID, error) {
+	rows, err := database.query(`SELECT DISTINCT id, channel, serial, station FROM samples WHERE (? = '*' OR station = ?) ORDER BY id, channel, serial, station`, database.station, database.station)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []SensorID
+	for rows.Next() {
+		var id SensorID
+		if err := rows.Scan(&id.ID, &id.Channel, &id.Serial, &id.Station); err != nil {
+			return nil, err
+		}
+		result = append(result, id)
+	}
+	return result, nil
+}
+
+func (database *Database) sensorExists(name string) (bool, error) {
+	row := database.db.QueryRow(database.driver.Rebind(`SELECT COUNT(1) FROM sensor_names WHERE name = ?`), name)
+	var count int
+	err := row.Scan(&count)
+	return count > 0, err
+}
+
+// SetSensor creates or updates a logical sensor.
+func (database *Database) SetSensor(name string, location string, height float64) error {
+	exists, err := database.sensorExists(name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		_, err = database.exec(`UPDATE sensor_names SET location = ?, height = ? WHERE name = ?`, location, height, name)
+	} else {
+		_, err = database.exec(`INSERT INTO sensor_names (name, location, height, retired) VALUES (?, ?, ?, 0)`, name, location, height)
+	}
+	database.invalidateRegistry()
+	return err
+}
+
+// AliasSensor registers a physical sensor under a logical name, creating the
+// logical sensor if needed. The physical sensor is removed from any other
+// active logical sensor.
+func (database *Database) AliasSensor(name string, id SensorID) error {
+	tx, err := database.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer database.invalidateRegistry()
+	if err := database.aliasSensor(tx, name, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (database *Database) aliasSensor(tx *sql.Tx, name string, id SensorID) error {
+	var count int
+	if err := tx.QueryRow(database.driver.Rebind(`SELECT COUNT(1) FROM sensor_names WHERE name = ?`), name).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		if _, err := tx.Exec(database.driver.Rebind(`INSERT INTO sensor_names (name, location, height, retired) VALUES (?, '', 0, 0)`), name); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(database.driver.Rebind(`
+		DELETE FROM sensor_ids
+		WHERE id = ? AND channel = ? AND serial = ? AND station = ?
+			AND name IN (SELECT name FROM sensor_names WHERE retired = 0)`), id.ID, id.Channel, id.Serial, id.Station); err != nil {
+		return err
+	}
+	_, err := tx.Exec(database.driver.Rebind(`INSERT INTO sensor_ids (name, id, channel, serial, station) VALUES (?, ?, ?, ?, ?)`), name, id.ID, id.Channel, id.Serial, id.Station)
+	return err
+}
+
+func (database *Database) RenameSensor(from string, to string) error {
+	exists, err := database.sensorExists(from)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("unknown sensor %q", from)
+	}
+	if exists, err = database.sensorExists(to); err != nil {
+		return err
+	} else if exists {
+		return fmt.Errorf("sensor %q already exists", to)
+	}
+	if _, err := database.exec(`UPDATE sensor_names SET name = ? WHERE name = ?`, to, from); err != nil {
+		return err
+	}
+	_, err = database.exec(`UPDATE sensor_ids SET name = ? WHERE name = ?`, to, from)
+	database.invalidateRegistry()
+	return err
+}
+
+// MergeSensors moves all physical sensors of one logical sensor into another
+// and removes the first.
+func (database *Database) MergeSensors(from string, into string) error {
+	for _, name := range []string{from, into} {
+		exists, err := database.sensorExists(name)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("unknown sensor %q", name)
+		}
+	}
+	if from == into {
+		return errors.New("cannot merge a sensor into itself")
+	}
+	if _, err := database.exec(`UPDATE sensor_ids SET name = ? WHERE name = ?`, into, from); err != nil {
+		return err
+	}
+	_, err := database.exec(`DELETE FROM sensor_names WHERE name = ?`, from)
+	database.invalidateRegistry()
+	return err
+}
+
+// RetireSensor marks a logical sensor as no longer in service. Its history
+// remains available, but new data is no longer assigned to it.
+func (database *Database) RetireSensor(name string, when time.Time) error {
+	res, err := database.exec(`UPDATE sensor_names SET retired = ? WHERE name = ?`, when.UTC().Unix(), name)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("unknown sensor %q", name)
+	}
+	database.invalidateRegistry()
+	return nil
+}
+
+// registryCache is the cached sensor tables, shared by the registries of
+// every station.
+type registryCache struct {
+	MaxAge time.Duration
+
+	db     *Database
+	mu     sync.Mutex
+	loaded time.Time
+	byName map[string]Sensor
+	byID   map[SensorID][]string
+}
+
+// Registry is a cached view of the sensor tables, used to translate between
+// the physical sensors of a station and logical names. The registry of
+// AllStations matches the physical sensors of every station.
+type Registry struct {
+	*registryCache
+	station string
+}
+
+// Registry returns the sensor registry of the database's station.
+func (database *Database) Registry() *Registry {
+	return &Registry{database.registry, database.station}
+}
+
+func (database *Database) invalidateRegistry() {
+	database.registry.mu.Lock()
+	database.registry.loaded = time.Time{}
+	database.registry.mu.Unlock()
+}
+
+// load refreshes the cache if it is stale. Must be called with r.mu held.
+func (r *registryCache) load() {
+	if r.byName != nil && time.Since(r.loaded) < r.MaxAge {
+		return
+	}
+	sensors, err := r.db.Sensors()
+	if err != nil {
+		return
+	}
+	r.byName = make(map[string]Sensor)
+	r.byID = make(map[SensorID][]string)
+	for _, s := range sensors {
+		r.byName[s.Name] = s
+		for _, id := range s.IDs {
+			r.byID[id] = append(r.byID[id], s.Name)
+		}
+	}
+	r.loaded = time.Now()
+}
+
+// names returns the logical names of a physical sensor of the station.
+// Must be called with r.mu held.
+func (r *Registry) names(id SensorID) []string {
+	if r.station != AllStations {
+		id.Station = r.station
+		return r.byID[id]
+	}
+	var result []string
+	for other, names := range r.byID {
+		if other.ID == id.ID && other.Channel == id.Channel && other.Serial == id.Serial {
+			result = append(result, names...)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Name returns the logical name of a physical sensor of the station,
+// preferring an exact serial number match and active sensors.
+func (r *Registry) Name(key SensorKey) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.load()
+	for _, id := range []SensorID{{ID: key.ID, Channel: key.Channel, Serial: key.Serial}, {ID: key.ID, Channel: key.Channel}} {
+		names := r.names(id)
+		for _, name := range names {
+			if r.byName[name].Retired == 0 {
+				return name, true
+			}
+		}
+		if len(names) > 0 {
+			return names[0], true
+		}
+	}
+	return "", false
+}
+
+// Lookup returns a logical sensor by name.
+func (r *Registry) Lookup(name string) (Sensor, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.load()
+	s, ok := r.byName[name]
+	return s, ok
+}
+
+// Matches reports whether a physical sensor belongs to a logical sensor.
+func (r *Registry) Matches(name string, key SensorKey) bool {
+	s, ok := r.Lookup(name)
+	if !ok {
+		return false
+	}
+	for _, id := range s.IDs {
+		if id.ID == key.ID && id.Channel == key.Channel && (id.Serial == "" || id.Serial == key.Serial) {
+			return true
+		}
+	}
+	return false
+}
+
+// Observe checks a physical sensor of the station against the registry.
+// Oregon Scientific sensors pick a new random serial number when their
+// batteries are changed, so an unknown serial on an ID and channel belonging
+// to exactly one active logical sensor of the station is assigned to that
+// sensor.
+func (r *Registry) Observe(key SensorKey) (string, bool) {
+	if name, ok := r.Name(key); ok {
+		return name, true
+	}
+	if r.station == AllStations {
+		return "", false
+	}
+
+	r.mu.Lock()
+	var candidates []string
+	for id, names := range r.byID {
+		if id.ID != key.ID || id.Channel != key.Channel || id.Station != r.station {
+			continue
+		}
+		for _, name := range names {
+			if r.byName[name].Retired == 0 {
+				candidates = append(candidates, name)
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	sort.Strings(candidates)
+	if len(candidates) == 0 || candidates[0] != candidates[len(candidates)-1] {
+		return "", false
+	}
+	if err := r.db.AliasSensor(candidates[0], SensorID{key.ID, key.Channel, key.Serial, r.station}); err != nil {
+		return "", false
+	}
+	return candidates[0], true
+}
@@ -0,0 +1,121 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package data
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func openTestDatabase(t *testing.T) *Database {
+	viper.Set("dbDriver", "sqlite3")
+	viper.Set("database", filepath.Join(t.TempDir(), "gowx.db"))
+	db, err := OpenDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+	return db
+}
+
+func TestRegistryAlias(t *testing.T) {
+	db := openTestDatabase(t)
+	if err := db.AliasSensor("outdoor", SensorID{"OS3:1D20", 1, "A4", ""}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AliasSensor("indoor", SensorID{"SHT", 0, "", ""}); err != nil {
+		t.Fatal(err)
+	}
+
+	reg := db.Registry()
+	if name, ok := reg.Name(SensorKey{"OS3:1D20", 1, "A4"}); !ok || name != "outdoor" {
+		t.Error("Unexpected name", name)
+	}
+	if name, ok := reg.Name(SensorKey{"SHT", 0, "0"}); !ok || name != "indoor" {
+		t.Error("Empty serial should match any serial", name)
+	}
+	if _, ok := reg.Name(SensorKey{"OS3:1D20", 2, "A4"}); ok {
+		t.Error("Channel should not match")
+	}
+	if !reg.Matches("outdoor", SensorKey{"OS3:1D20", 1, "A4"}) {
+		t.Error("Expected a match")
+	}
+}
+
+func TestRegistrySerialChange(t *testing.T) {
+	db := openTestDatabase(t)
+	db.AliasSensor("outdoor", SensorID{"OS3:1D20", 1, "A4", ""})
+
+	name, ok := db.Registry().Observe(SensorKey{"OS3:1D20", 1, "3B"})
+	if !ok || name != "outdoor" {
+		t.Fatal("New serial should be reassigned", name)
+	}
+	sensors, err := db.Sensors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sensors) != 1 || len(sensors[0].IDs) != 2 {
+		t.Error("Unexpected sensors", sensors)
+	}
+
+	db.RetireSensor("outdoor", time.Now())
+	if _, ok := db.Registry().Observe(SensorKey{"OS3:1D20", 1, "C7"}); ok {
+		t.Error("Retired sensors should not be reassigned")
+	}
+}
+
+func TestRegistryRenameMerge(t *testing.T) {
+	db := openTestDatabase(t)
+	db.AliasSensor("a", SensorID{"OS3:1D20", 1, "A4", ""})
+	db.AliasSensor("b", SensorID{"VN1:6D27", 1, "", ""})
+
+	if err := db.RenameSensor("a", "b"); err == nil {
+		t.Error("Rename onto an existing sensor should fail")
+	}
+	if err := db.RenameSensor("a", "greenhouse"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.MergeSensors("b", "greenhouse"); err != nil {
+		t.Fatal(err)
+	}
+
+	sensors, err := db.Sensors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sensors) != 1 || sensors[0].Name != "greenhouse" || len(sensors[0].IDs) != 2 {
+		t.Error("Unexpected sensors", sensors)
+	}
+}
+
+func TestRegistryStations(t *testing.T) {
+	db := openTestDatabase(t)
+	db.AliasSensor("outdoor", SensorID{"OS3:1D20", 1, "A4", ""})
+	db.AliasSensor("cabin", SensorID{"OS3:1D20", 1, "C7", "cabin"})
+
+	// The same ID and channel received by another station is another
+	// sensor, so a new serial there isn't assigned to the default station's.
+	if name, ok := db.Station("cabin").Registry().Name(SensorKey{"OS3:1D20", 1, "C7"}); !ok || name != "cabin" {
+		t.Error("Unexpected name", name)
+	}
+	if name, ok := db.Registry().Name(SensorKey{"OS3:1D20", 1, "C7"}); ok {
+		t.Error("Sensors of other stations should not match", name)
+	}
+	if name, ok := db.Station("cabin").Registry().Observe(SensorKey{"OS3:1D20", 1, "3B"}); !ok || name != "cabin" {
+		t.Error("New serial should be reassigned within the station", name)
+	}
+	if name, ok := db.Station(AllStations).Registry().Name(SensorKey{"OS3:1D20", 1, "A4"}); !ok || name != "outdoor" {
+		t.Error("Every station should match", name)
+	}
+
+	sensors, err := db.Sensors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sensors) != 2 || len(sensors[0].IDs) != 2 || sensors[0].IDs[1].String() != "OS3:1D20/1/C7@cabin" {
+		t.Error("Unexpected sensors", sensors)
+	}
+}
@@ -19,6 +19,7 @@ func (sqlite sqlite_driver) OpenDatabase(db *sql.DB) error {
 	if _, err := db.Exec(`
 	CREATE TABLE IF NOT EXISTS samples (
 		timestamp   integer,
+		station     text NOT NULL DEFAULT '',
 		id          text,
 		channel     integer,
 		serial      text,
@@ -49,21 +50,22 @@ func (sqlite sqlite_driver) OpenDatabase(db *sql.DB) error {
 func (sqlite sqlite_driver) Close(db *sql.DB) {
 }
 
-func (sqlite sqlite_driver) InsertRow(db *sql.DB, timestamp int64, id string, channel int, serial string, key string, min float64, max float64, avg float64) error {
+func (sqlite sqlite_driver) InsertRow(db *sql.DB, station string, timestamp int64, id string, channel int, serial string, key string, min float64, max float64, avg float64) error {
 	stmt := `INSERT INTO samples (
 		timestamp,
+		station,
 		id,
 		channel,
 		serial,
 		key,
 		min, max, avg
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	_, err := db.Exec(stmt, timestamp, id, channel, serial, key, min, max, avg)
+	_, err := db.Exec(stmt, timestamp, station, id, channel, serial, key, min, max, avg)
 	return err
 }
 
-func (sqlite sqlite_driver) QueryWind(db *sql.DB, start int64, key string, col string, id string, channel int) (*sql.Rows, error) {
+func (sqlite sqlite_driver) QueryWind(db *sql.DB, station string, start int64, end int64, key string, col string, sensor SensorID) (*sql.Rows, error) {
 	var column string
 	switch col {
 	case "avg":
@@ -81,60 +83,77 @@ func (sqlite sqlite_driver) QueryWind(db *sql.DB, start int64, key string, col s
 		` FROM samples dir
 		INNER JOIN samples d
 			ON d.timestamp = dir.timestamp
+			AND d.station = dir.station
 		WHERE dir.key = 'WindDir'
 			AND d.key = ?
 			AND dir.id LIKE ?
 			AND d.id LIKE ?
+			AND (dir.channel = ? OR ? < 0)
+			AND (d.channel = ? OR ? < 0)
 			AND dir.timestamp > ?
+			AND (? = 0 OR dir.timestamp <= ?)
+			AND (? = '*' OR dir.station = ?)
+			AND (? = '' OR dir.serial = ?)
+			AND (? = '' OR d.serial = ?)
 		GROUP BY dir;`
-	return db.Query(stmt, key, id, id, start)
+	return db.Query(stmt, key, sensor.ID, sensor.ID, sensor.Channel, sensor.Channel, sensor.Channel, sensor.Channel, start, end, end, station, station, sensor.Serial, sensor.Serial, sensor.Serial, sensor.Serial)
 }
 
-func (sqlite sqlite_driver) QueryFirst(db *sql.DB, start int64, key string, id string, channel int) (float64, error) {
+func (sqlite sqlite_driver) QueryFirst(db *sql.DB, station string, start int64, end int64, key string, sensor SensorID) (float64, error) {
 	stmt := `SELECT
 		avg FROM samples
 		WHERE
 			key = ? AND
 			id LIKE ? AND
-			channel = ? AND
-			timestamp > ?
+			(channel = ? OR ? < 0) AND
+			timestamp > ? AND
+			(? = 0 OR timestamp <= ?) AND
+			(? = '*' OR station = ?) AND
+			(? = '' OR serial = ?)
 		ORDER BY timestamp
 		LIMIT 1`
-	row := db.QueryRow(stmt, key, id, channel, start)
+	row := db.QueryRow(stmt, key, sensor.ID, sensor.Channel, sensor.Channel, start, end, end, station, station, sensor.Serial, sensor.Serial)
 	var result float64
 	err := row.Scan(&result)
 
 	return result, err
 }
 
-func (sqlite sqlite_driver) QueryLast(db *sql.DB, start int64, key string, id string, channel int) (float64, error) {
+func (sqlite sqlite_driver) QueryLast(db *sql.DB, station string, start int64, end int64, key string, sensor SensorID) (float64, error) {
 	stmt := `SELECT
 		avg FROM samples
 		WHERE
 			key = ? AND
 			id LIKE ? AND
-			channel = ? AND
-			timestamp > ?
+			(channel = ? OR ? < 0) AND
+			timestamp > ? AND
+			(? = 0 OR timestamp <= ?) AND
+			(? = '*' OR station = ?) AND
+			(? = '' OR serial = ?)
 		ORDER BY timestamp DESC
 		LIMIT 1`
-	row := db.QueryRow(stmt, key, id, channel, start)
+	row := db.QueryRow(stmt, key, sensor.ID, sensor.Channel, sensor.Channel, start, end, end, station, station, sensor.Serial, sensor.Serial)
 	var result float64
 	err := row.Scan(&result)
 
 	return result, err
 }
 
-func (sqlite sqlite_driver) QueryRows(db *sql.DB, start int64, key string, id string) (*sql.Rows, error) {
+func (sqlite sqlite_driver) QueryRows(db *sql.DB, station string, start int64, end int64, key string, sensor SensorID) (*sql.Rows, error) {
 	stmt := `SELECT timestamp,min,max,avg FROM samples
 		WHERE
 			key = ? AND
 			id LIKE ? AND
-			timestamp > ?
+			(channel = ? OR ? < 0) AND
+			timestamp > ? AND
+			(? = 0 OR timestamp <= ?) AND
+			(? = '*' OR station = ?) AND
+			(? = '' OR serial = ?)
 		ORDER BY timestamp`
-	return db.Query(stmt, key, id, start)
+	return db.Query(stmt, key, sensor.ID, sensor.Channel, sensor.Channel, start, end, end, station, station, sensor.Serial, sensor.Serial)
 }
 
-func (sqlite sqlite_driver) QueryRowsInterval(db *sql.DB, start int64, key string, id string, interval int64) (*sql.Rows, error) {
+func (sqlite sqlite_driver) QueryRowsInterval(db *sql.DB, station string, start int64, end int64, key string, sensor SensorID, interval int64) (*sql.Rows, error) {
 	stmt := `SELECT
 			CAST(timestamp/? as INTEGER) * ? as ts,
 			MIN(min),
@@ -144,8 +163,16 @@ func (sqlite sqlite_driver) QueryRowsInterval(db *sql.DB, start int64, key strin
 		WHERE
 			key = ? AND
 			id LIKE ? AND
-			timestamp > ?
+			(channel = ? OR ? < 0) AND
+			timestamp > ? AND
+			(? = 0 OR timestamp <= ?) AND
+			(? = '*' OR station = ?) AND
+			(? = '' OR serial = ?)
 		GROUP BY ts
 		ORDER BY ts`
-	return db.Query(stmt, interval, interval, key, id, start)
+	return db.Query(stmt, interval, interval, key, sensor.ID, sensor.Channel, sensor.Channel, start, end, end, station, station, sensor.Serial, sensor.Serial)
+}
+
+func (sqlite sqlite_driver) Rebind(stmt string) string {
+	return stmt
 }
@@ -0,0 +1,218 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+// Package health follows how often each sensor reports, to tell when one
+// has gone quiet and to find the gaps in its data.
+package health
+
+import (
+	"sort"
+	"time"
+
+	"github.com/geoffholden/gowx/data"
+)
+
+// The states of a sensor.
+const (
+	OK       = "ok"
+	Stale    = "stale"
+	Learning = "learning" // the expected interval isn't known yet
+)
+
+// learnSamples is the number of intervals the expected interval is learned
+// from.
+const learnSamples = 20
+
+// stationSensor is a physical sensor of a station.
+type stationSensor struct {
+	station string
+	key     data.SensorKey
+}
+
+type sensor struct {
+	station  string
+	key      data.SensorKey
+	lastSeen time.Time
+	learned  time.Duration
+	recent   []time.Duration
+	stale    bool
+	exists   bool
+	dirty    bool
+}
+
+// Status is the health of a sensor of a station.
+type Status struct {
+	Station string `json:",omitempty"`
+	data.SensorKey
+	Name     string `json:",omitempty"`
+	State    string
+	LastSeen time.Time
+	// Expected is the expected time between samples in seconds, zero while
+	// learning.
+	Expected float64
+}
+
+// Monitor follows the sensors. A sensor is stale once nothing has been
+// heard from it for Factor times its expected interval, and the time it
+// was stale is a gap. It isn't safe for concurrent use.
+type Monitor struct {
+	Factor float64
+	// MinStale is the shortest time a sensor has to be quiet to be stale,
+	// allowing for sensors that report very often.
+	MinStale time.Duration
+	// Configured returns the configured interval of a sensor, or zero to
+	// learn it from the samples.
+	Configured func(key data.SensorKey) time.Duration
+
+	sensors map[stationSensor]*sensor
+	checked bool
+}
+
+// NewMonitor creates a monitor from the last-seen table.
+func NewMonitor(statuses []data.SensorStatus) *Monitor {
+	m := &Monitor{
+		Factor:     3,
+		MinStale:   2 * time.Minute,
+		Configured: func(data.SensorKey) time.Duration { return 0 },
+		sensors:    make(map[stationSensor]*sensor),
+	}
+	for _, s := range statuses {
+		m.sensors[stationSensor{s.Station, s.SensorKey}] = &sensor{
+			station:  s.Station,
+			key:      s.SensorKey,
+			lastSeen: time.Unix(s.LastSeen, 0),
+			learned:  time.Duration(s.Interval * float64(time.Second)),
+			exists:   true,
+		}
+	}
+	return m
+}
+
+func (m *Monitor) expected(s *sensor) time.Duration {
+	if d := m.Configured(s.key); d > 0 {
+		return d
+	}
+	return s.learned
+}
+
+// threshold returns how long a sensor can be quiet before it's stale, zero
+// if that isn't known.
+func (m *Monitor) threshold(s *sensor) time.Duration {
+	expected := m.expected(s)
+	if expected <= 0 {
+		return 0
+	}
+	d := time.Duration(m.Factor * float64(expected))
+	if d < m.MinStale {
+		d = m.MinStale
+	}
+	return d
+}
+
+func (m *Monitor) learn(s *sensor, d time.Duration) {
+	s.recent = append(s.recent, d)
+	if len(s.recent) > learnSamples {
+		s.recent = s.recent[1:]
+	}
+	if len(s.recent) < 5 {
+		return
+	}
+	sorted := append([]time.Duration(nil), s.recent...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	s.learned = sorted[len(sorted)/2].Round(time.Second)
+}
+
+// Observe records a sample from a sensor of a station. It returns the gap
+// the sample ends, if any, and whether the sensor was stale.
+func (m *Monitor) Observe(station string, key data.SensorKey, t time.Time) (*data.Gap, bool) {
+	s := m.sensors[stationSensor{station, key}]
+	if s == nil {
+		s = &sensor{station: station, key: key}
+		m.sensors[stationSensor{station, key}] = s
+	}
+	if !t.After(s.lastSeen) {
+		return nil, false
+	}
+
+	var gap *data.Gap
+	if !s.lastSeen.IsZero() {
+		d := t.Sub(s.lastSeen)
+		if threshold := m.threshold(s); threshold > 0 && d > threshold {
+			gap = &data.Gap{Station: station, SensorKey: key, Start: s.lastSeen.Unix(), End: t.Unix()}
+		} else if d >= time.Second {
+			// Some sensors repeat every transmission, which doesn't count.
+			m.learn(s, d)
+		}
+	}
+	wasStale := s.stale
+	s.lastSeen, s.stale, s.dirty = t, false, true
+	return gap, wasStale
+}
+
+// Check returns the statuses of the sensors that have gone stale since the
+// last check. Sensors that were already stale when the monitor was created
+// aren't returned.
+func (m *Monitor) Check(now time.Time) []Status {
+	var result []Status
+	for _, s := range m.sensors {
+		threshold := m.threshold(s)
+		if s.stale || threshold == 0 || now.Sub(s.lastSeen) <= threshold {
+			continue
+		}
+		s.stale = true
+		if m.checked || !s.exists || s.dirty {
+			result = append(result, m.status(s, now))
+		}
+	}
+	m.checked = true
+	sort.Slice(result, func(i, j int) bool { return less(result[i], result[j]) })
+	return result
+}
+
+func less(a, b Status) bool {
+	if a.Station != b.Station {
+		return a.Station < b.Station
+	}
+	if a.ID != b.ID {
+		return a.ID < b.ID
+	}
+	if a.Channel != b.Channel {
+		return a.Channel < b.Channel
+	}
+	return a.Serial < b.Serial
+}
+
+// Save saves the entries that changed since the last save.
+func (m *Monitor) Save(save func(s data.SensorStatus, exists bool) error) error {
+	for _, s := range m.sensors {
+		if !s.dirty {
+			continue
+		}
+		status := data.SensorStatus{Station: s.station, SensorKey: s.key, LastSeen: s.lastSeen.Unix(), Interval: s.learned.Seconds()}
+		if err := save(status, s.exists); err != nil {
+			return err
+		}
+		s.exists, s.dirty = true, false
+	}
+	return nil
+}
+
+// Statuses returns the health of every sensor, sorted by station, ID,
+// channel and serial.
+func (m *Monitor) Statuses(now time.Time) []Status {
+	result := make([]Status, 0, len(m.sensors))
+	for _, s := range m.sensors {
+		result = append(result, m.status(s, now))
+	}
+	sort.Slice(result, func(i, j int) bool { return less(result[i], result[j]) })
+	return result
+}
+
+func (m *Monitor) status(s *sensor, now time.Time) Status {
+	status := Status{Station: s.station, SensorKey: s.key, State: OK, LastSeen: s.lastSeen, Expected: m.expected(s).Seconds()}
+	if threshold := m.threshold(s); threshold == 0 {
+		status.State = Learning
+	} else if now.Sub(s.lastSeen) > threshold {
+		status.State = Stale
+	}
+	return status
+}
@@ -0,0 +1,125 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package health
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geoffholden/gowx/data"
+)
+
+var (
+	outdoor = data.SensorKey{ID: "OS3:1D20", Channel: 1, Serial: "A4"}
+	wind    = data.SensorKey{ID: "VN1:6D27", Channel: 1, Serial: "00"}
+)
+
+var start = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+func at(seconds int) time.Time {
+	return start.Add(time.Duration(seconds) * time.Second)
+}
+
+func TestLearning(t *testing.T) {
+	m := NewMonitor(nil)
+	for i := 0; i <= 6; i++ {
+		// Every transmission is repeated.
+		m.Observe("", outdoor, at(i*40))
+		m.Observe("", outdoor, at(i*40).Add(100*time.Millisecond))
+	}
+	statuses := m.Statuses(at(240))
+	if len(statuses) != 1 || statuses[0].State != OK || statuses[0].Expected != 40 {
+		t.Fatal("Unexpected statuses", statuses)
+	}
+
+	// Three missed transmissions make the sensor stale.
+	if keys := m.Check(at(360)); len(keys) != 0 {
+		t.Error("Sensor shouldn't be stale yet", keys)
+	}
+	if keys := m.Check(at(361)); len(keys) != 1 || keys[0].SensorKey != outdoor {
+		t.Error("Sensor should be stale", keys)
+	}
+	if keys := m.Check(at(400)); len(keys) != 0 {
+		t.Error("Sensors only go stale once", keys)
+	}
+	if statuses := m.Statuses(at(400)); statuses[0].State != Stale {
+		t.Error("Unexpected state", statuses[0])
+	}
+
+	gap, back := m.Observe("", outdoor, at(600))
+	if !back || gap == nil || *gap != (data.Gap{SensorKey: outdoor, Start: at(240).Unix(), End: at(600).Unix()}) {
+		t.Error("Unexpected gap", gap, back)
+	}
+	if statuses := m.Statuses(at(601)); statuses[0].State != OK || statuses[0].Expected != 40 {
+		t.Error("Gaps shouldn't count as intervals", statuses[0])
+	}
+}
+
+func TestConfigured(t *testing.T) {
+	m := NewMonitor(nil)
+	m.Configured = func(key data.SensorKey) time.Duration {
+		if key == wind {
+			return 10 * time.Second
+		}
+		return 0
+	}
+	m.Observe("", wind, at(0))
+	m.Observe("", outdoor, at(0))
+	statuses := m.Statuses(at(60))
+	if statuses[0].State != Learning || statuses[1].State != OK || statuses[1].Expected != 10 {
+		t.Error("Unexpected statuses", statuses)
+	}
+	// The minimum applies to sensors reporting often.
+	if keys := m.Check(at(121)); len(keys) != 1 || keys[0].SensorKey != wind {
+		t.Error("Expected the wind sensor to be stale", keys)
+	}
+}
+
+func TestStations(t *testing.T) {
+	m := NewMonitor([]data.SensorStatus{{Station: "cottage", SensorKey: outdoor, LastSeen: at(0).Unix(), Interval: 40}})
+	// The same sensor at another station is a sensor of its own.
+	if gap, _ := m.Observe("", outdoor, at(1000)); gap != nil {
+		t.Error("Unexpected gap", gap)
+	}
+	gap, _ := m.Observe("cottage", outdoor, at(1000))
+	if gap == nil || gap.Station != "cottage" || gap.Start != at(0).Unix() {
+		t.Error("Expected a gap at the cottage", gap)
+	}
+	if statuses := m.Statuses(at(1000)); len(statuses) != 2 || statuses[0].Station != "" || statuses[1].Station != "cottage" {
+		t.Error("Unexpected statuses", statuses)
+	}
+}
+
+func TestSave(t *testing.T) {
+	saved := make(map[data.SensorKey]data.SensorStatus)
+	save := func(s data.SensorStatus, exists bool) error {
+		if _, ok := saved[s.SensorKey]; ok != exists {
+			t.Error("Unexpected exists", s, exists)
+		}
+		saved[s.SensorKey] = s
+		return nil
+	}
+
+	m := NewMonitor([]data.SensorStatus{{SensorKey: wind, LastSeen: at(0).Unix(), Interval: 20}})
+	saved[wind] = data.SensorStatus{}
+	m.Observe("", outdoor, at(1000))
+	if err := m.Save(save); err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 2 || saved[outdoor].LastSeen != at(1000).Unix() || saved[wind] != (data.SensorStatus{}) {
+		t.Error("Unexpected save", saved)
+	}
+
+	// The wind sensor was stale before the monitor started, so it isn't
+	// reported.
+	if keys := m.Check(at(1000)); len(keys) != 0 {
+		t.Error("Unexpected stale sensors", keys)
+	}
+	if gap, back := m.Observe("", wind, at(1010)); !back || gap == nil || gap.Start != at(0).Unix() {
+		t.Error("Expected a gap", gap, back)
+	}
+	m.Save(save)
+	if saved[wind].LastSeen != at(1010).Unix() || saved[wind].Interval != 20 {
+		t.Error("Unexpected save", saved[wind])
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+// Package homeassistant publishes Home Assistant MQTT discovery configs, so
+// the sensors show up in Home Assistant without writing any YAML.
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/units"
+)
+
+// Device groups the entities of one physical sensor.
+type Device struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model,omitempty"`
+}
+
+// Config is the discovery config of one sensor key.
+type Config struct {
+	Name              string `json:"name"`
+	UniqueID          string `json:"unique_id"`
+	StateTopic        string `json:"state_topic"`
+	AvailabilityTopic string `json:"availability_topic,omitempty"`
+	DeviceClass       string `json:"device_class,omitempty"`
+	StateClass        string `json:"state_class,omitempty"`
+	UnitOfMeasurement string `json:"unit_of_measurement,omitempty"`
+	ValueTemplate     string `json:"value_template,omitempty"`
+	Device            Device `json:"device"`
+}
+
+type kind struct {
+	deviceClass string
+	stateClass  string
+	unitKey     string // key of the "units" setting
+	unit        string // unit of the published values
+	convert     func(value float64, unit string) (float64, error)
+}
+
+var kinds = map[string]kind{
+	"Temperature": {"temperature", "measurement", "temperature", "C", func(v float64, unit string) (float64, error) {
+		u := units.NewTemperatureCelsius(v)
+		return u.Get(unit)
+	}},
+	"Humidity": {"humidity", "measurement", "", "%", nil},
+	"Pressure": {"atmospheric_pressure", "measurement", "pressure", "hPa", func(v float64, unit string) (float64, error) {
+		u := units.NewPressureHectopascal(v)
+		return u.Get(unit)
+	}},
+	"AverageWind": {"wind_speed", "measurement", "windspeed", "m/s", speed},
+	"CurrentWind": {"wind_speed", "measurement", "windspeed", "m/s", speed},
+	"WindDir":     {"", "measurement", "", "°", nil},
+	"RainTotal": {"precipitation", "total_increasing", "rain", "mm", func(v float64, unit string) (float64, error) {
+		u := units.NewDistanceMillimeters(v)
+		return u.Get(unit)
+	}},
+	"RainRate": {"precipitation_intensity", "measurement", "rain", "mm/h", func(v float64, unit string) (float64, error) {
+		u := units.NewDistanceMillimeters(v)
+		return u.Get(strings.TrimSuffix(unit, "/h"))
+	}},
+	"UV": {"", "measurement", "", "UV index", nil},
+}
+
+func speed(v float64, unit string) (float64, error) {
+	u := units.NewSpeedMetersPerSecond(v)
+	return u.Get(unit)
+}
+
+// unitLabel returns the unit as Home Assistant spells it.
+func unitLabel(key string, unit string) string {
+	switch strings.ToLower(unit) {
+	case "c", "celsius":
+		return "°C"
+	case "f", "fahrenheit":
+		return "°F"
+	case "k", "kelvin":
+		return "K"
+	case "knots", "kts":
+		return "kn"
+	}
+	if key == "RainRate" && !strings.HasSuffix(unit, "/h") {
+		return unit + "/h"
+	}
+	return unit
+}
+
+var invalidID = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// Publisher publishes MQTT messages, e.g. a bus.Bus.
+type Publisher interface {
+	Publish(topic string, payload []byte, retained bool) error
+}
+
+// Discovery publishes the discovery config of every sensor key it observes,
+// and publishes it again whenever it changes.
+type Discovery struct {
+	// Prefix is Home Assistant's discovery prefix.
+	Prefix string
+	// NodeID separates the entities of gowx from other publishers. The
+	// station of each sensor is added to it.
+	NodeID string
+	// StateTopic returns the topic carrying the values of a key.
+	StateTopic        func(station string, sensor data.SensorKey, key string) string
+	AvailabilityTopic string
+	// Units are the display units, keyed like the "units" setting.
+	Units map[string]string
+
+	publisher Publisher
+	mu        sync.Mutex
+	configs   map[string][]byte
+	order     []string
+}
+
+func New(publisher Publisher, stateTopic func(station string, sensor data.SensorKey, key string) string) *Discovery {
+	return &Discovery{
+		Prefix:     "homeassistant",
+		NodeID:     "gowx",
+		StateTopic: stateTopic,
+		Units:      make(map[string]string),
+		publisher:  publisher,
+		configs:    make(map[string][]byte),
+	}
+}
+
+// StatusTopic is where Home Assistant announces that it has started.
+func (d *Discovery) StatusTopic() string {
+	return d.Prefix + "/status"
+}
+
+// Config returns the discovery topic and config of a sensor key of a
+// station, "" for the default station. The name is the sensor's logical
+// name, or empty if it isn't registered.
+func (d *Discovery) Config(station string, sensor data.SensorKey, name string, key string) (string, Config) {
+	nodeID := d.NodeID
+	if station != "" {
+		nodeID += "_" + station
+	}
+	nodeID = invalidID.ReplaceAllString(nodeID, "_")
+	deviceID := invalidID.ReplaceAllString(fmt.Sprintf("%s_%s_%d", nodeID, sensor.ID, sensor.Channel), "_")
+	objectID := invalidID.ReplaceAllString(fmt.Sprintf("%s_%d_%s", sensor.ID, sensor.Channel, key), "_")
+
+	device := Device{
+		Identifiers:  []string{deviceID},
+		Name:         name,
+		Manufacturer: "gowx",
+		Model:        strings.SplitN(sensor.ID, ":", 2)[0],
+	}
+	if device.Name == "" {
+		device.Name = fmt.Sprintf("%s channel %d", sensor.ID, sensor.Channel)
+	}
+
+	config := Config{
+		Name:              humanize(key),
+		UniqueID:          nodeID + "_" + objectID,
+		StateTopic:        d.StateTopic(station, sensor, key),
+		AvailabilityTopic: d.AvailabilityTopic,
+		Device:            device,
+	}
+	if k, ok := kinds[key]; ok {
+		config.DeviceClass = k.deviceClass
+		config.StateClass = k.stateClass
+		config.UnitOfMeasurement = unitLabel(key, k.unit)
+		if unit, ok := d.Units[k.unitKey]; ok && k.convert != nil {
+			zero, err0 := k.convert(0, unit)
+			one, err1 := k.convert(1, unit)
+			if err0 == nil && err1 == nil {
+				config.UnitOfMeasurement = unitLabel(key, unit)
+				if zero != 0 || one != 1 {
+					config.ValueTemplate = "{{ (value | float * " + formatFloat(one-zero) + " + " + formatFloat(zero) + ") | round(2) }}"
+				}
+			}
+		}
+	} else {
+		config.StateClass = "measurement"
+	}
+	return d.Prefix + "/sensor/" + nodeID + "/" + objectID + "/config", config
+}
+
+// Observe publishes the config of a sensor key of a station if it hasn't
+// been published yet or has changed.
+func (d *Discovery) Observe(station string, sensor data.SensorKey, name string, key string) error {
+	topic, config := d.Config(station, sensor, name, key)
+	payload, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	previous, ok := d.configs[topic]
+	if ok && string(previous) == string(payload) {
+		d.mu.Unlock()
+		return nil
+	}
+	if !ok {
+		d.order = append(d.order, topic)
+	}
+	d.configs[topic] = payload
+	d.mu.Unlock()
+
+	return d.publisher.Publish(topic, payload, true)
+}
+
+// Republish publishes every config again, e.g. after Home Assistant has
+// restarted.
+func (d *Discovery) Republish() error {
+	d.mu.Lock()
+	topics := append([]string(nil), d.order...)
+	payloads := make([][]byte, len(topics))
+	for i, topic := range topics {
+		payloads[i] = d.configs[topic]
+	}
+	d.mu.Unlock()
+
+	var firstErr error
+	for i, topic := range topics {
+		if err := d.publisher.Publish(topic, payloads[i], true); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', 10, 64)
+}
+
+// humanize splits a key such as "AverageWind" into words.
+func humanize(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		if i > 0 && r >= 'A' && r <= 'Z' && key[i-1] >= 'a' && key[i-1] <= 'z' {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
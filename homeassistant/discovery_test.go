@@ -0,0 +1,142 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package homeassistant
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/geoffholden/gowx/bus"
+	"github.com/geoffholden/gowx/data"
+)
+
+var outdoor = data.SensorKey{ID: "OS3:1D20", Channel: 1, Serial: "A4"}
+
+func stateTopic(station string, sensor data.SensorKey, key string) string {
+	if station != "" {
+		station += "/"
+	}
+	return "gowx/" + station + sensor.ID + "/1/" + key + "/aggregated"
+}
+
+// recorder records what is published to a local bus.
+type recorder struct {
+	*bus.Local
+	messages []bus.Message
+}
+
+func (r *recorder) Publish(topic string, payload []byte, retained bool) error {
+	r.messages = append(r.messages, bus.Message{Topic: topic, Payload: payload, Retained: retained})
+	return r.Local.Publish(topic, payload, retained)
+}
+
+func newTestDiscovery(t *testing.T) (*Discovery, *recorder) {
+	r := &recorder{Local: bus.NewLocal()}
+	t.Cleanup(r.Close)
+	d := New(r, stateTopic)
+	d.AvailabilityTopic = "gowx/status/aggregator"
+	return d, r
+}
+
+func retainedConfig(t *testing.T, r *recorder, topic string) Config {
+	m, ok := r.Retained(topic)
+	if !ok {
+		t.Fatal("No retained config on", topic)
+	}
+	var config Config
+	if err := json.Unmarshal(m.Payload, &config); err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func TestDiscoveryPublish(t *testing.T) {
+	d, r := newTestDiscovery(t)
+	d.Units["temperature"] = "F"
+
+	if err := d.Observe("", outdoor, "", "Temperature"); err != nil {
+		t.Fatal(err)
+	}
+	topic := "homeassistant/sensor/gowx/OS3_1D20_1_Temperature/config"
+	config := retainedConfig(t, r, topic)
+	if config.UniqueID != "gowx_OS3_1D20_1_Temperature" || config.Name != "Temperature" {
+		t.Error("Unexpected ids", config)
+	}
+	if config.StateTopic != "gowx/OS3:1D20/1/Temperature/aggregated" || config.AvailabilityTopic != "gowx/status/aggregator" {
+		t.Error("Unexpected topics", config)
+	}
+	if config.DeviceClass != "temperature" || config.UnitOfMeasurement != "°F" {
+		t.Error("Unexpected class or unit", config)
+	}
+	if config.ValueTemplate != "{{ (value | float * 1.8 + 32) | round(2) }}" {
+		t.Error("Unexpected template", config.ValueTemplate)
+	}
+	if config.Device.Name != "OS3:1D20 channel 1" || config.Device.Model != "OS3" {
+		t.Error("Unexpected device", config.Device)
+	}
+
+	// Unchanged configs aren't published again, but a new name is.
+	d.Observe("", outdoor, "", "Temperature")
+	d.Observe("", outdoor, "outdoor", "Temperature")
+	if n := len(r.messages); n != 2 {
+		t.Error("Expected 2 messages, got", n)
+	}
+	if config := retainedConfig(t, r, topic); config.Device.Name != "outdoor" {
+		t.Error("Device name not updated", config.Device)
+	}
+}
+
+func TestDiscoveryStations(t *testing.T) {
+	d, r := newTestDiscovery(t)
+	d.Observe("", outdoor, "", "Temperature")
+	d.Observe("cabin", outdoor, "", "Temperature")
+
+	// The same sensor received by another station is a separate device.
+	config := retainedConfig(t, r, "homeassistant/sensor/gowx_cabin/OS3_1D20_1_Temperature/config")
+	if config.UniqueID != "gowx_cabin_OS3_1D20_1_Temperature" || config.StateTopic != "gowx/cabin/OS3:1D20/1/Temperature/aggregated" {
+		t.Error("Unexpected config", config)
+	}
+	if len(config.Device.Identifiers) != 1 || config.Device.Identifiers[0] != "gowx_cabin_OS3_1D20_1" {
+		t.Error("Unexpected device", config.Device)
+	}
+	if config := retainedConfig(t, r, "homeassistant/sensor/gowx/OS3_1D20_1_Temperature/config"); config.UniqueID != "gowx_OS3_1D20_1_Temperature" {
+		t.Error("Unexpected config", config)
+	}
+}
+
+func TestDiscoveryKinds(t *testing.T) {
+	d := New(nil, stateTopic)
+	d.Units["rain"] = "in"
+	d.Units["windspeed"] = "m/s"
+
+	if _, c := d.Config("", outdoor, "", "RainRate"); c.UnitOfMeasurement != "in/h" || c.DeviceClass != "precipitation_intensity" || c.ValueTemplate == "" {
+		t.Error("Unexpected rain rate", c)
+	}
+	if _, c := d.Config("", outdoor, "", "AverageWind"); c.Name != "Average Wind" || c.UnitOfMeasurement != "m/s" || c.ValueTemplate != "" {
+		t.Error("Unexpected wind", c)
+	}
+	if _, c := d.Config("", outdoor, "", "RainTotal"); c.StateClass != "total_increasing" || c.UnitOfMeasurement != "in" {
+		t.Error("Unexpected rain total", c)
+	}
+	if _, c := d.Config("", outdoor, "", "Battery"); c.DeviceClass != "" || c.UnitOfMeasurement != "" {
+		t.Error("Unknown keys should have no class or unit", c)
+	}
+}
+
+func TestDiscoveryRepublish(t *testing.T) {
+	d, r := newTestDiscovery(t)
+	d.Observe("", outdoor, "", "Temperature")
+	d.Observe("", outdoor, "", "Humidity")
+	if err := d.Republish(); err != nil {
+		t.Fatal(err)
+	}
+	messages := r.messages
+	if len(messages) != 4 || messages[2].Topic != messages[0].Topic || messages[3].Topic != messages[1].Topic {
+		t.Error("Unexpected messages", messages)
+	}
+	for _, m := range messages {
+		if !m.Retained {
+			t.Error("Configs should be retained", m.Topic)
+		}
+	}
+}
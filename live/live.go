@@ -0,0 +1,169 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+// Package live streams events to web browsers as Server-Sent Events.
+package live
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Event is an event sent to the clients, with its data sent as JSON.
+type Event struct {
+	Name string
+	Data interface{}
+}
+
+// Filter decides what a client receives, returning the events to send for
+// a published event, none to skip it. Filters run outside the hub's lock,
+// and may run concurrently when events are published concurrently.
+type Filter func(e Event) []Event
+
+// Client is a subscriber of a hub.
+type Client struct {
+	events chan Event
+	filter Filter
+}
+
+// Events returns the events for the client. It's closed once the client is
+// unsubscribed or dropped for falling behind.
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// Hub sends the published events to its clients. A client that falls more
+// than Buffer events behind is dropped rather than holding up the others; a
+// browser reconnects on its own.
+type Hub struct {
+	Buffer int
+	// Heartbeat is how often a comment is sent to idle clients, which keeps
+	// proxies from closing the connection.
+	Heartbeat time.Duration
+	// OnDrop is called when a client is dropped.
+	OnDrop func()
+
+	mu      sync.Mutex
+	clients map[*Client]bool
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		Buffer:    64,
+		Heartbeat: 30 * time.Second,
+		clients:   make(map[*Client]bool),
+	}
+}
+
+// Subscribe adds a client receiving the events filter returns, or every
+// event if filter is nil.
+func (h *Hub) Subscribe(filter Filter) *Client {
+	c := &Client{events: make(chan Event, h.Buffer), filter: filter}
+	h.mu.Lock()
+	h.clients[c] = true
+	h.mu.Unlock()
+	return c
+}
+
+// Unsubscribe removes a client.
+func (h *Hub) Unsubscribe(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(c)
+}
+
+func (h *Hub) remove(c *Client) {
+	if h.clients[c] {
+		delete(h.clients, c)
+		close(c.events)
+	}
+}
+
+// Clients returns the number of clients.
+func (h *Hub) Clients() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// Publish sends an event to the clients. It never blocks on a client.
+func (h *Hub) Publish(e Event) {
+	h.mu.Lock()
+	clients := make([]*Client, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.mu.Unlock()
+
+	for _, c := range clients {
+		events := []Event{e}
+		if c.filter != nil {
+			events = c.filter(e)
+		}
+		if len(events) > 0 {
+			h.send(c, events)
+		}
+	}
+}
+
+// send queues events for a client that is still subscribed, dropping it if
+// it has fallen behind.
+func (h *Hub) send(c *Client, events []Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.clients[c] {
+		return
+	}
+	for _, e := range events {
+		select {
+		case c.events <- e:
+		default:
+			h.remove(c)
+			if h.OnDrop != nil {
+				h.OnDrop()
+			}
+			return
+		}
+	}
+}
+
+// Serve streams the events filter returns to the client of the request
+// until it disconnects.
+func (h *Hub) Serve(w http.ResponseWriter, r *http.Request, filter Filter) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming isn't supported", http.StatusInternalServerError)
+		return
+	}
+	c := h.Subscribe(filter)
+	defer h.Unsubscribe(c)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	fmt.Fprint(w, "retry: 5000\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-c.Events():
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(e.Data)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Name, data)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package live
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServe(t *testing.T) {
+	h := NewHub()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.Serve(w, r, func(e Event) []Event {
+			if e.Name != "sample" {
+				return nil
+			}
+			return []Event{e}
+		})
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Error("Unexpected content type", ct)
+	}
+	r := bufio.NewReader(resp.Body)
+	if line, _ := r.ReadString('\n'); line != "retry: 5000\n" {
+		t.Errorf("Unexpected line %q", line)
+	}
+	r.ReadString('\n')
+
+	if h.Clients() != 1 {
+		t.Fatal("Expected a client", h.Clients())
+	}
+	h.Publish(Event{"aggregate", 1})
+	h.Publish(Event{"sample", map[string]float64{"Temperature": 21.5}})
+	var lines []string
+	for len(lines) < 3 {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if s := strings.Join(lines, ""); s != "event: sample\ndata: {\"Temperature\":21.5}\n\n" {
+		t.Errorf("Unexpected event %q", s)
+	}
+
+	resp.Body.Close()
+	for i := 0; h.Clients() != 0 && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if h.Clients() != 0 {
+		t.Error("The client should be gone")
+	}
+}
+
+func TestSlowClient(t *testing.T) {
+	h := NewHub()
+	h.Buffer = 2
+	dropped := 0
+	h.OnDrop = func() { dropped++ }
+	slow := h.Subscribe(nil)
+	fast := h.Subscribe(nil)
+	for i := 0; i < 3; i++ {
+		h.Publish(Event{"sample", i})
+		<-fast.Events()
+	}
+	if dropped != 1 || h.Clients() != 1 {
+		t.Error("The slow client should be dropped", dropped, h.Clients())
+	}
+	n := 0
+	for range slow.Events() {
+		n++
+	}
+	if n != 2 {
+		t.Error("Unexpected events", n)
+	}
+	h.Unsubscribe(slow)
+}
+
+func TestFilterOutsideLock(t *testing.T) {
+	h := NewHub()
+	var c *Client
+	c = h.Subscribe(func(e Event) []Event {
+		// A filter may use the hub, such as to unsubscribe its client.
+		h.Unsubscribe(c)
+		return []Event{e}
+	})
+	h.Publish(Event{"sample", 1})
+	if _, ok := <-c.Events(); ok || h.Clients() != 0 {
+		t.Error("The client should be unsubscribed without an event")
+	}
+}
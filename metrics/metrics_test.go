@@ -0,0 +1,77 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func collect(c collector) string {
+	buf := new(bytes.Buffer)
+	c.collect(buf)
+	return buf.String()
+}
+
+func TestCounter(t *testing.T) {
+	c := NewCounter("test_requests_total", "Requests.", "path")
+	if collect(c) != "" {
+		t.Error("Empty counter should not be written")
+	}
+	c.Inc("/a")
+	c.Add(2, "/b")
+	c.Inc("/a")
+
+	expected := `# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{path="/a"} 2
+test_requests_total{path="/b"} 2
+`
+	if out := collect(c); out != expected {
+		t.Error("Unexpected output", out)
+	}
+}
+
+func TestGauge(t *testing.T) {
+	g := NewGauge("test_connected", "Connected.")
+	g.Set(1)
+	g.Set(0)
+	if out := collect(g); !strings.HasSuffix(out, "test_connected 0\n") {
+		t.Error("Unexpected output", out)
+	}
+}
+
+func TestGaugeFunc(t *testing.T) {
+	g := NewGaugeFunc("test_value", "Value.", []string{"key"}, func(emit func(float64, ...string)) {
+		emit(1.5, "b")
+		emit(-2, `a"`)
+	})
+	expected := `# HELP test_value Value.
+# TYPE test_value gauge
+test_value{key="a\""} -2
+test_value{key="b"} 1.5
+`
+	if out := collect(g); out != expected {
+		t.Error("Unexpected output", out)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram("test_duration_seconds", "Duration.", []float64{0.1, 1}, "query")
+	h.Observe(0.05, "rows")
+	h.Observe(0.5, "rows")
+	h.Observe(5, "rows")
+
+	expected := `# HELP test_duration_seconds Duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{query="rows",le="0.1"} 1
+test_duration_seconds_bucket{query="rows",le="1"} 2
+test_duration_seconds_bucket{query="rows",le="+Inf"} 3
+test_duration_seconds_sum{query="rows"} 5.55
+test_duration_seconds_count{query="rows"} 3
+`
+	if out := collect(h); out != expected {
+		t.Error("Unexpected output", out)
+	}
+}
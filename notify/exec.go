@@ -0,0 +1,61 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func init() {
+	RegisterNotifierType("exec", newExec)
+}
+
+// execNotifier runs a local command, with the body on its standard input
+// and the message in the GOWX_TITLE, GOWX_BODY and GOWX_PRIORITY
+// environment variables.
+type execNotifier struct {
+	command string
+	args    []string
+	timeout time.Duration
+}
+
+func newExec(config *viper.Viper) (Notifier, error) {
+	e := &execNotifier{
+		command: config.GetString("command"),
+		args:    config.GetStringSlice("args"),
+		timeout: 30 * time.Second,
+	}
+	if e.command == "" {
+		return nil, errors.New("command is required")
+	}
+	if config.IsSet("timeout") {
+		e.timeout = time.Duration(config.GetFloat64("timeout") * float64(time.Second))
+	}
+	return e, nil
+}
+
+func (e *execNotifier) Notify(m Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, e.command, e.args...)
+	cmd.Stdin = strings.NewReader(m.Body)
+	cmd.Env = append(os.Environ(),
+		"GOWX_TITLE="+m.Title,
+		"GOWX_BODY="+m.Body,
+		"GOWX_PRIORITY="+strconv.Itoa(m.Priority),
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %s: %s", e.command, err.Error(), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
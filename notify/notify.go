@@ -0,0 +1,260 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+// Package notify delivers alerts and daily summaries through channels such
+// as email, push services and local commands.
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+
+	"github.com/geoffholden/gowx/alert"
+	"github.com/geoffholden/gowx/records"
+)
+
+// The types of event.
+const (
+	Alert = "alert"
+	Daily = "daily"
+	Test  = "test"
+)
+
+// Event is what is notified, and the data of the templates.
+type Event struct {
+	Type    string
+	Time    time.Time
+	Station string
+	// Alert is set for alerts.
+	Alert *alert.Alert
+	// Day and Records are set for daily summaries.
+	Day     string
+	Records []records.Record
+}
+
+// Message is a rendered notification.
+type Message struct {
+	Title string
+	Body  string
+	// Priority runs from 1 (min) to 5 (urgent), 3 being the default.
+	Priority int
+}
+
+// Notifier sends messages through a channel.
+type Notifier interface {
+	Notify(m Message) error
+}
+
+// Factory creates a notifier from its configuration section.
+type Factory func(config *viper.Viper) (Notifier, error)
+
+var factories map[string]Factory
+
+func RegisterNotifierType(name string, factory Factory) {
+	if nil == factories {
+		factories = make(map[string]Factory)
+	}
+	factories[name] = factory
+}
+
+const (
+	defaultTitle = `{{if .Station}}[{{.Station}}] {{end}}` +
+		`{{if eq .Type "test"}}Test notification` +
+		`{{else if .Alert}}{{.Alert.Rule}} {{if .Alert.Firing}}firing{{else}}cleared{{end}}` +
+		`{{else}}Summary for {{.Day}}{{end}}`
+	defaultBody = `{{if eq .Type "test"}}Notifications from gowx are working.` +
+		`{{else if .Alert}}{{.Alert.Message}} ({{printf "%g" .Alert.Value}}) at {{.Time.Format "2006-01-02 15:04"}}` +
+		`{{else}}{{range .Records}}{{if .Name}}{{.Name}}{{else}}{{.ID}}/{{.Channel}}{{end}} {{.Key}}: ` +
+		`high {{printf "%.1f" .High}}, low {{printf "%.1f" .Low}}, mean {{printf "%.1f" .Mean}}` +
+		`{{if .Total}}, total {{printf "%.1f" .Total}}{{end}}
+{{end}}{{end}}`
+)
+
+// TestEvent returns a test event. It has a sample alert and day, so that
+// templates written for alerts or daily summaries render too.
+func TestEvent(station string, now time.Time) Event {
+	return Event{
+		Type:    Test,
+		Time:    now,
+		Station: station,
+		Alert: &alert.Alert{
+			Rule:    "test",
+			Message: "Test alert",
+			Key:     "Temperature",
+			Firing:  true,
+			Time:    now,
+			Since:   now,
+		},
+		Day: now.Format("2006-01-02"),
+	}
+}
+
+// Channel is a configured notifier, with the events it takes, its message
+// templates and its rate limit.
+type Channel struct {
+	Name     string
+	Notifier Notifier
+	// Events are the event types sent, all of them if empty.
+	Events map[string]bool
+	Title  *template.Template
+	Body   *template.Template
+	// At most Limit messages are sent Per period, none if Limit is 0.
+	Limit int
+	Per   time.Duration
+	// Now is the clock of the rate limit.
+	Now func() time.Time
+
+	mu   sync.Mutex
+	sent []time.Time
+}
+
+// NewChannel creates a channel from its configuration section.
+func NewChannel(name string, config *viper.Viper) (*Channel, error) {
+	factory, ok := factories[config.GetString("type")]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %q", config.GetString("type"))
+	}
+	n, err := factory(config)
+	if err != nil {
+		return nil, err
+	}
+	c := &Channel{
+		Name:     name,
+		Notifier: n,
+		Events:   make(map[string]bool),
+		Limit:    config.GetInt("limit"),
+		Per:      time.Hour,
+		Now:      time.Now,
+	}
+	for _, event := range config.GetStringSlice("events") {
+		switch event {
+		case Alert, Daily:
+			c.Events[event] = true
+		default:
+			return nil, fmt.Errorf("unknown event %q", event)
+		}
+	}
+	if config.IsSet("per") {
+		if c.Per, err = time.ParseDuration(config.GetString("per")); err != nil {
+			return nil, err
+		}
+	}
+	title, body := defaultTitle, defaultBody
+	if config.IsSet("title") {
+		title = config.GetString("title")
+	}
+	if config.IsSet("body") {
+		body = config.GetString("body")
+	}
+	if c.Title, err = template.New("title").Parse(title); err != nil {
+		return nil, err
+	}
+	if c.Body, err = template.New("body").Parse(body); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Render renders the message of an event.
+func (c *Channel) Render(e Event) (Message, error) {
+	m := Message{Priority: 3}
+	if e.Alert != nil && e.Alert.Firing {
+		m.Priority = 4
+	}
+	var buf bytes.Buffer
+	if err := c.Title.Execute(&buf, e); err != nil {
+		return m, err
+	}
+	m.Title = strings.TrimSpace(buf.String())
+	buf.Reset()
+	if err := c.Body.Execute(&buf, e); err != nil {
+		return m, err
+	}
+	m.Body = strings.TrimSpace(buf.String())
+	return m, nil
+}
+
+// allow reports whether the rate limit allows another message, and counts
+// it if so. Only messages that rendered are counted.
+func (c *Channel) allow() bool {
+	if c.Limit <= 0 {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.Now()
+	cutoff := now.Add(-c.Per)
+	for len(c.sent) > 0 && !c.sent[0].After(cutoff) {
+		c.sent = c.sent[1:]
+	}
+	if len(c.sent) >= c.Limit {
+		return false
+	}
+	c.sent = append(c.sent, now)
+	return true
+}
+
+// Send renders and sends an event, unless the channel doesn't take it or
+// is over its rate limit.
+func (c *Channel) Send(e Event) error {
+	if e.Type != Test && len(c.Events) > 0 && !c.Events[e.Type] {
+		return nil
+	}
+	m, err := c.Render(e)
+	if err != nil {
+		return err
+	}
+	if !c.allow() {
+		jww.WARN.Printf("Notifier %s: over the rate limit, dropping %s event\n", c.Name, e.Type)
+		return nil
+	}
+	return c.Notifier.Notify(m)
+}
+
+// Notifiers sends events to every channel.
+type Notifiers struct {
+	Channels []*Channel
+}
+
+// Open creates the channels in the "notify.channels" section, or only the
+// named ones.
+func Open(names []string) (*Notifiers, error) {
+	if len(names) == 0 {
+		for name := range viper.GetStringMap("notify.channels") {
+			names = append(names, name)
+		}
+	}
+	n := &Notifiers{}
+	for _, name := range names {
+		config := viper.Sub("notify.channels." + name)
+		if config == nil {
+			return nil, fmt.Errorf("notifier %s: not configured", name)
+		}
+		c, err := NewChannel(name, config)
+		if err != nil {
+			return nil, fmt.Errorf("notifier %s: %s", name, err.Error())
+		}
+		n.Channels = append(n.Channels, c)
+	}
+	return n, nil
+}
+
+// Send sends an event to every channel in the background, logging errors.
+func (n *Notifiers) Send(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	for _, c := range n.Channels {
+		go func(c *Channel) {
+			if err := c.Send(e); err != nil {
+				jww.ERROR.Printf("Notifier %s: %s\n", c.Name, err.Error())
+			}
+		}(c)
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/geoffholden/gowx/alert"
+	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/records"
+	"github.com/spf13/viper"
+)
+
+// recorder is a notifier keeping the messages.
+type recorder struct {
+	messages []Message
+}
+
+func (r *recorder) Notify(m Message) error {
+	r.messages = append(r.messages, m)
+	return nil
+}
+
+func newTestChannel(t *testing.T, settings map[string]interface{}) (*Channel, *recorder) {
+	rec := &recorder{}
+	RegisterNotifierType("recorder", func(config *viper.Viper) (Notifier, error) {
+		return rec, nil
+	})
+	config := viper.New()
+	config.Set("type", "recorder")
+	for key, value := range settings {
+		config.Set(key, value)
+	}
+	c, err := NewChannel("test", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, rec
+}
+
+var frost = Event{
+	Type:    Alert,
+	Time:    time.Date(2026, 10, 19, 6, 30, 0, 0, time.UTC),
+	Station: "home",
+	Alert: &alert.Alert{
+		Rule:    "greenhouse_frost",
+		Message: "Frost in the greenhouse",
+		Sensor:  data.SensorKey{ID: "OS3:1D20", Channel: 2},
+		Key:     "Temperature",
+		Firing:  true,
+		Value:   -0.5,
+	},
+}
+
+var summary = Event{
+	Type: Daily,
+	Day:  "2026-10-18",
+	Records: []records.Record{
+		{Name: "outdoor", Key: "Temperature", High: 12.25, Low: 3, Mean: 7.5},
+		{SensorKey: data.SensorKey{ID: "VN1:6D27", Channel: 1}, Key: "RainTotal", High: 10, Low: 5, Mean: 7, Total: 5},
+	},
+}
+
+func TestDefaultTemplates(t *testing.T) {
+	c, _ := newTestChannel(t, nil)
+	m, err := c.Render(frost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Title != "[home] greenhouse_frost firing" || m.Body != "Frost in the greenhouse (-0.5) at 2026-10-19 06:30" || m.Priority != 4 {
+		t.Error("Unexpected message", m)
+	}
+
+	m, err = c.Render(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "outdoor Temperature: high 12.2, low 3.0, mean 7.5\nVN1:6D27/1 RainTotal: high 10.0, low 5.0, mean 7.0, total 5.0"
+	if m.Title != "Summary for 2026-10-18" || m.Body != expected || m.Priority != 3 {
+		t.Error("Unexpected message", m)
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	c, rec := newTestChannel(t, map[string]interface{}{
+		"title":  "{{.Alert.Rule}}",
+		"body":   `{{.Alert.Key}} is {{printf "%.1f" .Alert.Value}}`,
+		"events": []string{"alert"},
+	})
+	if err := c.Send(frost); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Send(summary); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.messages) != 1 || rec.messages[0].Title != "greenhouse_frost" || rec.messages[0].Body != "Temperature is -0.5" {
+		t.Error("Unexpected messages", rec.messages)
+	}
+	// Templates for alerts render the test event too.
+	if err := c.Send(TestEvent("home", time.Now())); err != nil {
+		t.Error("Unexpected error sending the test event", err)
+	}
+	if len(rec.messages) != 2 || rec.messages[1].Title != "test" {
+		t.Error("Unexpected test message", rec.messages)
+	}
+
+	if _, err := NewChannel("bad", viperOf(map[string]interface{}{"type": "recorder", "body": "{{.Alert"})); err == nil {
+		t.Error("Bad templates should be an error")
+	}
+	if _, err := NewChannel("bad", viperOf(map[string]interface{}{"type": "carrier-pigeon"})); err == nil {
+		t.Error("Unknown types should be an error")
+	}
+}
+
+func viperOf(settings map[string]interface{}) *viper.Viper {
+	config := viper.New()
+	for key, value := range settings {
+		config.Set(key, value)
+	}
+	return config
+}
+
+func TestRateLimit(t *testing.T) {
+	c, rec := newTestChannel(t, map[string]interface{}{"limit": 2, "per": "10m"})
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	c.Now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		c.Send(frost)
+		now = now.Add(time.Minute)
+	}
+	if len(rec.messages) != 2 {
+		t.Error("Expected 2 messages within the limit", len(rec.messages))
+	}
+	now = now.Add(8 * time.Minute)
+	c.Send(frost)
+	if len(rec.messages) != 3 {
+		t.Error("Expected the limit to allow another message", len(rec.messages))
+	}
+
+	// Messages that fail to render don't use up the limit.
+	c, rec = newTestChannel(t, map[string]interface{}{"limit": 1, "body": "{{.Alert.Message}}"})
+	if err := c.Send(summary); err == nil {
+		t.Error("Expected a template error")
+	}
+	c.Send(frost)
+	if len(rec.messages) != 1 {
+		t.Error("Expected the message within the limit", len(rec.messages))
+	}
+}
+
+func TestDefaultTestEvent(t *testing.T) {
+	c, _ := newTestChannel(t, nil)
+	m, err := c.Render(TestEvent("home", time.Now()))
+	if err != nil || m.Title != "[home] Test notification" || m.Body != "Notifications from gowx are working." {
+		t.Error("Unexpected test message", m, err)
+	}
+}
+
+type request struct {
+	path   string
+	header http.Header
+	body   []byte
+}
+
+func newServer(t *testing.T) (*[]request, string) {
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, request{r.URL.Path, r.Header, body})
+	}))
+	t.Cleanup(server.Close)
+	return &requests, server.URL
+}
+
+func TestNtfy(t *testing.T) {
+	requests, url := newServer(t)
+	n, err := newNtfy(viperOf(map[string]interface{}{"url": url + "/weather", "token": "tk_secret", "tags": []string{"snowflake"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(Message{"Frost", "It's cold", 4}); err != nil {
+		t.Fatal(err)
+	}
+	r := (*requests)[0]
+	if r.path != "/weather" || string(r.body) != "It's cold" || r.header.Get("Title") != "Frost" ||
+		r.header.Get("Priority") != "4" || r.header.Get("Tags") != "snowflake" || r.header.Get("Authorization") != "Bearer tk_secret" {
+		t.Error("Unexpected request", r)
+	}
+}
+
+func TestGotify(t *testing.T) {
+	requests, url := newServer(t)
+	n, err := newGotify(viperOf(map[string]interface{}{"url": url + "/", "token": "secret"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(Message{"Frost", "It's cold", 4}); err != nil {
+		t.Fatal(err)
+	}
+	r := (*requests)[0]
+	var body map[string]interface{}
+	json.Unmarshal(r.body, &body)
+	if r.path != "/message" || r.header.Get("X-Gotify-Key") != "secret" || body["title"] != "Frost" || body["message"] != "It's cold" || body["priority"] != 8.0 {
+		t.Error("Unexpected request", r.path, string(r.body))
+	}
+}
+
+func TestPushover(t *testing.T) {
+	requests, server := newServer(t)
+	n, err := newPushover(viperOf(map[string]interface{}{"url": server + "/1/messages.json", "token": "app", "user": "me"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(Message{"Frost", "It's cold", 5}); err != nil {
+		t.Fatal(err)
+	}
+	form, _ := url.ParseQuery(string((*requests)[0].body))
+	if form.Get("token") != "app" || form.Get("user") != "me" || form.Get("title") != "Frost" || form.Get("priority") != "1" {
+		t.Error("Unexpected form", form)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"status":0,"errors":["user identifier is invalid"]}`, http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	n, _ = newPushover(viperOf(map[string]interface{}{"url": failing.URL, "token": "app", "user": "me"}))
+	if err := n.Notify(Message{"Frost", "It's cold", 3}); err == nil || !strings.Contains(err.Error(), "invalid") {
+		t.Error("Expected an error", err)
+	}
+}
+
+func TestExec(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	n, err := newExec(viperOf(map[string]interface{}{
+		"command": "sh",
+		"args":    []string{"-c", `(echo "$GOWX_TITLE/$GOWX_PRIORITY"; cat) > "$0"`, out},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(Message{"Frost", "It's cold", 4}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "Frost/4\nIt's cold" {
+		t.Error("Unexpected output", string(b))
+	}
+
+	n, _ = newExec(viperOf(map[string]interface{}{"command": "sh", "args": []string{"-c", "echo oops; exit 3"}}))
+	if err := n.Notify(Message{}); err == nil || !strings.Contains(err.Error(), "oops") {
+		t.Error("Expected an error with the output", err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("notify.channels.script.type", "exec")
+	viper.Set("notify.channels.script.command", os.Args[0])
+	n, err := Open(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(n.Channels) != 1 || n.Channels[0].Name != "script" {
+		t.Error("Unexpected channels", n.Channels)
+	}
+	if _, err := Open([]string{"email"}); err == nil {
+		t.Error("Unconfigured channels should be an error")
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func init() {
+	RegisterNotifierType("ntfy", newNtfy)
+	RegisterNotifierType("gotify", newGotify)
+	RegisterNotifierType("pushover", newPushover)
+}
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+func post(req *http.Request) error {
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// ntfy publishes to a topic URL of an ntfy server, e.g.
+// https://ntfy.sh/mytopic.
+type ntfy struct {
+	url   string
+	token string
+	tags  []string
+}
+
+func newNtfy(config *viper.Viper) (Notifier, error) {
+	n := &ntfy{
+		url:   config.GetString("url"),
+		token: config.GetString("token"),
+		tags:  config.GetStringSlice("tags"),
+	}
+	if n.url == "" {
+		return nil, errors.New("url is required")
+	}
+	return n, nil
+}
+
+func (n *ntfy) Notify(m Message) error {
+	req, err := http.NewRequest(http.MethodPost, n.url, strings.NewReader(m.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", m.Title)
+	req.Header.Set("Priority", strconv.Itoa(m.Priority))
+	if len(n.tags) > 0 {
+		req.Header.Set("Tags", strings.Join(n.tags, ","))
+	}
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+	return post(req)
+}
+
+// gotify sends to a Gotify server with an application token. Gotify
+// priorities run from 0 to 10.
+type gotify struct {
+	url   string
+	token string
+}
+
+func newGotify(config *viper.Viper) (Notifier, error) {
+	g := &gotify{
+		url:   strings.TrimSuffix(config.GetString("url"), "/"),
+		token: config.GetString("token"),
+	}
+	if g.url == "" || g.token == "" {
+		return nil, errors.New("url and token are required")
+	}
+	return g, nil
+}
+
+func (g *gotify) Notify(m Message) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"title":    m.Title,
+		"message":  m.Body,
+		"priority": m.Priority * 2,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, g.url+"/message", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", g.token)
+	return post(req)
+}
+
+// pushover sends through the Pushover API, or a server compatible with it.
+// Pushover priorities run from -2 to 2.
+type pushover struct {
+	url   string
+	token string
+	user  string
+}
+
+func newPushover(config *viper.Viper) (Notifier, error) {
+	p := &pushover{
+		url:   "https://api.pushover.net/1/messages.json",
+		token: config.GetString("token"),
+		user:  config.GetString("user"),
+	}
+	if config.IsSet("url") {
+		p.url = config.GetString("url")
+	}
+	if p.token == "" || p.user == "" {
+		return nil, errors.New("token and user are required")
+	}
+	return p, nil
+}
+
+func (p *pushover) Notify(m Message) error {
+	priority := m.Priority - 3
+	if priority > 1 {
+		// Emergency priority needs retry and expire parameters.
+		priority = 1
+	}
+	form := url.Values{
+		"token":    {p.token},
+		"user":     {p.user},
+		"title":    {m.Title},
+		"message":  {m.Body},
+		"priority": {strconv.Itoa(priority)},
+	}
+	req, err := http.NewRequest(http.MethodPost, p.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return post(req)
+}
@@ -0,0 +1,127 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func init() {
+	RegisterNotifierType("smtp", newSMTP)
+}
+
+// smtpNotifier sends email. With "tls" the connection is TLS from the
+// start, as on port 465; otherwise STARTTLS is used when the server offers
+// it, and required unless "starttls" is false.
+type smtpNotifier struct {
+	server   string
+	username string
+	password string
+	from     string
+	to       []string
+	tls      bool
+	starttls bool
+	config   *tls.Config
+}
+
+func newSMTP(config *viper.Viper) (Notifier, error) {
+	s := &smtpNotifier{
+		server:   config.GetString("server"),
+		username: config.GetString("username"),
+		password: config.GetString("password"),
+		from:     config.GetString("from"),
+		to:       config.GetStringSlice("to"),
+		tls:      config.GetBool("tls"),
+		starttls: true,
+	}
+	if config.IsSet("starttls") {
+		s.starttls = config.GetBool("starttls")
+	}
+	if s.server == "" || s.from == "" || len(s.to) == 0 {
+		return nil, errors.New("server, from and to are required")
+	}
+	host, _, err := net.SplitHostPort(s.server)
+	if err != nil {
+		return nil, err
+	}
+	s.config = &tls.Config{ServerName: host, InsecureSkipVerify: config.GetBool("insecure")}
+	return s, nil
+}
+
+func (s *smtpNotifier) Notify(m Message) error {
+	conn, err := net.DialTimeout("tcp", s.server, 30*time.Second)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(time.Minute))
+	if s.tls {
+		conn = tls.Client(conn, s.config)
+	}
+	c, err := smtp.NewClient(conn, s.config.ServerName)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if !s.tls {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(s.config); err != nil {
+				return err
+			}
+		} else if s.starttls {
+			return errors.New("server doesn't support STARTTLS")
+		}
+	}
+	if s.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.username, s.password, s.config.ServerName)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(s.from); err != nil {
+		return err
+	}
+	for _, to := range s.to {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(s.message(m)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (s *smtpNotifier) message(m Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", s.from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Title))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	if m.Priority >= 4 {
+		buf.WriteString("X-Priority: 1\r\n")
+	}
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.Replace(m.Body, "\n", "\r\n", -1))
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
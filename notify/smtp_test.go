@@ -0,0 +1,136 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package notify
+
+import (
+	"bufio"
+	"encoding/base64"
+	"net"
+	"strings"
+	"testing"
+)
+
+// smtpServer is a stand-in SMTP server, recording one session.
+type smtpServer struct {
+	listener net.Listener
+	commands []string
+	auth     string
+	data     string
+	done     chan struct{}
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{listener: l, done: make(chan struct{})}
+	t.Cleanup(func() { l.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *smtpServer) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) {
+		conn.Write([]byte(line + "\r\n"))
+	}
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		s.commands = append(s.commands, strings.Fields(line)[0])
+		switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			b, _ := base64.StdEncoding.DecodeString(strings.Fields(line)[2])
+			s.auth = string(b)
+			reply("235 Authentication successful")
+		case "MAIL", "RCPT":
+			reply("250 OK")
+		case "DATA":
+			reply("354 Go ahead")
+			var data []string
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data = append(data, l)
+			}
+			s.data = strings.Join(data, "")
+			reply("250 Queued")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Not implemented")
+		}
+	}
+}
+
+func TestSMTP(t *testing.T) {
+	s := newSMTPServer(t)
+	n, err := newSMTP(viperOf(map[string]interface{}{
+		"server":   s.listener.Addr().String(),
+		"username": "station",
+		"password": "secret",
+		"from":     "station@example.com",
+		"to":       []string{"me@example.com", "you@example.com"},
+		"starttls": false,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(Message{"Frost in the greenhouse", "It's -0.5°C.\nCover the plants.", 4}); err != nil {
+		t.Fatal(err)
+	}
+	<-s.done
+
+	if strings.Join(s.commands, " ") != "EHLO AUTH MAIL RCPT RCPT DATA QUIT" {
+		t.Error("Unexpected commands", s.commands)
+	}
+	if s.auth != "\x00station\x00secret" {
+		t.Errorf("Unexpected credentials %q", s.auth)
+	}
+	for _, expected := range []string{
+		"From: station@example.com\r\n",
+		"To: me@example.com, you@example.com\r\n",
+		"Subject: Frost in the greenhouse\r\n",
+		"X-Priority: 1\r\n",
+		"\r\n\r\nIt's -0.5°C.\r\nCover the plants.\r\n",
+	} {
+		if !strings.Contains(s.data, expected) {
+			t.Errorf("Expected %q in %q", expected, s.data)
+		}
+	}
+}
+
+func TestSMTPRequiresSTARTTLS(t *testing.T) {
+	s := newSMTPServer(t)
+	n, err := newSMTP(viperOf(map[string]interface{}{
+		"server": s.listener.Addr().String(),
+		"from":   "station@example.com",
+		"to":     []string{"me@example.com"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(Message{"Frost", "Cold", 3}); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Error("Expected an error without STARTTLS", err)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+// Package queue is a disk-backed FIFO queue, used to store messages while
+// they can't be delivered and forward them in order once they can.
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const suffix = ".msg"
+
+// Queue is a bounded queue with one file per item. When it is full, the
+// oldest items are dropped.
+type Queue struct {
+	dir  string
+	max  int
+	mu   sync.Mutex
+	seqs []uint64
+	next uint64
+}
+
+// Open opens the queue in dir, creating the directory if needed. max is the
+// maximum number of items, or 0 for no limit.
+func Open(dir string, max int) (*Queue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	q := &Queue{dir: dir, max: max}
+	// ReadDir sorts by name, and the names are zero padded sequence numbers.
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), suffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(f.Name(), suffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		q.seqs = append(q.seqs, seq)
+		q.next = seq + 1
+	}
+	return q, nil
+}
+
+func (q *Queue) path(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, suffix))
+}
+
+// Len returns the number of queued items.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.seqs)
+}
+
+// Push adds an item at the end of the queue.
+func (q *Queue) Push(item []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	seq := q.next
+	tmp := q.path(seq) + ".tmp"
+	if err := ioutil.WriteFile(tmp, item, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, q.path(seq)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	q.next++
+	q.seqs = append(q.seqs, seq)
+
+	for q.max > 0 && len(q.seqs) > q.max {
+		os.Remove(q.path(q.seqs[0]))
+		q.seqs = q.seqs[1:]
+	}
+	return nil
+}
+
+// Peek returns the item at the front of the queue.
+func (q *Queue) Peek() ([]byte, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.seqs) == 0 {
+		return nil, false, nil
+	}
+	item, err := ioutil.ReadFile(q.path(q.seqs[0]))
+	return item, err == nil, err
+}
+
+// Pop removes the item at the front of the queue.
+func (q *Queue) Pop() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.seqs) == 0 {
+		return errors.New("queue: empty")
+	}
+	err := os.Remove(q.path(q.seqs[0]))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	q.seqs = q.seqs[1:]
+	return nil
+}
+
+// Replay sends the queued items in order, removing each one once it is
+// sent. It stops at the first item that can't be sent, and returns the
+// number of items sent.
+func (q *Queue) Replay(send func(item []byte) error) (int, error) {
+	n := 0
+	for {
+		item, ok, err := q.Peek()
+		if err != nil {
+			// Unreadable items would block the queue forever.
+			if err := q.Pop(); err != nil {
+				return n, err
+			}
+			continue
+		}
+		if !ok {
+			return n, nil
+		}
+		if err := send(item); err != nil {
+			return n, err
+		}
+		if err := q.Pop(); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// Forwarder sends items, queueing the ones that can't be sent until they
+// can. Items are always sent in order. A Forwarder without a Queue just
+// sends.
+type Forwarder struct {
+	Queue *Queue
+	Send  func(item []byte) error
+	// OnError, if set, is called when sending fails.
+	OnError func(err error)
+
+	mu sync.Mutex
+}
+
+// Forward sends an item, or queues it if it can't be sent or older items
+// are still queued. An error is only returned if the item is lost.
+func (f *Forwarder) Forward(item []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Queue == nil || f.Queue.Len() == 0 {
+		err := f.Send(item)
+		if err == nil || f.Queue == nil {
+			return err
+		}
+		f.error(err)
+		return f.Queue.Push(item)
+	}
+	if err := f.Queue.Push(item); err != nil {
+		return err
+	}
+	f.flush()
+	return nil
+}
+
+// Flush sends the queued items.
+func (f *Forwarder) Flush() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flush()
+}
+
+func (f *Forwarder) flush() {
+	if f.Queue == nil {
+		return
+	}
+	if _, err := f.Queue.Replay(f.Send); err != nil {
+		f.error(err)
+	}
+}
+
+func (f *Forwarder) error(err error) {
+	if f.OnError != nil {
+		f.OnError(err)
+	}
+}
+
+// Run flushes the queue every interval until stop is closed.
+func (f *Forwarder) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			f.Flush()
+		case <-stop:
+			return
+		}
+	}
+}
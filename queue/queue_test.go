@@ -0,0 +1,104 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package queue
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func contents(t *testing.T, q *Queue) []string {
+	var items []string
+	if _, err := q.Replay(func(item []byte) error {
+		items = append(items, string(item))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return items
+}
+
+func TestQueue(t *testing.T) {
+	dir := t.TempDir()
+	q, err := Open(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 4; i++ {
+		if err := q.Push([]byte(strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if q.Len() != 3 {
+		t.Error("Expected 3 items, got", q.Len())
+	}
+
+	// The queue survives a restart, and the oldest item was dropped.
+	q, err = Open(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Push([]byte("5"))
+	items := contents(t, q)
+	if len(items) != 3 || items[0] != "3" || items[1] != "4" || items[2] != "5" {
+		t.Error("Unexpected items", items)
+	}
+	if q.Len() != 0 {
+		t.Error("Queue should be empty", q.Len())
+	}
+}
+
+func TestReplayStops(t *testing.T) {
+	q, err := Open(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Push([]byte("a"))
+	q.Push([]byte("b"))
+	n, err := q.Replay(func(item []byte) error {
+		if string(item) == "b" {
+			return errors.New("offline")
+		}
+		return nil
+	})
+	if n != 1 || err == nil {
+		t.Error("Unexpected result", n, err)
+	}
+	if item, ok, _ := q.Peek(); !ok || string(item) != "b" {
+		t.Error("Unsent item should stay queued", string(item))
+	}
+}
+
+func TestForwarder(t *testing.T) {
+	q, err := Open(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	online := false
+	var sent []string
+	f := &Forwarder{Queue: q, Send: func(item []byte) error {
+		if !online {
+			return errors.New("offline")
+		}
+		sent = append(sent, string(item))
+		return nil
+	}}
+
+	f.Forward([]byte("1"))
+	f.Forward([]byte("2"))
+	if len(sent) != 0 || q.Len() != 2 {
+		t.Fatal("Items should be queued", sent, q.Len())
+	}
+
+	online = true
+	f.Forward([]byte("3"))
+	if len(sent) != 3 || sent[0] != "1" || sent[1] != "2" || sent[2] != "3" {
+		t.Error("Items should be sent in order", sent)
+	}
+
+	f.Forward([]byte("4"))
+	if len(sent) != 4 || q.Len() != 0 {
+		t.Error("Items should be sent directly", sent, q.Len())
+	}
+}
@@ -0,0 +1,368 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+// Package records maintains daily summaries of the aggregated data and
+// derives monthly, yearly and all-time records from them.
+package records
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/geoffholden/gowx/data"
+)
+
+const dayFormat = "2006-01-02"
+
+// Aggregate is one aggregated value, as produced by the aggregator.
+type Aggregate struct {
+	Timestamp int64
+	Sensor    data.SensorKey
+	Key       string
+	Min       float64
+	Max       float64
+	Avg       float64
+	// Name is the logical sensor of Sensor, or "" if it isn't registered.
+	// A logical sensor's summaries carry on when its physical sensor
+	// changes, such as after a battery change.
+	Name string
+	// Station is the station of the sensor, "" for the default one.
+	Station string
+}
+
+// Store loads and saves daily summaries.
+type Store interface {
+	DailySummary(day string, station string, name string, sensor data.SensorKey, key string) (data.DailySummary, bool, error)
+	SaveDailySummary(s data.DailySummary, exists bool) error
+}
+
+// seriesKey identifies the summaries of one key of a sensor of a station:
+// the logical sensor if it's named, otherwise the physical one.
+type seriesKey struct {
+	station string
+	name    string
+	sensor  data.SensorKey
+	key     string
+}
+
+func newSeriesKey(station string, name string, sensor data.SensorKey, key string) seriesKey {
+	if name != "" {
+		sensor = data.SensorKey{}
+	}
+	return seriesKey{station, name, sensor, key}
+}
+
+type entry struct {
+	summary data.DailySummary
+	exists  bool
+}
+
+// Tracker incrementally updates the daily summaries as aggregates arrive.
+type Tracker struct {
+	// Location defines the day boundary of the default station, and
+	// StationLocation, if set, that of a named station.
+	Location        *time.Location
+	StationLocation func(station string) *time.Location
+	// Cumulative keys, such as RainTotal, also track their increase over
+	// the day.
+	Cumulative map[string]bool
+	// DirectionKey is recorded alongside the daily high of other keys from
+	// the same sensor, giving the direction of the maximum gust.
+	DirectionKey string
+	// OnChange, if set, is called when a saved aggregate sets a new daily
+	// high or low. The first aggregate of a day and cumulative keys don't
+	// count.
+	OnChange func(c Change)
+
+	store Store
+	today map[seriesKey]*entry
+	last  map[seriesKey]float64
+}
+
+func NewTracker(store Store, location *time.Location) *Tracker {
+	return &Tracker{
+		Location:     location,
+		Cumulative:   map[string]bool{"RainTotal": true},
+		DirectionKey: "WindDir",
+		store:        store,
+		today:        make(map[seriesKey]*entry),
+		last:         make(map[seriesKey]float64),
+	}
+}
+
+// Change is a new daily high or low.
+type Change struct {
+	Day       string
+	Station   string
+	Sensor    data.SensorKey
+	Key       string
+	Kind      string // "high" or "low"
+	Value     float64
+	Previous  float64
+	Timestamp int64
+}
+
+// Day returns the day of a timestamp, local to a station.
+func (t *Tracker) Day(station string, timestamp int64) string {
+	loc := t.Location
+	if station != "" && t.StationLocation != nil {
+		loc = t.StationLocation(station)
+	}
+	return time.Unix(timestamp, 0).In(loc).Format(dayFormat)
+}
+
+// Update applies a batch of aggregates to the daily summaries.
+func (t *Tracker) Update(aggs []Aggregate) error {
+	dirs := make(map[dirKey]float64)
+	for _, agg := range aggs {
+		if agg.Key == t.DirectionKey {
+			dirs[dirKey{agg.Station, agg.Sensor}] = math.Mod(agg.Avg+360.0, 360.0)
+		}
+	}
+
+	var firstErr error
+	for _, agg := range aggs {
+		if strings.HasSuffix(agg.Key, "Dir") {
+			continue
+		}
+		sk := newSeriesKey(agg.Station, agg.Name, agg.Sensor, agg.Key)
+		day := t.Day(agg.Station, agg.Timestamp)
+		e := t.today[sk]
+		if e == nil || e.summary.Day != day {
+			s, exists, err := t.store.DailySummary(day, agg.Station, agg.Name, agg.Sensor, agg.Key)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			if !exists {
+				s = data.DailySummary{Day: day, Station: agg.Station, Name: agg.Name, SensorKey: agg.Sensor, Key: agg.Key, HighDir: math.NaN()}
+			} else if _, ok := t.last[sk]; !ok {
+				t.last[sk] = s.Latest
+			}
+			e = &entry{s, exists}
+			t.today[sk] = e
+		}
+
+		prev := e.summary
+		t.apply(&e.summary, sk, agg, dirs)
+		if err := t.store.SaveDailySummary(e.summary, e.exists); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		e.exists = true
+		if t.OnChange != nil && prev.MeanCount > 0 && !t.Cumulative[agg.Key] {
+			t.changes(prev, e.summary, agg.Timestamp)
+		}
+	}
+	return firstErr
+}
+
+func (t *Tracker) changes(prev data.DailySummary, s data.DailySummary, timestamp int64) {
+	c := Change{Day: s.Day, Station: s.Station, Sensor: s.SensorKey, Key: s.Key, Timestamp: timestamp}
+	if s.High > prev.High {
+		c.Kind, c.Value, c.Previous = "high", s.High, prev.High
+		t.OnChange(c)
+	}
+	if s.Low < prev.Low {
+		c.Kind, c.Value, c.Previous = "low", s.Low, prev.Low
+		t.OnChange(c)
+	}
+}
+
+// dirKey is the sensor of a station a direction is recorded for.
+type dirKey struct {
+	station string
+	sensor  data.SensorKey
+}
+
+func (t *Tracker) apply(s *data.DailySummary, sk seriesKey, agg Aggregate, dirs map[dirKey]float64) {
+	dir, hasDir := dirs[dirKey{agg.Station, agg.Sensor}]
+	if !hasDir {
+		dir = math.NaN()
+	}
+	if s.MeanCount == 0 {
+		s.High, s.HighTime, s.HighDir = agg.Max, agg.Timestamp, dir
+		s.Low, s.LowTime = agg.Min, agg.Timestamp
+	} else {
+		if agg.Max > s.High {
+			s.High, s.HighTime, s.HighDir = agg.Max, agg.Timestamp, dir
+		}
+		if agg.Min < s.Low {
+			s.Low, s.LowTime = agg.Min, agg.Timestamp
+		}
+	}
+	s.MeanSum += agg.Avg
+	s.MeanCount++
+
+	if t.Cumulative[agg.Key] {
+		// A decrease means the counter was reset, so only count increases.
+		if prev, ok := t.last[sk]; ok && agg.Avg > prev {
+			s.Total += agg.Avg - prev
+		}
+	}
+	s.SensorKey = agg.Sensor
+	s.Latest = agg.Avg
+	t.last[sk] = agg.Avg
+}
+
+// Record holds the extremes of one key of one sensor over a period.
+type Record struct {
+	Station string `json:",omitempty"`
+	Name    string `json:",omitempty"`
+	data.SensorKey
+	Key string
+
+	High     float64
+	HighTime int64
+	HighDir  *float64 `json:",omitempty"`
+	Low      float64
+	LowTime  int64
+
+	Mean        float64
+	HighMean    float64
+	HighMeanDay string
+	LowMean     float64
+	LowMeanDay  string
+
+	Total        float64 `json:",omitempty"`
+	HighTotal    float64 `json:",omitempty"`
+	HighTotalDay string  `json:",omitempty"`
+
+	Days int
+}
+
+// Compute derives the records from a set of daily summaries, in order of
+// day, one per station, sensor and key, sorted by station, sensor then key.
+// The sensor of a logical sensor's record is the last physical one seen.
+func Compute(summaries []data.DailySummary, cumulative map[string]bool) []Record {
+	records := make(map[seriesKey]*Record)
+	sums := make(map[seriesKey]float64)
+	for _, s := range summaries {
+		if s.MeanCount == 0 {
+			continue
+		}
+		sk := newSeriesKey(s.Station, s.Name, s.SensorKey, s.Key)
+		mean := s.Mean()
+		r, ok := records[sk]
+		if !ok {
+			r = &Record{
+				Station:     s.Station,
+				Name:        s.Name,
+				SensorKey:   s.SensorKey,
+				Key:         s.Key,
+				High:        s.High,
+				HighTime:    s.HighTime,
+				Low:         s.Low,
+				LowTime:     s.LowTime,
+				HighMean:    mean,
+				HighMeanDay: s.Day,
+				LowMean:     mean,
+				LowMeanDay:  s.Day,
+			}
+			if !math.IsNaN(s.HighDir) {
+				dir := s.HighDir
+				r.HighDir = &dir
+			}
+			if cumulative[s.Key] {
+				r.HighTotal, r.HighTotalDay = s.Total, s.Day
+			}
+			records[sk] = r
+		} else {
+			r.SensorKey = s.SensorKey
+			if s.High > r.High {
+				r.High, r.HighTime, r.HighDir = s.High, s.HighTime, nil
+				if !math.IsNaN(s.HighDir) {
+					dir := s.HighDir
+					r.HighDir = &dir
+				}
+			}
+			if s.Low < r.Low {
+				r.Low, r.LowTime = s.Low, s.LowTime
+			}
+			if mean > r.HighMean {
+				r.HighMean, r.HighMeanDay = mean, s.Day
+			}
+			if mean < r.LowMean {
+				r.LowMean, r.LowMeanDay = mean, s.Day
+			}
+			if cumulative[s.Key] && s.Total > r.HighTotal {
+				r.HighTotal, r.HighTotalDay = s.Total, s.Day
+			}
+		}
+		if cumulative[s.Key] {
+			r.Total += s.Total
+		}
+		sums[sk] += mean
+		r.Days++
+	}
+
+	result := make([]Record, 0, len(records))
+	for sk, r := range records {
+		r.Mean = sums[sk] / float64(r.Days)
+		result = append(result, *r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Station != b.Station {
+			return a.Station < b.Station
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.ID != b.ID {
+			return a.ID < b.ID
+		}
+		if a.Channel != b.Channel {
+			return a.Channel < b.Channel
+		}
+		if a.Serial != b.Serial {
+			return a.Serial < b.Serial
+		}
+		return a.Key < b.Key
+	})
+	return result
+}
+
+// Period returns the first and last day of a period: "day" (date given as
+// 2006-01-02), "month" (2006-01), "year" (2006) or "all". An empty date
+// selects the period containing now.
+func Period(period string, date string, now time.Time) (string, string, error) {
+	switch period {
+	case "all":
+		return "0000-01-01", "9999-12-31", nil
+	case "day":
+		if date == "" {
+			date = now.Format(dayFormat)
+		}
+		t, err := time.Parse(dayFormat, date)
+		if err != nil {
+			return "", "", err
+		}
+		return t.Format(dayFormat), t.Format(dayFormat), nil
+	case "month", "":
+		if date == "" {
+			date = now.Format("2006-01")
+		}
+		t, err := time.Parse("2006-01", date)
+		if err != nil {
+			return "", "", err
+		}
+		return t.Format(dayFormat), t.AddDate(0, 1, -1).Format(dayFormat), nil
+	case "year":
+		if date == "" {
+			date = now.Format("2006")
+		}
+		t, err := time.Parse("2006", date)
+		if err != nil {
+			return "", "", err
+		}
+		return t.Format(dayFormat), t.AddDate(1, 0, -1).Format(dayFormat), nil
+	}
+	return "", "", fmt.Errorf("unknown period %q", period)
+}
@@ -0,0 +1,210 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package records
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/geoffholden/gowx/data"
+)
+
+type memStore map[string]data.DailySummary
+
+func (m memStore) DailySummary(day string, station string, name string, sensor data.SensorKey, key string) (data.DailySummary, bool, error) {
+	if name == "" {
+		name = sensor.ID
+	}
+	s, ok := m[day+station+name+key]
+	return s, ok, nil
+}
+
+func (m memStore) SaveDailySummary(s data.DailySummary, exists bool) error {
+	name := s.Name
+	if name == "" {
+		name = s.ID
+	}
+	m[s.Day+s.Station+name+s.Key] = s
+	return nil
+}
+
+var (
+	wind    = data.SensorKey{ID: "VN1:6D27", Channel: 1, Serial: "00"}
+	outdoor = data.SensorKey{ID: "OS3:1D20", Channel: 1, Serial: "A4"}
+)
+
+func TestTrackerDayBoundary(t *testing.T) {
+	loc := time.FixedZone("EST", -5*60*60)
+	store := memStore{}
+	tracker := NewTracker(store, loc)
+
+	// 04:00 UTC is still the previous day in EST.
+	late := time.Date(2026, 1, 2, 4, 0, 0, 0, time.UTC).Unix()
+	early := time.Date(2026, 1, 2, 6, 0, 0, 0, time.UTC).Unix()
+	tracker.Update([]Aggregate{{late, outdoor, "Temperature", -3, -1, -2, "", ""}})
+	tracker.Update([]Aggregate{{early, outdoor, "Temperature", -8, -6, -7, "", ""}})
+
+	if s := store["2026-01-01"+outdoor.ID+"Temperature"]; s.Low != -3 || s.MeanCount != 1 {
+		t.Error("Unexpected summary for Jan 1", s)
+	}
+	if s := store["2026-01-02"+outdoor.ID+"Temperature"]; s.Low != -8 || s.LowTime != early {
+		t.Error("Unexpected summary for Jan 2", s)
+	}
+}
+
+func TestTrackerExtremes(t *testing.T) {
+	store := memStore{}
+	tracker := NewTracker(store, time.UTC)
+	base := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+	tracker.Update([]Aggregate{
+		{base, wind, "CurrentWind", 1, 5, 3, "", ""},
+		{base, wind, "WindDir", 90, 90, 90, "", ""},
+		{base, wind, "RainTotal", 10, 10, 10, "", ""},
+	})
+	tracker.Update([]Aggregate{
+		{base + 300, wind, "CurrentWind", 0, 12, 4, "", ""},
+		{base + 300, wind, "WindDir", -45, -45, -45, "", ""},
+		{base + 300, wind, "RainTotal", 12.5, 12.5, 12.5, "", ""},
+	})
+	tracker.Update([]Aggregate{
+		{base + 600, wind, "CurrentWind", 2, 8, 5, "", ""},
+		{base + 600, wind, "WindDir", 180, 180, 180, "", ""},
+		{base + 600, wind, "RainTotal", 1, 1, 1, "", ""},
+	})
+
+	s := store["2026-09-01"+wind.ID+"CurrentWind"]
+	if s.High != 12 || s.HighTime != base+300 || s.HighDir != 315 {
+		t.Error("Unexpected gust", s)
+	}
+	if s.Low != 0 || s.Mean() != 4 {
+		t.Error("Unexpected low or mean", s)
+	}
+	if _, ok := store["2026-09-01"+wind.ID+"WindDir"]; ok {
+		t.Error("Directions should not be summarized")
+	}
+	if s := store["2026-09-01"+wind.ID+"RainTotal"]; s.Total != 2.5 {
+		t.Error("Counter reset should not count as rain", s.Total)
+	}
+}
+
+func TestTrackerLogicalSensor(t *testing.T) {
+	store := memStore{}
+	tracker := NewTracker(store, time.UTC)
+	base := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+	// A battery change gives the sensor a new serial number.
+	replaced := data.SensorKey{ID: outdoor.ID, Channel: outdoor.Channel, Serial: "B7"}
+	tracker.Update([]Aggregate{{base, outdoor, "Temperature", 10, 12, 11, "outdoor", ""}})
+	tracker.Update([]Aggregate{{base + 300, replaced, "Temperature", 8, 9, 8.5, "outdoor", ""}})
+
+	s := store["2026-09-01outdoorTemperature"]
+	if s.High != 12 || s.Low != 8 || s.MeanCount != 2 || s.SensorKey != replaced {
+		t.Error("Unexpected summary", s)
+	}
+	records := Compute([]data.DailySummary{s}, nil)
+	if len(records) != 1 || records[0].Name != "outdoor" || records[0].SensorKey != replaced {
+		t.Error("Unexpected records", records)
+	}
+}
+
+func TestTrackerStations(t *testing.T) {
+	store := memStore{}
+	tracker := NewTracker(store, time.UTC)
+	tracker.StationLocation = func(station string) *time.Location {
+		return time.FixedZone("EST", -5*60*60)
+	}
+
+	// The same sensor at two stations, at 02:00 UTC, which is still the
+	// previous day at the cottage.
+	ts := time.Date(2026, 9, 2, 2, 0, 0, 0, time.UTC).Unix()
+	tracker.Update([]Aggregate{
+		{ts, outdoor, "Temperature", 10, 12, 11, "", ""},
+		{ts, outdoor, "Temperature", 20, 22, 21, "", "cottage"},
+	})
+
+	if s := store["2026-09-02"+outdoor.ID+"Temperature"]; s.High != 12 || s.MeanCount != 1 {
+		t.Error("Unexpected summary of the default station", s)
+	}
+	s := store["2026-09-01cottage"+outdoor.ID+"Temperature"]
+	if s.High != 22 || s.MeanCount != 1 || s.Station != "cottage" {
+		t.Error("Unexpected summary of the cottage", s)
+	}
+	records := Compute([]data.DailySummary{store["2026-09-02"+outdoor.ID+"Temperature"], s}, nil)
+	if len(records) != 2 || records[0].Station != "" || records[1].Station != "cottage" || records[1].High != 22 {
+		t.Error("Unexpected records", records)
+	}
+}
+
+func TestTrackerChanges(t *testing.T) {
+	tracker := NewTracker(memStore{}, time.UTC)
+	var changes []Change
+	tracker.OnChange = func(c Change) {
+		changes = append(changes, c)
+	}
+	base := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+	tracker.Update([]Aggregate{{base, outdoor, "Temperature", 10, 12, 11, "", ""}, {base, wind, "RainTotal", 1, 1, 1, "", ""}})
+	tracker.Update([]Aggregate{{base + 300, outdoor, "Temperature", 11, 12, 11.5, "", ""}, {base + 300, wind, "RainTotal", 2, 2, 2, "", ""}})
+	tracker.Update([]Aggregate{{base + 600, outdoor, "Temperature", 9, 14, 12, "", ""}})
+	if len(changes) != 2 {
+		t.Fatal("Unexpected changes", changes)
+	}
+	if c := changes[0]; c.Kind != "high" || c.Value != 14 || c.Previous != 12 || c.Timestamp != base+600 || c.Day != "2026-09-01" {
+		t.Error("Unexpected high", c)
+	}
+	if c := changes[1]; c.Kind != "low" || c.Value != 9 || c.Previous != 10 {
+		t.Error("Unexpected low", c)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	summaries := []data.DailySummary{
+		{Day: "2026-09-01", SensorKey: outdoor, Key: "Temperature", High: 25, HighTime: 1, HighDir: math.NaN(), Low: 10, LowTime: 2, MeanSum: 36, MeanCount: 2},
+		{Day: "2026-09-02", SensorKey: outdoor, Key: "Temperature", High: 28, HighTime: 3, HighDir: math.NaN(), Low: 12, LowTime: 4, MeanSum: 20, MeanCount: 1},
+		{Day: "2026-09-01", SensorKey: wind, Key: "RainTotal", High: 5, HighDir: math.NaN(), Low: 1, MeanSum: 3, MeanCount: 1, Total: 4},
+		{Day: "2026-09-02", SensorKey: wind, Key: "RainTotal", High: 12, HighDir: math.NaN(), Low: 5, MeanSum: 8, MeanCount: 1, Total: 7},
+	}
+	records := Compute(summaries, map[string]bool{"RainTotal": true})
+	if len(records) != 2 {
+		t.Fatal("Expected 2 records", records)
+	}
+
+	temp := records[0]
+	if temp.Key != "Temperature" || temp.High != 28 || temp.HighTime != 3 || temp.Low != 10 || temp.LowTime != 2 {
+		t.Error("Unexpected temperature record", temp)
+	}
+	if temp.HighMean != 20 || temp.HighMeanDay != "2026-09-02" || temp.Mean != 19 || temp.Days != 2 {
+		t.Error("Unexpected temperature means", temp)
+	}
+	if temp.Total != 0 || temp.HighDir != nil {
+		t.Error("Temperature should have no total or direction", temp)
+	}
+
+	rain := records[1]
+	if rain.Total != 11 || rain.HighTotal != 7 || rain.HighTotalDay != "2026-09-02" {
+		t.Error("Unexpected rain record", rain)
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	now := time.Date(2026, 2, 14, 12, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		period, date, first, last string
+	}{
+		{"month", "", "2026-02-01", "2026-02-28"},
+		{"month", "2024-02", "2024-02-01", "2024-02-29"},
+		{"year", "2025", "2025-01-01", "2025-12-31"},
+		{"day", "", "2026-02-14", "2026-02-14"},
+		{"all", "", "0000-01-01", "9999-12-31"},
+	} {
+		first, last, err := Period(test.period, test.date, now)
+		if err != nil || first != test.first || last != test.last {
+			t.Error("Unexpected period", test.period, test.date, first, last, err)
+		}
+	}
+	if _, _, err := Period("week", "", now); err == nil {
+		t.Error("Unknown period should give an error")
+	}
+}
@@ -0,0 +1,520 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+// Package reports renders text reports from the daily summaries.
+package reports
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/units"
+)
+
+// Store is the part of the database the reports are generated from.
+type Store interface {
+	DailySummaries(first string, last string, key string) ([]data.DailySummary, error)
+	QueryRows(start int64, key string, sensor data.SensorID) <-chan data.Row
+}
+
+// Series selects one key from one or more sensors. An ID of "%" matches any
+// sensor, a negative channel any channel and an empty serial any serial.
+type Series struct {
+	Key     string
+	Sensors []data.SensorID
+}
+
+func (s Series) Match(key data.SensorKey) bool {
+	for _, id := range s.Sensors {
+		if (id.ID == "%" || id.ID == key.ID) &&
+			(id.Channel < 0 || id.Channel == key.Channel) &&
+			(id.Serial == "" || id.Serial == key.Serial) {
+			return true
+		}
+	}
+	return false
+}
+
+// Units are the units the report is shown in, as understood by the units
+// package.
+type Units struct {
+	Temperature string
+	Rain        string
+	Wind        string
+}
+
+func (u Units) validate() error {
+	t := units.NewTemperatureCelsius(0)
+	if _, err := t.Get(u.Temperature); err != nil {
+		return err
+	}
+	r := units.NewDistanceMillimeters(0)
+	if _, err := r.Get(u.Rain); err != nil {
+		return err
+	}
+	s := units.NewSpeedMetersPerSecond(0)
+	if _, err := s.Get(u.Wind); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u Units) temperature(celsius float64) float64 {
+	t := units.NewTemperatureCelsius(celsius)
+	v, _ := t.Get(u.Temperature)
+	return v
+}
+
+func (u Units) rain(mm float64) float64 {
+	r := units.NewDistanceMillimeters(mm)
+	v, _ := r.Get(u.Rain)
+	return v
+}
+
+func (u Units) wind(ms float64) float64 {
+	s := units.NewSpeedMetersPerSecond(ms)
+	v, _ := s.Get(u.Wind)
+	return v
+}
+
+func (u Units) rainDecimals() int {
+	switch strings.ToLower(u.Rain) {
+	case "in", "inch", "inches":
+		return 2
+	}
+	return 1
+}
+
+// Vector accumulates wind directions, weighted by speed, to find the
+// dominant direction.
+type Vector struct {
+	X, Y float64
+}
+
+func (v *Vector) Add(dir float64, weight float64) {
+	rad := dir * math.Pi / 180.0
+	v.X += weight * math.Sin(rad)
+	v.Y += weight * math.Cos(rad)
+}
+
+// Direction returns the dominant direction in degrees, or false if there was
+// no wind.
+func (v Vector) Direction() (float64, bool) {
+	if math.Hypot(v.X, v.Y) < 1e-9 {
+		return 0, false
+	}
+	dir := math.Atan2(v.X, v.Y) * 180.0 / math.Pi
+	if dir < 0 {
+		dir += 360.0
+	}
+	return dir, true
+}
+
+// Day holds the summaries used for one day of a report. Missing summaries are
+// nil.
+type Day struct {
+	Date        time.Time
+	Temperature *data.DailySummary
+	Rain        *data.DailySummary
+	Wind        *data.DailySummary
+	Gust        *data.DailySummary
+	Direction   Vector
+}
+
+// NOAA generates NOAA-style climatological summaries.
+type NOAA struct {
+	Name      string
+	Elevation float64 // meters
+	Location  *time.Location
+	Units     Units
+	// HeatingBase and CoolingBase are the degree day base temperatures, in
+	// Celsius.
+	HeatingBase float64
+	CoolingBase float64
+
+	// Temperature has no default sensor, as indoor and outdoor sensors
+	// both measure it, and must be set.
+	Temperature Series
+	Rain        Series // cumulative, the daily total is used
+	Wind        Series // average wind speed
+	Gust        Series
+	Direction   Series
+}
+
+func NewNOAA(location *time.Location) *NOAA {
+	all := []data.SensorID{{ID: "%", Channel: -1}}
+	return &NOAA{
+		Location:    location,
+		Units:       Units{"C", "mm", "m/s"},
+		HeatingBase: 18.3,
+		CoolingBase: 18.3,
+		Temperature: Series{Key: "Temperature"},
+		Rain:        Series{"RainTotal", all},
+		Wind:        Series{"AverageWind", all},
+		Gust:        Series{"CurrentWind", all},
+		Direction:   Series{"WindDir", all},
+	}
+}
+
+// Days loads the days from first to last inclusive.
+func (n *NOAA) Days(store Store, first time.Time, last time.Time) ([]Day, error) {
+	first = time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, n.Location)
+	last = time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, n.Location)
+	var days []Day
+	index := make(map[string]int)
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		index[d.Format("2006-01-02")] = len(days)
+		days = append(days, Day{Date: d})
+	}
+
+	summaries, err := store.DailySummaries(first.Format("2006-01-02"), last.Format("2006-01-02"), "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range summaries {
+		s := &summaries[i]
+		j, ok := index[s.Day]
+		if !ok {
+			continue
+		}
+		day := &days[j]
+		for _, field := range []struct {
+			series Series
+			dest   **data.DailySummary
+		}{
+			{n.Temperature, &day.Temperature},
+			{n.Rain, &day.Rain},
+			{n.Wind, &day.Wind},
+			{n.Gust, &day.Gust},
+		} {
+			if *field.dest == nil && s.Key == field.series.Key && field.series.Match(s.SensorKey) {
+				*field.dest = s
+			}
+		}
+	}
+
+	start := first.Unix() - 1
+	end := last.AddDate(0, 0, 1).Unix()
+	speeds := make(map[int64]float64)
+	if err := n.rows(store, n.Wind, start, end, func(row data.Row) {
+		speeds[row.Timestamp] = row.Avg
+	}); err != nil {
+		return nil, err
+	}
+	err = n.rows(store, n.Direction, start, end, func(row data.Row) {
+		weight := 1.0
+		if len(speeds) > 0 {
+			weight = speeds[row.Timestamp]
+		}
+		day := time.Unix(row.Timestamp, 0).In(n.Location).Format("2006-01-02")
+		if i, ok := index[day]; ok {
+			days[i].Direction.Add(row.Avg, weight)
+		}
+	})
+	return days, err
+}
+
+func (n *NOAA) rows(store Store, series Series, start int64, end int64, fn func(data.Row)) error {
+	for _, id := range series.Sensors {
+		rows := store.QueryRows(start, series.Key, id)
+		if rows == nil {
+			return errors.New("reports: unable to query " + series.Key)
+		}
+		for row := range rows {
+			if row.Timestamp < end {
+				fn(row)
+			}
+		}
+	}
+	return nil
+}
+
+// summary holds the totals and extremes over a set of days, in the report's
+// units.
+type summary struct {
+	tempDays                  int
+	meanSum, highSum, lowSum  float64
+	high, low                 float64
+	highTime, lowTime         int64
+	heat, cool                float64
+	rainDays                  int
+	rain, maxRain             float64
+	maxRainDay                time.Time
+	windDays                  int
+	windSum                   float64
+	gustDays                  int
+	gust                      float64
+	gustTime                  int64
+	dir                       Vector
+	dayMean, dayHeat, dayCool float64 // of the last day added
+}
+
+func (n *NOAA) add(s *summary, day Day) {
+	if t := day.Temperature; t != nil && t.MeanCount > 0 {
+		mean := n.Units.temperature(t.Mean())
+		high := n.Units.temperature(t.High)
+		low := n.Units.temperature(t.Low)
+		s.dayMean = mean
+		s.dayHeat = math.Max(0, n.Units.temperature(n.HeatingBase)-mean)
+		s.dayCool = math.Max(0, mean-n.Units.temperature(n.CoolingBase))
+		if s.tempDays == 0 || high > s.high {
+			s.high, s.highTime = high, t.HighTime
+		}
+		if s.tempDays == 0 || low < s.low {
+			s.low, s.lowTime = low, t.LowTime
+		}
+		s.meanSum += mean
+		s.highSum += high
+		s.lowSum += low
+		s.heat += s.dayHeat
+		s.cool += s.dayCool
+		s.tempDays++
+	}
+	if r := day.Rain; r != nil {
+		rain := n.Units.rain(r.Total)
+		if s.rainDays == 0 || rain > s.maxRain {
+			s.maxRain, s.maxRainDay = rain, day.Date
+		}
+		s.rain += rain
+		s.rainDays++
+	}
+	if w := day.Wind; w != nil && w.MeanCount > 0 {
+		s.windSum += n.Units.wind(w.Mean())
+		s.windDays++
+	}
+	if g := day.Gust; g != nil {
+		gust := n.Units.wind(g.High)
+		if s.gustDays == 0 || gust > s.gust {
+			s.gust, s.gustTime = gust, g.HighTime
+		}
+		s.gustDays++
+	}
+	s.dir.X += day.Direction.X
+	s.dir.Y += day.Direction.Y
+}
+
+func num(v float64, decimals int, ok bool) string {
+	if !ok {
+		return "---"
+	}
+	return fmt.Sprintf("%.*f", decimals, v)
+}
+
+func (n *NOAA) when(t int64, layout string, ok bool) string {
+	if !ok {
+		return "---"
+	}
+	return time.Unix(t, 0).In(n.Location).Format(layout)
+}
+
+func direction(v Vector) string {
+	dir, ok := v.Direction()
+	return num(dir, 0, ok)
+}
+
+type column struct {
+	width  int
+	header [3]string
+}
+
+type table []column
+
+func (t table) row(w io.Writer, cells ...string) {
+	var line strings.Builder
+	for i, c := range t {
+		if i > 0 {
+			line.WriteByte(' ')
+		}
+		fmt.Fprintf(&line, "%*s", c.width, cells[i])
+	}
+	fmt.Fprintln(w, strings.TrimRight(line.String(), " "))
+}
+
+func (t table) rule(w io.Writer) {
+	width := len(t) - 1
+	for _, c := range t {
+		width += c.width
+	}
+	fmt.Fprintln(w, strings.Repeat("-", width))
+}
+
+func (t table) header(w io.Writer) {
+	for i := 0; i < 3; i++ {
+		cells := make([]string, len(t))
+		for j, c := range t {
+			cells[j] = c.header[i]
+		}
+		t.row(w, cells...)
+	}
+	t.rule(w)
+}
+
+func (n *NOAA) title(w io.Writer, title string) {
+	fmt.Fprintf(w, "%20s%s\n\n", "", title)
+	if n.Name != "" {
+		fmt.Fprintf(w, "NAME: %s\n", n.Name)
+	}
+	fmt.Fprintf(w, "ELEV: %.0f m\n\n", n.Elevation)
+	fmt.Fprintf(w, "%20sTEMPERATURE (%s), RAIN (%s), WIND SPEED (%s)\n\n", "", n.Units.Temperature, n.Units.Rain, n.Units.Wind)
+}
+
+var monthTable = table{
+	{3, [3]string{"", "", "DAY"}},
+	{6, [3]string{"", "MEAN", "TEMP"}},
+	{6, [3]string{"", "", "HIGH"}},
+	{5, [3]string{"", "", "TIME"}},
+	{6, [3]string{"", "", "LOW"}},
+	{5, [3]string{"", "", "TIME"}},
+	{6, [3]string{"HEAT", "DEG", "DAYS"}},
+	{6, [3]string{"COOL", "DEG", "DAYS"}},
+	{7, [3]string{"", "", "RAIN"}},
+	{6, [3]string{"AVG", "WIND", "SPEED"}},
+	{6, [3]string{"", "HIGH", "GUST"}},
+	{5, [3]string{"", "", "TIME"}},
+	{4, [3]string{"", "DOM", "DIR"}},
+}
+
+func (n *NOAA) validate() error {
+	if len(n.Temperature.Sensors) == 0 {
+		return errors.New("no temperature sensor selected")
+	}
+	return n.Units.validate()
+}
+
+// Month writes the monthly summary for the month containing month.
+func (n *NOAA) Month(w io.Writer, store Store, month time.Time) error {
+	if err := n.validate(); err != nil {
+		return err
+	}
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, n.Location)
+	days, err := n.Days(store, first, first.AddDate(0, 1, -1))
+	if err != nil {
+		return err
+	}
+
+	n.title(w, "MONTHLY CLIMATOLOGICAL SUMMARY for "+first.Format("Jan 2006"))
+	monthTable.header(w)
+	rd := n.Units.rainDecimals()
+	var total summary
+	for _, day := range days {
+		var s summary
+		n.add(&s, day)
+		n.add(&total, day)
+		temp, rain, wind, gust := s.tempDays > 0, s.rainDays > 0, s.windDays > 0, s.gustDays > 0
+		monthTable.row(w,
+			day.Date.Format("02"),
+			num(s.dayMean, 1, temp),
+			num(s.high, 1, temp),
+			n.when(s.highTime, "15:04", temp),
+			num(s.low, 1, temp),
+			n.when(s.lowTime, "15:04", temp),
+			num(s.dayHeat, 1, temp),
+			num(s.dayCool, 1, temp),
+			num(s.rain, rd, rain),
+			num(s.windSum, 1, wind),
+			num(s.gust, 1, gust),
+			n.when(s.gustTime, "15:04", gust),
+			direction(day.Direction),
+		)
+	}
+	monthTable.rule(w)
+
+	temp, rain, wind, gust := total.tempDays > 0, total.rainDays > 0, total.windDays > 0, total.gustDays > 0
+	monthTable.row(w,
+		"",
+		num(total.meanSum/float64(total.tempDays), 1, temp),
+		num(total.high, 1, temp),
+		n.when(total.highTime, "02", temp),
+		num(total.low, 1, temp),
+		n.when(total.lowTime, "02", temp),
+		num(total.heat, 1, temp),
+		num(total.cool, 1, temp),
+		num(total.rain, rd, rain),
+		num(total.windSum/float64(total.windDays), 1, wind),
+		num(total.gust, 1, gust),
+		n.when(total.gustTime, "02", gust),
+		direction(total.dir),
+	)
+	return nil
+}
+
+var yearTable = table{
+	{4, [3]string{"", "", "YR"}},
+	{2, [3]string{"", "", "MO"}},
+	{6, [3]string{"", "MEAN", "HIGH"}},
+	{6, [3]string{"", "MEAN", "LOW"}},
+	{6, [3]string{"", "", "MEAN"}},
+	{6, [3]string{"HEAT", "DEG", "DAYS"}},
+	{6, [3]string{"COOL", "DEG", "DAYS"}},
+	{6, [3]string{"", "", "HIGH"}},
+	{5, [3]string{"", "", "DATE"}},
+	{6, [3]string{"", "", "LOW"}},
+	{5, [3]string{"", "", "DATE"}},
+	{8, [3]string{"", "", "RAIN"}},
+	{6, [3]string{"MAX", "DAY", "RAIN"}},
+	{5, [3]string{"", "", "DATE"}},
+	{6, [3]string{"AVG", "WIND", "SPEED"}},
+	{6, [3]string{"", "HIGH", "GUST"}},
+	{5, [3]string{"", "", "DATE"}},
+	{4, [3]string{"", "DOM", "DIR"}},
+}
+
+func (n *NOAA) yearRow(w io.Writer, year string, month string, s summary) {
+	temp, rain, wind, gust := s.tempDays > 0, s.rainDays > 0, s.windDays > 0, s.gustDays > 0
+	rainDay := "---"
+	if rain {
+		rainDay = s.maxRainDay.Format("01-02")
+	}
+	rd := n.Units.rainDecimals()
+	yearTable.row(w,
+		year,
+		month,
+		num(s.highSum/float64(s.tempDays), 1, temp),
+		num(s.lowSum/float64(s.tempDays), 1, temp),
+		num(s.meanSum/float64(s.tempDays), 1, temp),
+		num(s.heat, 1, temp),
+		num(s.cool, 1, temp),
+		num(s.high, 1, temp),
+		n.when(s.highTime, "01-02", temp),
+		num(s.low, 1, temp),
+		n.when(s.lowTime, "01-02", temp),
+		num(s.rain, rd, rain),
+		num(s.maxRain, rd, rain),
+		rainDay,
+		num(s.windSum/float64(s.windDays), 1, wind),
+		num(s.gust, 1, gust),
+		n.when(s.gustTime, "01-02", gust),
+		direction(s.dir),
+	)
+}
+
+// Year writes the yearly summary for the given year.
+func (n *NOAA) Year(w io.Writer, store Store, year int) error {
+	if err := n.validate(); err != nil {
+		return err
+	}
+	first := time.Date(year, time.January, 1, 0, 0, 0, 0, n.Location)
+	days, err := n.Days(store, first, first.AddDate(1, 0, -1))
+	if err != nil {
+		return err
+	}
+
+	n.title(w, fmt.Sprintf("CLIMATOLOGICAL SUMMARY for year %d", year))
+	yearTable.header(w)
+	var months [12]summary
+	var total summary
+	for _, day := range days {
+		n.add(&months[day.Date.Month()-1], day)
+		n.add(&total, day)
+	}
+	for i, s := range months {
+		n.yearRow(w, fmt.Sprint(year), fmt.Sprintf("%02d", i+1), s)
+	}
+	yearTable.rule(w)
+	n.yearRow(w, "", "", total)
+	return nil
+}
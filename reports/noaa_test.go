@@ -0,0 +1,160 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package reports
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/records"
+	"github.com/spf13/viper"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+var (
+	outdoor = data.SensorKey{ID: "OS3:1D20", Channel: 1, Serial: "A4"}
+	indoor  = data.SensorKey{ID: "BMP", Channel: 0, Serial: "0"}
+	wind    = data.SensorKey{ID: "VN1:6D27", Channel: 1, Serial: "00"}
+)
+
+// fixtureDatabase fills a database with hourly samples from Aug 30 to Sep 4
+// 2026, with the daily summaries maintained by a records tracker as the
+// aggregator does.
+func fixtureDatabase(t *testing.T) *data.Database {
+	viper.Set("dbDriver", "sqlite3")
+	viper.Set("database", filepath.Join(t.TempDir(), "gowx.db"))
+	db, err := data.OpenDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+
+	tracker := records.NewTracker(db, time.UTC)
+	start := time.Date(2026, 8, 30, 0, 0, 0, 0, time.UTC)
+	rain := 100.0
+	for h := 0; h < 6*24; h++ {
+		ts := start.Add(time.Duration(h) * time.Hour).Unix()
+		day, hour := float64(h/24), float64(h%24)
+		temp := 14 + day - 6*math.Cos((hour-3)*math.Pi/12)
+		speed := 2 + day/2 + 2*math.Sin(hour*math.Pi/24)
+		dir := math.Mod(200+40*day+5*hour, 360)
+		if h/24 == 2 && hour >= 6 && hour < 12 {
+			rain += 1.5
+		}
+		if h == 4*24 {
+			rain = 0 // counter reset
+		}
+		agg := func(sensor data.SensorKey, key string, min, max, avg float64) records.Aggregate {
+			return records.Aggregate{Timestamp: ts, Sensor: sensor, Key: key, Min: min, Max: max, Avg: avg}
+		}
+		aggs := []records.Aggregate{
+			agg(outdoor, "Temperature", temp-0.3, temp+0.4, temp),
+			agg(indoor, "Temperature", 21, 22, 21.5),
+			agg(wind, "AverageWind", speed, speed, speed),
+			agg(wind, "CurrentWind", speed-1, speed*1.8, speed),
+			agg(wind, "WindDir", dir, dir, dir),
+			agg(wind, "RainTotal", rain, rain, rain),
+		}
+		for _, a := range aggs {
+			if err := db.InsertRow(a.Timestamp, a.Sensor.ID, a.Sensor.Channel, a.Sensor.Serial, a.Key, a.Min, a.Max, a.Avg); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tracker.Update(aggs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func testNOAA() *NOAA {
+	n := NewNOAA(time.UTC)
+	n.Name = "Test Station"
+	n.Elevation = 250
+	n.Temperature.Sensors = []data.SensorID{{ID: outdoor.ID, Channel: outdoor.Channel}}
+	return n
+}
+
+func golden(t *testing.T, name string, got []byte) {
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match, got:\n%s", name, got)
+	}
+}
+
+func TestNOAAMonth(t *testing.T) {
+	db := fixtureDatabase(t)
+	var buf bytes.Buffer
+	if err := testNOAA().Month(&buf, db, time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "noaa-2026-09.txt", buf.Bytes())
+}
+
+func TestNOAAMonthImperial(t *testing.T) {
+	db := fixtureDatabase(t)
+	n := testNOAA()
+	n.Units = Units{"F", "in", "mph"}
+	var buf bytes.Buffer
+	if err := n.Month(&buf, db, time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "noaa-2026-09-imperial.txt", buf.Bytes())
+}
+
+func TestNOAAYear(t *testing.T) {
+	db := fixtureDatabase(t)
+	var buf bytes.Buffer
+	if err := testNOAA().Year(&buf, db, 2026); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "noaa-2026.txt", buf.Bytes())
+}
+
+func TestNOAAUnits(t *testing.T) {
+	n := testNOAA()
+	n.Units.Wind = "furlongs"
+	var buf bytes.Buffer
+	if err := n.Month(&buf, nil, time.Now()); err == nil {
+		t.Error("Unknown units should give an error")
+	}
+}
+
+func TestNOAADefaults(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewNOAA(time.UTC).Month(&buf, fixtureDatabase(t), time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Error("A report without a temperature sensor should give an error", buf.String())
+	}
+}
+
+func TestVectorDirection(t *testing.T) {
+	var v Vector
+	if _, ok := v.Direction(); ok {
+		t.Error("Calm should have no direction")
+	}
+	v.Add(350, 1)
+	v.Add(20, 1)
+	if dir, _ := v.Direction(); math.Abs(dir-5) > 1e-9 {
+		t.Error("Unexpected direction", dir)
+	}
+	v.Add(180, 0)
+	if dir, _ := v.Direction(); math.Abs(dir-5) > 1e-9 {
+		t.Error("Calm samples should not change the direction", dir)
+	}
+}
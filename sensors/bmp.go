@@ -5,7 +5,6 @@ package sensors
 import (
 	"github.com/geoffholden/gowx/data"
 	jww "github.com/spf13/jwalterweatherman"
-	"github.com/spf13/viper"
 	"math"
 	"strconv"
 	"strings"
@@ -25,6 +24,10 @@ type BMP struct {
 	p0, p1, p2     float64
 
 	calibrated bool
+
+	// elevation is the station's elevation in meters, for the pressure
+	// reduced to sea level.
+	elevation float64
 }
 
 func init() {
@@ -70,7 +73,7 @@ func (b *BMP) Parse(key string, input string) data.SensorData {
 		val, err := strconv.ParseUint(input, 16, 16)
 		if err != nil {
 			jww.ERROR.Println(err)
-			return data.SensorData{}
+			return parseError(key)
 		}
 		b.cal[key[2]-'0'] = int32(val)
 	case "BMA":
@@ -80,14 +83,14 @@ func (b *BMP) Parse(key string, input string) data.SensorData {
 		val, err := strconv.ParseInt(input, 16, 16)
 		if err != nil {
 			jww.ERROR.Println(err)
-			return data.SensorData{}
+			return parseError(key)
 		}
 		b.avgCount = int32(val)
 	case "BMO":
 		val, err := strconv.ParseInt(input, 16, 16)
 		if err != nil {
 			jww.ERROR.Println(err)
-			return data.SensorData{}
+			return parseError(key)
 		}
 		b.ossMode = int32(val)
 	case "BMX":
@@ -110,7 +113,7 @@ func (b *BMP) Parse(key string, input string) data.SensorData {
 		z := (float64(p) - x) / y
 		bmpPressure := (b.p2*z+b.p1)*z + b.p0
 
-		elevationAdj := float64(viper.GetInt("elevation")) * 12.0 / 100.0 // 12 hPa/100m
+		elevationAdj := b.elevation * 12.0 / 100.0 // 12 hPa/100m
 		bmpPressure += elevationAdj
 
 		var result data.SensorData
@@ -126,6 +129,11 @@ func (b *BMP) Parse(key string, input string) data.SensorData {
 	return data.SensorData{}
 }
 
+// SetElevation sets the elevation of the station, in meters.
+func (b *BMP) SetElevation(meters float64) {
+	b.elevation = meters
+}
+
 func (b *BMP) updateCal() {
 	c3 := 160.0 * math.Pow(2, -15) * float64(b.cal[2])
 	c4 := 0.001 * math.Pow(2, -15) * float64(b.cal[3])
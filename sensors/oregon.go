@@ -20,11 +20,10 @@ func init() {
 }
 
 func (d *Oregon) Parse(key string, input string) data.SensorData {
-	var emptyResult data.SensorData
 	channel, err := strconv.ParseUint(input[4:5], 16, 8)
 	if err != nil {
 		jww.ERROR.Println(err)
-		return data.SensorData{}
+		return parseError(key)
 	}
 	result := data.SensorData{
 		TimeStamp: time.Now().UTC(),
@@ -37,7 +36,7 @@ func (d *Oregon) Parse(key string, input string) data.SensorData {
 	if key == "OS3" {
 		// checksum validation
 		if len(input) < 2 {
-			return emptyResult
+			return sensorError(key, result)
 		}
 		sum := int8(0)
 		for _, b := range input[0 : len(input)-2] {
@@ -51,14 +50,14 @@ func (d *Oregon) Parse(key string, input string) data.SensorData {
 
 		x, _ := strconv.ParseInt(string(provided), 16, 8)
 		if int8(x) != sum {
-			return emptyResult
+			return sensorError(key, result)
 		}
 	}
 
 	switch input[0:4] {
 	case "1D20", "F824", "F8B4":
 		if len(input) != 17 {
-			return emptyResult
+			return sensorError(key, result)
 		}
 		temperature := float64(input[10]-'0') * 10.0
 		temperature += float64(input[9]-'0') * 1.0
@@ -73,7 +72,7 @@ func (d *Oregon) Parse(key string, input string) data.SensorData {
 		result.Data["Humidity"] = humidity
 	case "EC40", "C844":
 		if len(input) != 14 {
-			return emptyResult
+			return sensorError(key, result)
 		}
 		temperature := float64(input[10]-'0') * 10.0
 		temperature += float64(input[9]-'0') * 1.0
@@ -85,7 +84,7 @@ func (d *Oregon) Parse(key string, input string) data.SensorData {
 		result.Data["Temperature"] = temperature
 	case "EC70":
 		if len(input) != 14 {
-			return emptyResult
+			return sensorError(key, result)
 		}
 		uv := (input[9] - '0') * 10
 		uv += (input[8] - '0') * 1
@@ -93,7 +92,7 @@ func (d *Oregon) Parse(key string, input string) data.SensorData {
 		result.Data["UV"] = float64(uv)
 	case "D874":
 		if len(input) != 15 {
-			return emptyResult
+			return sensorError(key, result)
 		}
 		uv := (input[12] - '0') * 10
 		uv += (input[11] - '0') * 1
@@ -101,7 +100,7 @@ func (d *Oregon) Parse(key string, input string) data.SensorData {
 		result.Data["UV"] = float64(uv)
 	case "1984", "1994":
 		if len(input) != 19 {
-			return emptyResult
+			return sensorError(key, result)
 		}
 		dir, _ := strconv.ParseInt(input[8:9], 16, 8)
 		direction := float64(dir) * 22.5
@@ -118,7 +117,7 @@ func (d *Oregon) Parse(key string, input string) data.SensorData {
 		result.Data["AverageWind"] = average
 	case "2914":
 		if len(input) != 20 {
-			return emptyResult
+			return sensorError(key, result)
 		}
 		rate := float64(input[11]-'0') * 10.0
 		rate += float64(input[10]-'0') * 1.0
@@ -138,7 +137,7 @@ func (d *Oregon) Parse(key string, input string) data.SensorData {
 		result.Data["RainTotal"] = total
 	case "2D10":
 		if len(input) != 18 {
-			return emptyResult
+			return sensorError(key, result)
 		}
 		rate := float64(input[10]-'0') * 10.0
 		rate += float64(input[9]-'0') * 1.0
@@ -154,7 +153,7 @@ func (d *Oregon) Parse(key string, input string) data.SensorData {
 		result.Data["RainTotal"] = total
 	case "5D60":
 		if len(input) != 20 {
-			return emptyResult
+			return sensorError(key, result)
 		}
 		temperature := float64(input[10]-'0') * 10.0
 		temperature += float64(input[9]-'0') * 1.0
@@ -3,8 +3,11 @@
 package sensors
 
 import (
+	"bytes"
 	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/metrics"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -54,4 +57,18 @@ func TestOregonBadChecksum(t *testing.T) {
 	if !reflect.DeepEqual(res, empty) {
 		t.Error("SensorResult should be empty")
 	}
+	o.Parse("OS3", "1D20X85C480882835")
+
+	// The checksum failure is counted against its sensor, the unparsed
+	// header against the family only.
+	var buf bytes.Buffer
+	metrics.Default.Write(&buf)
+	for _, line := range []string{
+		`gowx_parse_errors_total{sensor="OS3",id="OS3:1D20",channel="4"}`,
+		`gowx_parse_errors_total{sensor="OS3",id="",channel=""}`,
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Error("Missing metric", line)
+		}
+	}
 }
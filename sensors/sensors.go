@@ -3,14 +3,44 @@
 package sensors
 
 import (
+	"strconv"
+
 	"github.com/geoffholden/gowx/data"
+	"github.com/geoffholden/gowx/metrics"
 )
 
 var Sensors map[string]data.SensorParser
 
+var parseErrors = metrics.NewCounter("gowx_parse_errors_total", "Number of sensor messages that could not be parsed.", "sensor", "id", "channel")
+
+// parseError records a message that failed to parse before the sensor
+// sending it was known, and returns an empty result.
+func parseError(key string) data.SensorData {
+	parseErrors.Inc(key, "", "")
+	return data.SensorData{}
+}
+
+// sensorError records a message from a known sensor that failed to parse,
+// such as one failing its checksum, and returns an empty result. d is the
+// result so far, with the ID and channel from the message header.
+func sensorError(key string, d data.SensorData) data.SensorData {
+	parseErrors.Inc(key, d.ID, strconv.Itoa(d.Channel))
+	return data.SensorData{}
+}
+
 func RegisterSensor(key string, sensor data.SensorParser) {
 	if nil == Sensors {
 		Sensors = make(map[string]data.SensorParser)
 	}
 	Sensors[key] = sensor
 }
+
+// SetElevation sets the station's elevation, in meters, on the sensors that
+// correct for it.
+func SetElevation(meters float64) {
+	for _, sensor := range Sensors {
+		if s, ok := sensor.(interface{ SetElevation(float64) }); ok {
+			s.SetElevation(meters)
+		}
+	}
+}
@@ -20,33 +20,23 @@ func init() {
 }
 
 func (d *VN1TX) Parse(key string, input string) data.SensorData {
-	var emptyResult data.SensorData
 
 	message := make([]uint32, len([]rune(input)))
 	for i := range message {
 		v, err := strconv.ParseUint(input[i:i+1], 16, 8)
 		if err != nil {
 			jww.ERROR.Println(err)
-			return data.SensorData{}
+			return parseError(key)
 		}
 		message[i] = uint32(v)
 	}
 	if len(message) != 17 {
-		return emptyResult
+		return parseError(key)
 	}
 
 	channel := 4 - (message[0] >> 2)
 	msgId := message[5]
 
-	var sum uint8
-	for k := 0; k < 14; k += 2 {
-		sum += uint8((message[k] << 4) | message[k+1])
-	}
-
-	if sum != uint8((message[14]<<4)|message[15]) {
-		return emptyResult
-	}
-
 	result := data.SensorData{
 		TimeStamp: time.Now().UTC(),
 		ID:        key + ":" + input[1:5],
@@ -55,6 +45,15 @@ func (d *VN1TX) Parse(key string, input string) data.SensorData {
 		Data:      make(map[string]float64),
 	}
 
+	var sum uint8
+	for k := 0; k < 14; k += 2 {
+		sum += uint8((message[k] << 4) | message[k+1])
+	}
+
+	if sum != uint8((message[14]<<4)|message[15]) {
+		return sensorError(key, result)
+	}
+
 	wspd := float64(((message[6] & 0x01) << 7) | (message[7] << 3) | (message[8] & 0x07))
 	if wspd > 0.0 {
 		wspd = wspd*0.8278 + 1.00
@@ -81,7 +80,7 @@ func (d *VN1TX) Parse(key string, input string) data.SensorData {
 		result.Data["Humidity"] = float64(RH)
 	default:
 		jww.ERROR.Println("Invalid Message ID", msgId)
-		return data.SensorData{}
+		return sensorError(key, result)
 	}
 	return result
 }
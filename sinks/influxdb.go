@@ -0,0 +1,124 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package sinks
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// InfluxDB writes points using the InfluxDB line protocol over HTTP. The URL
+// is the full write endpoint, e.g. http://localhost:8086/write?db=gowx for
+// InfluxDB 1.x or http://localhost:8086/api/v2/write?org=home&bucket=gowx
+// for 2.x.
+type InfluxDB struct {
+	URL         string
+	Token       string
+	Username    string
+	Password    string
+	Measurement string
+	Tags        TagMap
+	Client      *http.Client
+}
+
+func init() {
+	RegisterSinkType("influxdb", newInfluxDB)
+}
+
+func newInfluxDB(config *viper.Viper) (Sink, error) {
+	if config.GetString("url") == "" {
+		return nil, errors.New("url is required")
+	}
+	return &InfluxDB{
+		URL:         config.GetString("url"),
+		Token:       config.GetString("token"),
+		Username:    config.GetString("username"),
+		Password:    config.GetString("password"),
+		Measurement: config.GetString("measurement"),
+		Tags:        newTagMap(config),
+		Client:      &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxTagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// Line formats a point in line protocol. The measurement is the sample key
+// unless a fixed measurement name has been configured.
+func (influx *InfluxDB) Line(p Point) string {
+	measurement := influx.Measurement
+	if measurement == "" {
+		measurement = p.Key
+	}
+
+	var line strings.Builder
+	line.WriteString(influxMeasurementEscaper.Replace(measurement))
+	for _, tag := range influx.Tags.Tags(p) {
+		if tag[1] == "" {
+			continue
+		}
+		line.WriteString(",")
+		line.WriteString(influxTagEscaper.Replace(tag[0]))
+		line.WriteString("=")
+		line.WriteString(influxTagEscaper.Replace(tag[1]))
+	}
+	fmt.Fprintf(&line, " min=%s,max=%s,avg=%s %d",
+		strconv.FormatFloat(p.Min, 'g', -1, 64),
+		strconv.FormatFloat(p.Max, 'g', -1, 64),
+		strconv.FormatFloat(p.Avg, 'g', -1, 64),
+		p.Timestamp)
+	return line.String()
+}
+
+func (influx *InfluxDB) Write(points []Point) error {
+	u, err := url.Parse(influx.URL)
+	if err != nil {
+		return err
+	}
+	query := u.Query()
+	query.Set("precision", "s")
+	u.RawQuery = query.Encode()
+
+	body := new(bytes.Buffer)
+	for _, p := range points {
+		body.WriteString(influx.Line(p))
+		body.WriteString("\n")
+	}
+
+	req, err := http.NewRequest("POST", u.String(), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if influx.Token != "" {
+		req.Header.Set("Authorization", "Token "+influx.Token)
+	} else if influx.Username != "" {
+		req.SetBasicAuth(influx.Username, influx.Password)
+	}
+
+	client := influx.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("influxdb: %s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package sinks
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInfluxDBLine(t *testing.T) {
+	influx := InfluxDB{Tags: defaultTags}
+	line := influx.Line(Point{1500000000, "OS3:1D20", 1, "A4", "Temperature", -1.5, 2, 0.25, ""})
+	if line != "Temperature,channel=1,id=OS3:1D20,serial=A4 min=-1.5,max=2,avg=0.25 1500000000" {
+		t.Error("Unexpected line", line)
+	}
+	line = influx.Line(Point{1500000000, "OS3:1D20", 1, "A4", "Temperature", -1.5, 2, 0.25, "cabin"})
+	if line != "Temperature,channel=1,id=OS3:1D20,serial=A4,station=cabin min=-1.5,max=2,avg=0.25 1500000000" {
+		t.Error("Unexpected line", line)
+	}
+
+	influx = InfluxDB{Measurement: "weather station", Tags: TagMap{"id": "sensor", "key": "key"}}
+	line = influx.Line(Point{1500000000, "a,b", 0, "0", "Temperature", 1, 1, 1, ""})
+	if line != `weather\ station,key=Temperature,sensor=a\,b min=1,max=1,avg=1 1500000000` {
+		t.Error("Unexpected line", line)
+	}
+}
+
+func TestInfluxDBWrite(t *testing.T) {
+	var body, query, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		query = r.URL.RawQuery
+		auth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	influx := InfluxDB{URL: server.URL + "/write?db=gowx", Token: "secret", Tags: TagMap{"id": "id"}}
+	err := influx.Write([]Point{
+		{1500000000, "BMP", 0, "0", "Pressure", 1000, 1001, 1000.5, ""},
+		{1500000000, "BMP", 0, "0", "Temperature", 20, 21, 20.5, ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "Pressure,id=BMP min=1000,max=1001,avg=1000.5 1500000000\nTemperature,id=BMP min=20,max=21,avg=20.5 1500000000\n" {
+		t.Error("Unexpected body", body)
+	}
+	if query != "db=gowx&precision=s" {
+		t.Error("Unexpected query", query)
+	}
+	if auth != "Token secret" {
+		t.Error("Unexpected authorization", auth)
+	}
+}
+
+func TestInfluxDBError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "database not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	influx := InfluxDB{URL: server.URL + "/write?db=gowx", Tags: defaultTags}
+	if err := influx.Write([]Point{{Key: "Temperature"}}); err == nil {
+		t.Error("Expected an error")
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package sinks
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Prometheus pushes points to a Prometheus remote-write endpoint. Each point
+// becomes three series, <namespace>_<key>_min, _max and _avg.
+type Prometheus struct {
+	URL       string
+	Namespace string
+	Username  string
+	Password  string
+	Tags      TagMap
+	Client    *http.Client
+}
+
+func init() {
+	RegisterSinkType("prometheus", newPrometheus)
+}
+
+func newPrometheus(config *viper.Viper) (Sink, error) {
+	if config.GetString("url") == "" {
+		return nil, errors.New("url is required")
+	}
+	namespace := "gowx"
+	if config.IsSet("namespace") {
+		namespace = config.GetString("namespace")
+	}
+	return &Prometheus{
+		URL:       config.GetString("url"),
+		Namespace: namespace,
+		Username:  config.GetString("username"),
+		Password:  config.GetString("password"),
+		Tags:      newTagMap(config),
+		Client:    &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+var (
+	metricWordBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+	metricInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+)
+
+// MetricName converts a sample key such as "RainTotal" into a metric name
+// such as "gowx_rain_total".
+func MetricName(namespace string, key string) string {
+	name := metricWordBoundary.ReplaceAllString(key, "${1}_${2}")
+	name = strings.ToLower(metricInvalidChars.ReplaceAllString(name, "_"))
+	if namespace != "" {
+		name = namespace + "_" + name
+	}
+	return name
+}
+
+func (prom *Prometheus) Write(points []Point) error {
+	var request []byte
+	for _, p := range points {
+		name := MetricName(prom.Namespace, p.Key)
+		tags := prom.Tags.Tags(p)
+		for _, stat := range []struct {
+			suffix string
+			value  float64
+		}{{"_avg", p.Avg}, {"_max", p.Max}, {"_min", p.Min}} {
+			// WriteRequest.timeseries = 1
+			request = appendProtoBytes(request, 1, encodeTimeSeries(name+stat.suffix, tags, stat.value, p.Timestamp*1000))
+		}
+	}
+
+	req, err := http.NewRequest("POST", prom.URL, bytes.NewReader(snappyEncode(request)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if prom.Username != "" {
+		req.SetBasicAuth(prom.Username, prom.Password)
+	}
+
+	client := prom.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("prometheus: %s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// encodeTimeSeries encodes a single-sample prometheus.TimeSeries message.
+// Remote write requires the labels sorted by name, __name__ included, and
+// labels with empty values are left out as Prometheus drops them.
+func encodeTimeSeries(name string, tags [][2]string, value float64, timestamp int64) []byte {
+	labels := [][2]string{{"__name__", name}}
+	for _, tag := range tags {
+		if tag[1] != "" {
+			labels = append(labels, tag)
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i][0] < labels[j][0]
+	})
+
+	var series []byte
+	// TimeSeries.labels = 1
+	for _, label := range labels {
+		series = appendProtoBytes(series, 1, encodeLabel(label[0], label[1]))
+	}
+
+	// Sample.value = 1 (double), Sample.timestamp = 2 (int64)
+	var sample []byte
+	sample = append(sample, 1<<3|1)
+	sample = binary.LittleEndian.AppendUint64(sample, math.Float64bits(value))
+	sample = append(sample, 2<<3|0)
+	sample = binary.AppendUvarint(sample, uint64(timestamp))
+
+	// TimeSeries.samples = 2
+	return appendProtoBytes(series, 2, sample)
+}
+
+func encodeLabel(name string, value string) []byte {
+	// Label.name = 1, Label.value = 2
+	label := appendProtoBytes(nil, 1, []byte(name))
+	return appendProtoBytes(label, 2, []byte(value))
+}
+
+func appendProtoBytes(buf []byte, field int, value []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field<<3|2))
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
+
+// snappyEncode produces a valid snappy block using only literals. The
+// payloads are small enough that compression isn't worth a dependency.
+func snappyEncode(src []byte) []byte {
+	dst := binary.AppendUvarint(nil, uint64(len(src)))
+	for len(src) > 0 {
+		n := len(src)
+		if n > 65536 {
+			n = 65536
+		}
+		switch {
+		case n-1 < 60:
+			dst = append(dst, byte(n-1)<<2)
+		case n-1 < 256:
+			dst = append(dst, 60<<2, byte(n-1))
+		default:
+			dst = append(dst, 61<<2, byte(n-1), byte((n-1)>>8))
+		}
+		dst = append(dst, src[:n]...)
+		src = src[n:]
+	}
+	return dst
+}
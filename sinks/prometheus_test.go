@@ -0,0 +1,103 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package sinks
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// snappyDecode handles the literal-only blocks produced by snappyEncode.
+func snappyDecode(t *testing.T, src []byte) []byte {
+	length, n := binary.Uvarint(src)
+	src = src[n:]
+	var dst []byte
+	for len(src) > 0 {
+		tag := src[0]
+		if tag&3 != 0 {
+			t.Fatal("Unexpected copy element")
+		}
+		var size int
+		switch tag >> 2 {
+		case 60:
+			size = int(src[1]) + 1
+			src = src[2:]
+		case 61:
+			size = int(src[1]) | int(src[2])<<8 + 1
+			src = src[3:]
+		default:
+			size = int(tag>>2) + 1
+			src = src[1:]
+		}
+		dst = append(dst, src[:size]...)
+		src = src[size:]
+	}
+	if uint64(len(dst)) != length {
+		t.Fatal("Length mismatch", len(dst), length)
+	}
+	return dst
+}
+
+func TestSnappyEncode(t *testing.T) {
+	for _, size := range []int{0, 1, 60, 61, 256, 257, 65536, 100000} {
+		src := bytes.Repeat([]byte{'x'}, size)
+		if !bytes.Equal(snappyDecode(t, snappyEncode(src)), src) {
+			t.Error("Round trip failed for size", size)
+		}
+	}
+}
+
+func TestMetricName(t *testing.T) {
+	if name := MetricName("gowx", "RainTotal"); name != "gowx_rain_total" {
+		t.Error("Unexpected name", name)
+	}
+	if name := MetricName("", "UV"); name != "uv" {
+		t.Error("Unexpected name", name)
+	}
+}
+
+func TestPrometheusWrite(t *testing.T) {
+	var body []byte
+	var encoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		encoding = r.Header.Get("Content-Encoding")
+	}))
+	defer server.Close()
+
+	prom := Prometheus{URL: server.URL, Namespace: "gowx", Tags: TagMap{"id": "id", "station": "station"}}
+	if err := prom.Write([]Point{{1500000000, "BMP", 0, "0", "Pressure", 1000, 1001, 1000.5, "cabin"}}); err != nil {
+		t.Fatal(err)
+	}
+	if encoding != "snappy" {
+		t.Error("Unexpected encoding", encoding)
+	}
+
+	request := snappyDecode(t, body)
+	expected := appendProtoBytes(nil, 1, encodeTimeSeries("gowx_pressure_avg", [][2]string{{"id", "BMP"}, {"station", "cabin"}}, 1000.5, 1500000000000))
+	if !bytes.HasPrefix(request, expected) {
+		t.Error("Unexpected request", request)
+	}
+	for _, name := range []string{"gowx_pressure_min", "gowx_pressure_max", "gowx_pressure_avg"} {
+		if !bytes.Contains(request, []byte(name)) {
+			t.Error("Missing series", name)
+		}
+	}
+}
+
+func TestEncodeTimeSeriesLabelOrder(t *testing.T) {
+	series := encodeTimeSeries("gowx_pressure_avg", [][2]string{{"A", "BMP"}, {"serial", ""}, {"z", "1"}}, 1, 0)
+	expected := appendProtoBytes(nil, 1, encodeLabel("A", "BMP"))
+	expected = appendProtoBytes(expected, 1, encodeLabel("__name__", "gowx_pressure_avg"))
+	expected = appendProtoBytes(expected, 1, encodeLabel("z", "1"))
+	if !bytes.HasPrefix(series, expected) {
+		t.Error("Unexpected labels", series)
+	}
+	if bytes.Contains(series, []byte("serial")) {
+		t.Error("Empty label not skipped", series)
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package sinks
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+)
+
+// Point is a single aggregated value, as produced by the aggregator.
+type Point struct {
+	Timestamp int64
+	ID        string
+	Channel   int
+	Serial    string
+	Key       string
+	Min       float64
+	Max       float64
+	Avg       float64
+	Station   string
+}
+
+// Sink writes aggregated points to an external time series database.
+type Sink interface {
+	Write(points []Point) error
+}
+
+// Factory creates a sink from its configuration section.
+type Factory func(config *viper.Viper) (Sink, error)
+
+var factories map[string]Factory
+
+func RegisterSinkType(name string, factory Factory) {
+	if nil == factories {
+		factories = make(map[string]Factory)
+	}
+	factories[name] = factory
+}
+
+// TagMap maps the fields of a Point (id, channel, serial, key and station)
+// to the tag names used by a sink. Fields mapped to an empty string are not
+// sent, and neither are empty values, such as the default station.
+type TagMap map[string]string
+
+var defaultTags = TagMap{
+	"id":      "id",
+	"channel": "channel",
+	"serial":  "serial",
+	"station": "station",
+}
+
+// newTagMap reads the configured tags. The station is tagged unless the
+// configuration maps it to something else, so that stations sharing a
+// database stay apart.
+func newTagMap(config *viper.Viper) TagMap {
+	if !config.IsSet("tags") {
+		return defaultTags
+	}
+	tags := TagMap(config.GetStringMapString("tags"))
+	if _, ok := tags["station"]; !ok {
+		tags["station"] = "station"
+	}
+	return tags
+}
+
+// Tags returns the tags for a point, sorted by name.
+func (m TagMap) Tags(p Point) [][2]string {
+	values := map[string]string{
+		"id":      p.ID,
+		"channel": strconv.Itoa(p.Channel),
+		"serial":  p.Serial,
+		"key":     p.Key,
+		"station": p.Station,
+	}
+	var result [][2]string
+	for field, value := range values {
+		if name := m[field]; name != "" {
+			result = append(result, [2]string{name, value})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i][0] < result[j][0]
+	})
+	return result
+}
+
+// Buffered batches points for a sink, retrying failed writes and keeping
+// unsent points until the next write.
+type Buffered struct {
+	Name       string
+	BatchSize  int
+	MaxPending int
+	Retries    int
+	Backoff    time.Duration
+
+	sink    Sink
+	mu      sync.Mutex
+	pending []Point
+}
+
+func NewBuffered(name string, sink Sink) *Buffered {
+	return &Buffered{
+		Name:       name,
+		BatchSize:  500,
+		MaxPending: 10000,
+		Retries:    3,
+		Backoff:    time.Second,
+		sink:       sink,
+	}
+}
+
+// Write queues the points and sends everything pending in batches.
+func (b *Buffered) Write(points []Point) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pending = append(b.pending, points...)
+	if b.MaxPending > 0 && len(b.pending) > b.MaxPending {
+		dropped := len(b.pending) - b.MaxPending
+		jww.ERROR.Printf("Sink %s: dropping %d points\n", b.Name, dropped)
+		b.pending = b.pending[dropped:]
+	}
+
+	for len(b.pending) > 0 {
+		n := len(b.pending)
+		if b.BatchSize > 0 && n > b.BatchSize {
+			n = b.BatchSize
+		}
+		if err := b.send(b.pending[:n]); err != nil {
+			return err
+		}
+		b.pending = b.pending[n:]
+	}
+	b.pending = nil
+	return nil
+}
+
+// Pending returns the number of points waiting to be sent.
+func (b *Buffered) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.pending)
+}
+
+func (b *Buffered) send(batch []Point) error {
+	backoff := b.Backoff
+	var err error
+	for attempt := 0; attempt <= b.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		if err = b.sink.Write(batch); err == nil {
+			return nil
+		}
+		jww.ERROR.Printf("Sink %s: %s\n", b.Name, err.Error())
+	}
+	return err
+}
+
+// Group delivers points to all configured sinks from a background goroutine,
+// so that slow or unreachable sinks don't hold up the aggregator.
+type Group struct {
+	sinks []*Buffered
+	queue chan []Point
+}
+
+// Open creates the sinks listed in the "sinks" configuration section.
+func Open() (*Group, error) {
+	group := &Group{queue: make(chan []Point, 64)}
+	config := viper.Sub("sinks")
+	if config == nil {
+		return group, nil
+	}
+	for name := range viper.GetStringMap("sinks") {
+		sub := config.Sub(name)
+		if sub == nil {
+			continue
+		}
+		factory, ok := factories[sub.GetString("type")]
+		if !ok {
+			return nil, fmt.Errorf("sink %s: unknown type %q", name, sub.GetString("type"))
+		}
+		sink, err := factory(sub)
+		if err != nil {
+			return nil, fmt.Errorf("sink %s: %s", name, err.Error())
+		}
+		b := NewBuffered(name, sink)
+		if sub.IsSet("batch_size") {
+			b.BatchSize = sub.GetInt("batch_size")
+		}
+		if sub.IsSet("max_pending") {
+			b.MaxPending = sub.GetInt("max_pending")
+		}
+		if sub.IsSet("retries") {
+			b.Retries = sub.GetInt("retries")
+		}
+		group.sinks = append(group.sinks, b)
+	}
+	go group.run()
+	return group, nil
+}
+
+// Write queues the points for delivery to every sink.
+func (g *Group) Write(points []Point) {
+	if len(g.sinks) == 0 {
+		return
+	}
+	select {
+	case g.queue <- points:
+	default:
+		jww.ERROR.Printf("Sink queue full, dropping %d points\n", len(points))
+	}
+}
+
+func (g *Group) run() {
+	for points := range g.queue {
+		for _, sink := range g.sinks {
+			if err := sink.Write(points); err != nil {
+				jww.ERROR.Printf("Sink %s: %d points pending\n", sink.Name, sink.Pending())
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package sinks
+
+import (
+	"errors"
+	"testing"
+)
+
+type stubSink struct {
+	failures int
+	batches  [][]Point
+}
+
+func (s *stubSink) Write(points []Point) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("unavailable")
+	}
+	s.batches = append(s.batches, points)
+	return nil
+}
+
+func TestBufferedBatches(t *testing.T) {
+	stub := &stubSink{}
+	b := NewBuffered("stub", stub)
+	b.BatchSize = 2
+
+	if err := b.Write(make([]Point, 5)); err != nil {
+		t.Fatal(err)
+	}
+	if len(stub.batches) != 3 || len(stub.batches[2]) != 1 {
+		t.Error("Unexpected batches", stub.batches)
+	}
+}
+
+func TestBufferedRetry(t *testing.T) {
+	stub := &stubSink{failures: 2}
+	b := NewBuffered("stub", stub)
+	b.Backoff = 0
+
+	if err := b.Write(make([]Point, 3)); err != nil {
+		t.Fatal(err)
+	}
+	if len(stub.batches) != 1 {
+		t.Error("Expected one batch after retrying", stub.batches)
+	}
+}
+
+func TestBufferedKeepsPending(t *testing.T) {
+	stub := &stubSink{failures: 2}
+	b := NewBuffered("stub", stub)
+	b.Backoff = 0
+	b.Retries = 1
+	b.MaxPending = 4
+
+	if err := b.Write(make([]Point, 3)); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if b.Pending() != 3 {
+		t.Error("Expected 3 pending points", b.Pending())
+	}
+
+	if err := b.Write(make([]Point, 3)); err != nil {
+		t.Fatal(err)
+	}
+	if len(stub.batches) != 1 || len(stub.batches[0]) != 4 {
+		t.Error("Expected the oldest points to be dropped", stub.batches)
+	}
+}
+
+func TestTagMap(t *testing.T) {
+	tags := TagMap{"id": "sensor", "key": "key", "serial": ""}.Tags(Point{ID: "BMP", Serial: "0", Key: "Pressure"})
+	if len(tags) != 2 || tags[0] != [2]string{"key", "Pressure"} || tags[1] != [2]string{"sensor", "BMP"} {
+		t.Error("Unexpected tags", tags)
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package upload
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/geoffholden/gowx/units"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	RegisterUploaderType("cwop", newCWOP)
+}
+
+// cwopInterval is the minimum time between CWOP reports.
+const cwopInterval = 5 * time.Minute
+
+// cwop sends APRS weather reports to the Citizen Weather Observer Program
+// through APRS-IS.
+type cwop struct {
+	server    string
+	callsign  string
+	passcode  string
+	latitude  float64
+	longitude float64
+	timeout   time.Duration
+}
+
+func newCWOP(config *viper.Viper) (Uploader, error) {
+	c := &cwop{
+		server:   "cwop.aprs.net:14580",
+		callsign: strings.ToUpper(config.GetString("callsign")),
+		passcode: "-1",
+		timeout:  30 * time.Second,
+	}
+	if config.IsSet("server") {
+		c.server = config.GetString("server")
+	}
+	if config.IsSet("passcode") {
+		c.passcode = config.GetString("passcode")
+	}
+	if c.callsign == "" {
+		return nil, errors.New("callsign is required")
+	}
+
+	// The position defaults to the station's.
+	for key, v := range map[string]*float64{"latitude": &c.latitude, "longitude": &c.longitude} {
+		switch {
+		case config.IsSet(key):
+			*v = config.GetFloat64(key)
+		case viper.IsSet(key):
+			*v = viper.GetFloat64(key)
+		default:
+			return nil, errors.New(key + " is required")
+		}
+	}
+	if math.Abs(c.latitude) > 90 || math.Abs(c.longitude) > 180 {
+		return nil, errors.New("invalid position")
+	}
+	return c, nil
+}
+
+func (c *cwop) MinInterval() time.Duration {
+	return cwopInterval
+}
+
+// aprsPosition formats a position as DDMM.mmN/DDDMM.mmW, with the weather
+// station symbol.
+func aprsPosition(latitude float64, longitude float64) string {
+	format := func(v float64, degrees int, pos byte, neg byte) string {
+		hemisphere := pos
+		if v < 0 {
+			hemisphere = neg
+			v = -v
+		}
+		// Round to hundredths of minutes first, so 59.999' doesn't become 60.00'.
+		hundredths := int(math.Round(v * 6000))
+		return fmt.Sprintf("%0*d%02d.%02d%c", degrees, hundredths/6000, hundredths%6000/100, hundredths%100, hemisphere)
+	}
+	return format(latitude, 2, 'N', 'S') + "/" + format(longitude, 3, 'E', 'W') + "_"
+}
+
+// aprsValue formats a value in a fixed number of digits, or dots if it's
+// missing.
+func aprsValue(v float64, ok bool, digits int) string {
+	if !ok {
+		return strings.Repeat(".", digits)
+	}
+	n := int(math.Round(v))
+	max := int(math.Pow10(digits)) - 1
+	if n > max {
+		n = max
+	}
+	if n < 0 {
+		// Negative values give up a digit for the sign.
+		min := -int(math.Pow10(digits-1)) + 1
+		if n < min {
+			n = min
+		}
+		return fmt.Sprintf("-%0*d", digits-1, -n)
+	}
+	return fmt.Sprintf("%0*d", digits, n)
+}
+
+// Packet returns the APRS position and weather report of an observation.
+func (c *cwop) Packet(o Observation) (string, error) {
+	if !hasData(o) {
+		return "", errors.New("cwop: no data")
+	}
+	field := func(name string, convert func(float64) float64, digits int) string {
+		v, ok := o.Get(name)
+		if ok && convert != nil {
+			v = convert(v)
+		}
+		return aprsValue(v, ok, digits)
+	}
+	mph := func(v float64) float64 {
+		u := units.NewSpeedMetersPerSecond(v)
+		return u.MilesPerHour()
+	}
+	hundredthsInch := func(v float64) float64 {
+		u := units.NewDistanceMillimeters(v)
+		return u.Inches() * 100
+	}
+
+	var w strings.Builder
+	fmt.Fprintf(&w, "%s>APRS,TCPIP*:@%sz", c.callsign, o.Time.UTC().Format("021504"))
+	w.WriteString(aprsPosition(c.latitude, c.longitude))
+
+	dir, ok := o.Get(WindDir)
+	if ok {
+		// Due north is 360, 000 means calm.
+		dir = math.Mod(math.Round(dir)+359, 360) + 1
+	}
+	w.WriteString(aprsValue(dir, ok, 3))
+	w.WriteString("/" + field(WindSpeed, mph, 3))
+	w.WriteString("g" + field(WindGust, mph, 3))
+	w.WriteString("t" + field(Temperature, func(v float64) float64 {
+		u := units.NewTemperatureCelsius(v)
+		return u.Fahrenheit()
+	}, 3))
+	w.WriteString("r" + field(RainHour, hundredthsInch, 3))
+	w.WriteString("p" + field(Rain24Hours, hundredthsInch, 3))
+	w.WriteString("P" + field(RainDay, hundredthsInch, 3))
+	if h, ok := o.Get(Humidity); ok {
+		// Two digits, with 00 for 100%.
+		n := int(math.Round(h))
+		if n < 1 {
+			n = 1
+		} else if n > 100 {
+			n = 100
+		}
+		w.WriteString(fmt.Sprintf("h%02d", n%100))
+	}
+	if _, ok := o.Get(Pressure); ok {
+		w.WriteString("b" + field(Pressure, func(v float64) float64 { return v * 10 }, 5))
+	}
+	w.WriteString("gowx")
+	return w.String(), nil
+}
+
+func hasData(o Observation) bool {
+	for _, name := range []string{WindDir, WindSpeed, WindGust, Temperature, RainHour, Rain24Hours, RainDay, Humidity, Pressure} {
+		if _, ok := o.Get(name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cwop) Request(o Observation) (Request, error) {
+	packet, err := c.Packet(o)
+	if err != nil {
+		return nil, err
+	}
+	return &aprsRequest{cwop: c, packet: packet}, nil
+}
+
+type aprsRequest struct {
+	*cwop
+	packet string
+}
+
+func (r *aprsRequest) String() string {
+	return "APRS-IS " + r.server + " " + r.packet
+}
+
+// Send logs in to the APRS-IS server and sends the packet.
+func (r *aprsRequest) Send() error {
+	conn, err := net.DialTimeout("tcp", r.server, r.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(r.timeout))
+	reader := bufio.NewReader(conn)
+
+	// The server greets with a comment line.
+	banner, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(banner, "#") {
+		return fmt.Errorf("cwop: unexpected banner %q", strings.TrimSpace(banner))
+	}
+
+	if _, err := fmt.Fprintf(conn, "user %s pass %s vers gowx 1.0\r\n", r.callsign, r.passcode); err != nil {
+		return err
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "#"))
+		if len(fields) < 3 || fields[0] != "logresp" {
+			continue
+		}
+		if !strings.EqualFold(fields[1], r.callsign) {
+			return Permanent(fmt.Errorf("cwop: login rejected: %s", line))
+		}
+		// CWOP stations log in unverified with passcode -1, so only
+		// a verified login is required with another passcode.
+		if fields[2] != "verified," && r.passcode != "-1" {
+			return Permanent(fmt.Errorf("cwop: login rejected: %s", line))
+		}
+		break
+	}
+
+	_, err = fmt.Fprintf(conn, "%s\r\n", r.packet)
+	return err
+}
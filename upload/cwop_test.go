@@ -0,0 +1,137 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package upload
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func newTestCWOP(t *testing.T, server string) *cwop {
+	config := viper.New()
+	config.Set("callsign", "cw0001")
+	config.Set("server", server)
+	config.Set("latitude", 45.4215)
+	config.Set("longitude", -75.6972)
+	u, err := newCWOP(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.(*cwop)
+}
+
+func TestCWOPPacket(t *testing.T) {
+	c := newTestCWOP(t, "")
+	packet, err := c.Packet(Observation{
+		Time: time.Date(2026, 10, 19, 8, 5, 0, 0, time.UTC),
+		Values: map[string]float64{
+			Temperature: -20,
+			Humidity:    100,
+			Pressure:    1013.2,
+			WindDir:     0,
+			WindSpeed:   2.2352,
+			RainDay:     2.54,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "CW0001>APRS,TCPIP*:@190805z4525.29N/07541.83W_360/005g...t-04r...p...P010h00b10132gowx"
+	if packet != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, packet)
+	}
+
+	if _, err := c.Packet(Observation{}); err == nil {
+		t.Error("Empty observations should be an error")
+	}
+}
+
+func TestAPRSValue(t *testing.T) {
+	for _, test := range []struct {
+		v      float64
+		digits int
+		out    string
+	}{
+		{5, 3, "005"},
+		{-5, 3, "-05"},
+		{-150, 3, "-99"},
+		{1234, 3, "999"},
+	} {
+		if out := aprsValue(test.v, true, test.digits); out != test.out {
+			t.Error("Expected", test.out, "got", out)
+		}
+	}
+}
+
+// cwopServer is a stand-in APRS-IS server. It sends the login response and
+// returns the lines received.
+func cwopServer(t *testing.T, logresp string) (string, <-chan []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	lines := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		conn.Write([]byte("# aprsc 2.1.10\r\n"))
+		var received []string
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			received = append(received, scanner.Text())
+			if len(received) == 1 {
+				conn.Write([]byte(logresp + "\r\n"))
+			}
+		}
+		lines <- received
+	}()
+	return listener.Addr().String(), lines
+}
+
+func TestCWOPSend(t *testing.T) {
+	server, lines := cwopServer(t, "# logresp CW0001 unverified, server CWOP-4")
+	r := NewRunner("cwop", newTestCWOP(t, server))
+	r.Interval = 0
+	if err := r.Send(Observation{Time: time.Now(), Values: map[string]float64{Temperature: 20}}); err != nil {
+		t.Fatal(err)
+	}
+	received := <-lines
+	if len(received) != 2 || received[0] != "user CW0001 pass -1 vers gowx 1.0" || !strings.HasPrefix(received[1], "CW0001>APRS,TCPIP*:@") {
+		t.Error("Unexpected lines", received)
+	}
+}
+
+func TestCWOPLoginRejected(t *testing.T) {
+	server, _ := cwopServer(t, "# logresp N0CALL unverified, server CWOP-4")
+	r := NewRunner("cwop", newTestCWOP(t, server))
+	r.Interval = 0
+	if err := r.Send(Observation{Time: time.Now(), Values: map[string]float64{Temperature: 20}}); !IsPermanent(err) {
+		t.Error("Expected a permanent error, got", err)
+	}
+}
+
+func TestCWOPInterval(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("latitude", 45.4)
+	viper.Set("longitude", -75.7)
+	viper.Set("upload.services.cwop.callsign", "CW0001")
+	viper.Set("upload.services.cwop.interval", 60)
+	runners, err := Open(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runners[0].Interval != 5*time.Minute {
+		t.Error("CWOP uploads should be at least 5 minutes apart", runners[0].Interval)
+	}
+}
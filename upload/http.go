@@ -0,0 +1,92 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package upload
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// HTTPRequest is an upload through an HTTP request.
+type HTTPRequest struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+	// Secrets are left out of String.
+	Secrets []string
+	// Validate checks the response of a successful request. Without it, any
+	// 2xx status is accepted.
+	Validate func(res *http.Response, body []byte) error
+}
+
+func (r *HTTPRequest) Send() error {
+	method := r.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(method, r.URL, bytes.NewReader(r.Body))
+	if err != nil {
+		return Permanent(err)
+	}
+	for name, values := range r.Header {
+		req.Header[name] = values
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	// Server errors and rate limiting are worth retrying, other errors are
+	// not.
+	if res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+	if r.Validate != nil {
+		return r.Validate(res, body)
+	}
+	if res.StatusCode >= 300 {
+		return Permanent(fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(body))))
+	}
+	return nil
+}
+
+func (r *HTTPRequest) String() string {
+	method := r.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	s := method + " " + r.URL
+	names := make([]string, 0, len(r.Header))
+	for name := range r.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s += "\n" + name + ": " + r.Header.Get(name)
+	}
+	if len(r.Body) > 0 {
+		s += "\n\n" + string(r.Body)
+	}
+	for _, secret := range r.Secrets {
+		if secret != "" {
+			s = strings.Replace(s, secret, "REDACTED", -1)
+			s = strings.Replace(s, url.QueryEscape(secret), "REDACTED", -1)
+			s = strings.Replace(s, url.PathEscape(secret), "REDACTED", -1)
+		}
+	}
+	return s
+}
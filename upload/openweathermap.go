@@ -0,0 +1,76 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package upload
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+
+	"github.com/spf13/viper"
+)
+
+func init() {
+	RegisterUploaderType("openweathermap", newOpenWeatherMap)
+}
+
+// openWeatherMap uploads measurements to a station registered with the
+// OpenWeatherMap stations API. The API takes metric units.
+type openWeatherMap struct {
+	url       string
+	key       string
+	stationID string
+}
+
+func newOpenWeatherMap(config *viper.Viper) (Uploader, error) {
+	w := &openWeatherMap{
+		url:       "https://api.openweathermap.org/data/3.0/measurements",
+		key:       config.GetString("appid"),
+		stationID: config.GetString("station_id"),
+	}
+	if config.IsSet("url") {
+		w.url = config.GetString("url")
+	}
+	if w.key == "" || w.stationID == "" {
+		return nil, errors.New("appid and station_id are required")
+	}
+	return w, nil
+}
+
+func (w *openWeatherMap) Request(o Observation) (Request, error) {
+	measurement := map[string]interface{}{}
+	set := func(name string, field string) {
+		if v, ok := o.Get(field); ok {
+			measurement[name] = math.Round(v*100) / 100
+		}
+	}
+	set("temperature", Temperature)
+	set("humidity", Humidity)
+	set("dew_point", DewPoint)
+	set("pressure", Pressure)
+	set("wind_speed", WindSpeed)
+	set("wind_deg", WindDir)
+	set("wind_gust", WindGust)
+	set("rain_1h", RainHour)
+	set("rain_24h", Rain24Hours)
+	set("visibility_distance", Visibility)
+	if len(measurement) == 0 {
+		return nil, errors.New("openweathermap: no data")
+	}
+	measurement["station_id"] = w.stationID
+	measurement["dt"] = o.Time.Unix()
+
+	body, err := json.Marshal([]interface{}{measurement})
+	if err != nil {
+		return nil, err
+	}
+	return &HTTPRequest{
+		Method:  http.MethodPost,
+		URL:     w.url + "?appid=" + url.QueryEscape(w.key),
+		Header:  http.Header{"Content-Type": {"application/json"}},
+		Body:    body,
+		Secrets: []string{w.key},
+	}, nil
+}
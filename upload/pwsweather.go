@@ -0,0 +1,84 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package upload
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/viper"
+)
+
+func init() {
+	RegisterUploaderType("pwsweather", newPWSweather)
+}
+
+// pwsweatherFields are the Weather Underground parameters accepted by
+// PWSweather.
+var pwsweatherFields = map[string]string{
+	"tempf":          Temperature,
+	"humidity":       Humidity,
+	"dewptf":         DewPoint,
+	"baromin":        Pressure,
+	"windspeedmph":   WindSpeed,
+	"winddir":        WindDir,
+	"windgustmph":    WindGust,
+	"rainin":         RainHour,
+	"dailyrainin":    RainDay,
+	"UV":             UV,
+	"solarradiation": SolarRadiation,
+}
+
+// pwsweather uploads to PWSweather, whose API takes Weather Underground
+// parameters and answers with JSON.
+type pwsweather struct {
+	url string
+	id  string
+	key string
+}
+
+func newPWSweather(config *viper.Viper) (Uploader, error) {
+	p := &pwsweather{
+		url: "https://pwsupdate.pwsweather.com/api/v1/submitwx",
+		id:  config.GetString("id"),
+		key: config.GetString("key"),
+	}
+	if config.IsSet("url") {
+		p.url = config.GetString("url")
+	}
+	if p.id == "" || p.key == "" {
+		return nil, errors.New("id and key are required")
+	}
+	return p, nil
+}
+
+func (p *pwsweather) Request(o Observation) (Request, error) {
+	v := wundergroundValues(o, pwsweatherFields)
+	if len(v) == 0 {
+		return nil, errors.New("pwsweather: no data")
+	}
+	v.Set("ID", p.id)
+	v.Set("PASSWORD", p.key)
+	v.Set("dateutc", o.Time.UTC().Format("2006-01-02 15:04:05"))
+	v.Set("softwaretype", "gowx")
+
+	return &HTTPRequest{
+		URL:     p.url + "?" + v.Encode(),
+		Secrets: []string{p.key},
+		Validate: func(res *http.Response, body []byte) error {
+			var result struct {
+				Error struct {
+					Code    int    `json:"code"`
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			json.Unmarshal(body, &result)
+			if res.StatusCode >= 300 || result.Error.Code != 0 || result.Error.Message != "" {
+				return Permanent(fmt.Errorf("pwsweather: %s %s", res.Status, result.Error.Message))
+			}
+			return nil
+		},
+	}, nil
+}
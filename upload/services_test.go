@@ -0,0 +1,184 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package upload
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// stub is a stand-in for a service, answering with a fixed status and body
+// and recording the last request.
+type stub struct {
+	status int
+	body   string
+
+	method string
+	path   string
+	query  map[string][]string
+	data   []byte
+	count  int
+}
+
+func newStub(t *testing.T, status int, body string) (*stub, string) {
+	s := &stub{status: status, body: body}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.count++
+		s.method = r.Method
+		s.path = r.URL.Path
+		s.query = r.URL.Query()
+		s.data, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(s.status)
+		w.Write([]byte(s.body))
+	}))
+	t.Cleanup(server.Close)
+	return s, server.URL
+}
+
+func newTestRunnerOf(t *testing.T, factory Factory, settings map[string]interface{}) *Runner {
+	config := viper.New()
+	for key, value := range settings {
+		config.Set(key, value)
+	}
+	u, err := factory(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRunner("test", u)
+	r.Interval = 0
+	r.Backoff = time.Millisecond
+	r.Retries = 1
+	return r
+}
+
+var full = Observation{
+	Time: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+	Values: map[string]float64{
+		Temperature: 10,
+		Humidity:    80,
+		Pressure:    1000,
+		WindSpeed:   5,
+		WindDir:     270,
+		RainHour:    1.2,
+		Rain24Hours: 6.35,
+		Visibility:  12000,
+	},
+}
+
+func TestWOW(t *testing.T) {
+	s, url := newStub(t, http.StatusOK, "{}")
+	r := newTestRunnerOf(t, newWOW, map[string]interface{}{"siteid": "1234", "authkey": "secret", "url": url})
+	if err := r.Send(full); err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range map[string]string{
+		"siteid":                "1234",
+		"siteAuthenticationKey": "secret",
+		"dateutc":               "2026-10-19 12:00:00",
+		"softwaretype":          "gowx",
+		"tempf":                 "50.00",
+		"baromin":               "29.53",
+		"rainin":                "0.05",
+		"visibility":            "12.00",
+	} {
+		if got := s.query[key]; len(got) != 1 || got[0] != value {
+			t.Errorf("%s: expected %s, got %v", key, value, got)
+		}
+	}
+
+	s.status, s.body = http.StatusForbidden, "Invalid site authentication key"
+	if err := r.Send(full); !IsPermanent(err) {
+		t.Error("Expected a permanent error, got", err)
+	}
+}
+
+func TestPWSweather(t *testing.T) {
+	s, url := newStub(t, http.StatusOK, `{"status":"ok"}`)
+	r := newTestRunnerOf(t, newPWSweather, map[string]interface{}{"id": "STATION", "key": "secret", "url": url})
+	if err := r.Send(full); err != nil {
+		t.Fatal(err)
+	}
+	if s.query["ID"][0] != "STATION" || s.query["windspeedmph"][0] != "11.18" || s.query["winddir"][0] != "270" {
+		t.Error("Unexpected query", s.query)
+	}
+
+	s.body = `{"error":{"code":401,"message":"invalid station password"}}`
+	if err := r.Send(full); !IsPermanent(err) {
+		t.Error("Expected a permanent error, got", err)
+	}
+
+	s.status = http.StatusBadGateway
+	if err := r.Send(full); err == nil || IsPermanent(err) || s.count != 4 {
+		t.Error("Server errors should be retried", err, s.count)
+	}
+}
+
+func TestWindy(t *testing.T) {
+	s, url := newStub(t, http.StatusOK, "SUCCESS")
+	r := newTestRunnerOf(t, newWindy, map[string]interface{}{"key": "secret/key", "station": 1, "url": url + "/pws/update/"})
+	if err := r.Send(full); err != nil {
+		t.Fatal(err)
+	}
+	if s.method != http.MethodPost || s.path != "/pws/update/secret/key" {
+		t.Error("Unexpected request", s.method, s.path)
+	}
+	var body struct {
+		Observations []map[string]interface{}
+	}
+	if err := json.Unmarshal(s.data, &body); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"station":  1.0,
+		"dateutc":  "2026-10-19T12:00:00",
+		"temp":     10.0,
+		"rh":       80.0,
+		"pressure": 100000.0,
+		"wind":     5.0,
+		"winddir":  270.0,
+		"precip":   1.2,
+	}
+	if len(body.Observations) != 1 || len(body.Observations[0]) != len(expected) {
+		t.Fatal("Unexpected body", string(s.data))
+	}
+	for key, value := range expected {
+		if body.Observations[0][key] != value {
+			t.Errorf("%s: expected %v, got %v", key, value, body.Observations[0][key])
+		}
+	}
+
+	s.status = http.StatusUnauthorized
+	if err := r.Send(full); !IsPermanent(err) {
+		t.Error("Expected a permanent error, got", err)
+	}
+}
+
+func TestOpenWeatherMap(t *testing.T) {
+	s, url := newStub(t, http.StatusNoContent, "")
+	r := newTestRunnerOf(t, newOpenWeatherMap, map[string]interface{}{"appid": "secret", "station_id": "5ed21a12cca8ce0001f1aef1", "url": url})
+	if err := r.Send(full); err != nil {
+		t.Fatal(err)
+	}
+	if s.method != http.MethodPost || s.query["appid"][0] != "secret" {
+		t.Error("Unexpected request", s.method, s.query)
+	}
+	var body []map[string]interface{}
+	if err := json.Unmarshal(s.data, &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body) != 1 || body[0]["station_id"] != "5ed21a12cca8ce0001f1aef1" || body[0]["dt"] != float64(full.Time.Unix()) ||
+		body[0]["temperature"] != 10.0 || body[0]["rain_24h"] != 6.35 || body[0]["visibility_distance"] != 12000.0 {
+		t.Error("Unexpected body", string(s.data))
+	}
+
+	s.status, s.body = http.StatusBadRequest, `{"code":400001,"message":"Station id not found"}`
+	if err := r.Send(full); !IsPermanent(err) {
+		t.Error("Expected a permanent error, got", err)
+	}
+}
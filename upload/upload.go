@@ -0,0 +1,282 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+// Package upload sends weather observations to online services such as
+// Weather Underground. Each service is an Uploader, which turns an
+// observation into a request; a Runner sends the requests with rate
+// limiting and retries.
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+)
+
+// The fields of an observation. All values are in metric units.
+const (
+	Temperature       = "temperature"       // °C
+	Humidity          = "humidity"          // %
+	DewPoint          = "dewpoint"          // °C
+	Pressure          = "pressure"          // hPa, at sea level
+	WindSpeed         = "windspeed"         // m/s
+	WindDir           = "winddir"           // degrees
+	WindGust          = "windgust"          // m/s
+	WindGustDir       = "windgustdir"       // degrees
+	WindSpeedAvg2m    = "windspeed2m"       // m/s, two minute average
+	WindDirAvg2m      = "winddir2m"         // degrees, two minute average
+	WindGust10m       = "windgust10m"       // m/s, over ten minutes
+	WindGustDir10m    = "windgustdir10m"    // degrees
+	RainHour          = "rainhour"          // mm over the past hour
+	Rain24Hours       = "rain24h"           // mm over the past 24 hours
+	RainDay           = "rainday"           // mm since midnight, station time
+	UV                = "uv"                // index
+	SolarRadiation    = "solarradiation"    // W/m²
+	SoilTemperature   = "soiltemperature"   // °C
+	SoilMoisture      = "soilmoisture"      // %
+	Visibility        = "visibility"        // m
+	IndoorTemperature = "indoortemperature" // °C
+	IndoorHumidity    = "indoorhumidity"    // %
+)
+
+// Observation is the state of the weather at a point in time.
+type Observation struct {
+	Time   time.Time
+	Values map[string]float64
+}
+
+// Get returns the value of a field.
+func (o Observation) Get(field string) (float64, bool) {
+	v, ok := o.Values[field]
+	return v, ok
+}
+
+// DewPointOf returns the dew point in °C from the temperature in °C and the
+// relative humidity in %, using the Magnus formula.
+func DewPointOf(temperature float64, humidity float64) float64 {
+	const b, c = 17.62, 243.12
+	gamma := math.Log(humidity/100) + b*temperature/(c+temperature)
+	return c * gamma / (b - gamma)
+}
+
+// Request is a prepared upload.
+type Request interface {
+	Send() error
+	// String describes the request for dry runs, without any secrets.
+	String() string
+}
+
+// Uploader prepares the requests of a service.
+type Uploader interface {
+	Request(o Observation) (Request, error)
+}
+
+// A service can require a minimum time between uploads by implementing
+// MinInterval.
+type minInterval interface {
+	MinInterval() time.Duration
+}
+
+// A service can ask to be sent an observation for every sample, rather
+// than for every aggregate, by implementing Realtime. The interval is the
+// time between uploads, or 0 if the service isn't realtime.
+type realtime interface {
+	Realtime() time.Duration
+}
+
+// Factory creates an uploader from its configuration section.
+type Factory func(config *viper.Viper) (Uploader, error)
+
+var factories map[string]Factory
+
+func RegisterUploaderType(name string, factory Factory) {
+	if nil == factories {
+		factories = make(map[string]Factory)
+	}
+	factories[name] = factory
+}
+
+type permanent struct {
+	error
+}
+
+// Permanent marks an error that retrying won't fix, such as a rejected
+// password.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanent{err}
+}
+
+// IsPermanent reports whether an error was marked with Permanent.
+func IsPermanent(err error) bool {
+	var p permanent
+	return errors.As(err, &p)
+}
+
+// Runner sends observations to one service. It keeps at least Interval
+// between uploads, and retries failed uploads with an exponential backoff.
+// Realtime runners are sent observations of the samples rather than of the
+// aggregates.
+type Runner struct {
+	Name     string
+	Interval time.Duration
+	Retries  int
+	Backoff  time.Duration
+	Realtime bool
+	// MaxAge is the age after which a queued observation is dropped rather
+	// than sent, or zero to send every observation. Most services only show
+	// current conditions, and replaying a long backlog at the rate limit
+	// would hold up the new observations.
+	MaxAge time.Duration
+	// DryRun, if set, receives the requests instead of sending them.
+	DryRun io.Writer
+
+	uploader Uploader
+	mu       sync.Mutex
+	last     time.Time
+	pending  chan Observation
+}
+
+func NewRunner(name string, uploader Uploader) *Runner {
+	return &Runner{
+		Name:     name,
+		Interval: time.Minute,
+		Retries:  3,
+		Backoff:  time.Second,
+		MaxAge:   15 * time.Minute,
+		uploader: uploader,
+		pending:  make(chan Observation, 1),
+	}
+}
+
+// Send uploads an observation, waiting for the rate limit if needed.
+// Observations the service won't accept are returned as permanent errors.
+func (r *Runner) Send(o Observation) error {
+	req, err := r.uploader.Request(o)
+	if err != nil {
+		return Permanent(err)
+	}
+	if r.DryRun != nil {
+		fmt.Fprintf(r.DryRun, "%s: %s\n", r.Name, req)
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if wait := r.Interval - time.Since(r.last); wait > 0 {
+		time.Sleep(wait)
+	}
+
+	backoff := r.Backoff
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		err = req.Send()
+		r.last = time.Now()
+		if err == nil || IsPermanent(err) || attempt >= r.Retries {
+			return err
+		}
+		jww.ERROR.Printf("Upload %s: %s\n", r.Name, err.Error())
+	}
+}
+
+// Stale reports whether an observation is older than MaxAge.
+func (r *Runner) Stale(o Observation, now time.Time) bool {
+	return r.MaxAge > 0 && !o.Time.IsZero() && now.Sub(o.Time) > r.MaxAge
+}
+
+// Submit queues an observation for Run, replacing any observation that
+// hasn't been picked up yet.
+func (r *Runner) Submit(o Observation) {
+	for {
+		select {
+		case r.pending <- o:
+			return
+		default:
+		}
+		select {
+		case <-r.pending:
+		default:
+		}
+	}
+}
+
+// Run calls handle with each submitted observation.
+func (r *Runner) Run(handle func(o Observation)) {
+	for o := range r.pending {
+		handle(o)
+	}
+}
+
+// Open creates the uploaders listed in the "upload.services" configuration
+// section, or only the named ones if names are given. The type of a service
+// defaults to its name.
+func Open(names []string) ([]*Runner, error) {
+	config := viper.Sub("upload.services")
+	if config == nil {
+		return nil, errors.New("no upload services configured")
+	}
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	var runners []*Runner
+	for name := range viper.GetStringMap("upload.services") {
+		if len(wanted) > 0 && !wanted[name] {
+			continue
+		}
+		delete(wanted, name)
+		sub := config.Sub(name)
+		if sub == nil {
+			continue
+		}
+		typ := sub.GetString("type")
+		if typ == "" {
+			typ = name
+		}
+		factory, ok := factories[typ]
+		if !ok {
+			return nil, fmt.Errorf("upload %s: unknown type %q", name, typ)
+		}
+		uploader, err := factory(sub)
+		if err != nil {
+			return nil, fmt.Errorf("upload %s: %s", name, err.Error())
+		}
+		r := NewRunner(name, uploader)
+		if sub.IsSet("interval") {
+			r.Interval = time.Duration(sub.GetInt("interval")) * time.Second
+		}
+		if m, ok := uploader.(minInterval); ok && r.Interval < m.MinInterval() {
+			r.Interval = m.MinInterval()
+		}
+		if rt, ok := uploader.(realtime); ok && rt.Realtime() > 0 {
+			r.Realtime = true
+			r.Interval = rt.Realtime()
+		}
+		if sub.IsSet("retries") {
+			r.Retries = sub.GetInt("retries")
+		}
+		if sub.IsSet("max_age") {
+			r.MaxAge = time.Duration(sub.GetInt("max_age")) * time.Second
+		}
+		runners = append(runners, r)
+	}
+	for name := range wanted {
+		return nil, fmt.Errorf("upload %s: not configured", name)
+	}
+	sort.Slice(runners, func(i, j int) bool {
+		return runners[i].Name < runners[j].Name
+	})
+	return runners, nil
+}
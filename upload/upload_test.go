@@ -0,0 +1,264 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type fakeRequest struct {
+	errs []error
+	sent int
+}
+
+func (r *fakeRequest) Send() error {
+	r.sent++
+	if len(r.errs) == 0 {
+		return nil
+	}
+	err := r.errs[0]
+	r.errs = r.errs[1:]
+	return err
+}
+
+func (r *fakeRequest) String() string {
+	return "fake"
+}
+
+type fakeUploader struct {
+	req *fakeRequest
+}
+
+func (u fakeUploader) Request(o Observation) (Request, error) {
+	return u.req, nil
+}
+
+func newTestRunner(req *fakeRequest) *Runner {
+	r := NewRunner("fake", fakeUploader{req})
+	r.Interval = 0
+	r.Backoff = time.Millisecond
+	return r
+}
+
+func TestRunnerRetries(t *testing.T) {
+	req := &fakeRequest{errs: []error{errors.New("timeout"), errors.New("timeout")}}
+	if err := newTestRunner(req).Send(Observation{}); err != nil || req.sent != 3 {
+		t.Error("Expected success after 3 attempts", err, req.sent)
+	}
+
+	req = &fakeRequest{errs: []error{errors.New("a"), errors.New("b"), errors.New("c"), errors.New("d")}}
+	if err := newTestRunner(req).Send(Observation{}); err == nil || IsPermanent(err) || req.sent != 4 {
+		t.Error("Expected failure after 4 attempts", err, req.sent)
+	}
+
+	req = &fakeRequest{errs: []error{Permanent(errors.New("bad password"))}}
+	if err := newTestRunner(req).Send(Observation{}); !IsPermanent(err) || req.sent != 1 {
+		t.Error("Permanent errors shouldn't be retried", err, req.sent)
+	}
+}
+
+func TestRunnerInterval(t *testing.T) {
+	r := newTestRunner(&fakeRequest{})
+	r.Interval = 50 * time.Millisecond
+	start := time.Now()
+	r.Send(Observation{})
+	r.Send(Observation{})
+	if elapsed := time.Since(start); elapsed < r.Interval {
+		t.Error("Uploads should be rate limited", elapsed)
+	}
+}
+
+func TestRunnerSubmit(t *testing.T) {
+	r := newTestRunner(&fakeRequest{})
+	r.Submit(Observation{Time: time.Unix(1, 0)})
+	r.Submit(Observation{Time: time.Unix(2, 0)})
+	if o := <-r.pending; o.Time.Unix() != 2 {
+		t.Error("Submit should replace the pending observation", o.Time)
+	}
+}
+
+func TestRunnerStale(t *testing.T) {
+	r := newTestRunner(&fakeRequest{})
+	now := time.Unix(10000, 0)
+	if r.Stale(Observation{Time: now.Add(-time.Minute)}, now) || !r.Stale(Observation{Time: now.Add(-time.Hour)}, now) {
+		t.Error("Unexpected staleness with the default maximum age")
+	}
+	r.MaxAge = 0
+	if r.Stale(Observation{Time: now.Add(-24 * time.Hour)}, now) {
+		t.Error("Observations should not be stale without a maximum age")
+	}
+}
+
+func TestDewPoint(t *testing.T) {
+	if d := DewPointOf(20, 50); math.Abs(d-9.3) > 0.05 {
+		t.Error("Unexpected dew point", d)
+	}
+}
+
+func newTestWunderground(t *testing.T, handler http.HandlerFunc) *Runner {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	config := viper.New()
+	config.Set("id", "KTEST1")
+	config.Set("key", "secret")
+	config.Set("url", server.URL)
+	w, err := newWunderground(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRunner("wunderground", w)
+	r.Interval = 0
+	r.Backoff = time.Millisecond
+	return r
+}
+
+var observation = Observation{
+	Time: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+	Values: map[string]float64{
+		Temperature: 20,
+		WindSpeed:   10,
+		WindDir:     359.6,
+		RainDay:     25.4,
+		Pressure:    1013.25,
+	},
+}
+
+func TestWunderground(t *testing.T) {
+	var query url.Values
+	r := newTestWunderground(t, func(w http.ResponseWriter, req *http.Request) {
+		query = req.URL.Query()
+		w.Write([]byte("success\n"))
+	})
+	if err := r.Send(observation); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"ID":           "KTEST1",
+		"PASSWORD":     "secret",
+		"action":       "updateraw",
+		"dateutc":      "2026-10-19 12:00:00",
+		"tempf":        "68.00",
+		"windspeedmph": "22.37",
+		"winddir":      "0",
+		"dailyrainin":  "1.00",
+		"baromin":      "29.92",
+	}
+	for key, value := range expected {
+		if query.Get(key) != value {
+			t.Errorf("%s: expected %s, got %s", key, value, query.Get(key))
+		}
+	}
+	if len(query) != len(expected) {
+		t.Error("Unexpected parameters", query)
+	}
+}
+
+func TestWundergroundRejected(t *testing.T) {
+	attempts := 0
+	r := newTestWunderground(t, func(w http.ResponseWriter, req *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("INVALIDPASSWORDID|Password or key and/or id are incorrect\n"))
+	})
+	if err := r.Send(observation); !IsPermanent(err) || attempts != 1 {
+		t.Error("Rejected uploads shouldn't be retried", err, attempts)
+	}
+
+	attempts = 0
+	r = newTestWunderground(t, func(w http.ResponseWriter, req *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	if err := r.Send(observation); err == nil || IsPermanent(err) || attempts != 4 {
+		t.Error("Server errors should be retried", err, attempts)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	r := newTestWunderground(t, func(w http.ResponseWriter, req *http.Request) {
+		t.Error("Dry runs shouldn't send anything")
+	})
+	var out bytes.Buffer
+	r.DryRun = &out
+	if err := r.Send(observation); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "wunderground: GET http") || strings.Contains(out.String(), "secret") || !strings.Contains(out.String(), "tempf=68.00") {
+		t.Error("Unexpected dry run output", out.String())
+	}
+}
+
+func TestOpen(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("upload.services.wu.type", "wunderground")
+	viper.Set("upload.services.wu.id", "KTEST1")
+	viper.Set("upload.services.wu.key", "secret")
+	viper.Set("upload.services.wu.interval", 300)
+
+	runners, err := Open(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runners) != 1 || runners[0].Name != "wu" || runners[0].Interval != 5*time.Minute {
+		t.Error("Unexpected runners", runners)
+	}
+	if _, err := Open([]string{"pwsweather"}); err == nil {
+		t.Error("Unconfigured services should be an error")
+	}
+}
+
+func TestWundergroundRapidFire(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		query = req.URL.Query()
+		w.Write([]byte("success\n"))
+	}))
+	defer server.Close()
+
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("upload.services.wunderground.id", "KTEST1")
+	viper.Set("upload.services.wunderground.key", "secret")
+	viper.Set("upload.services.wunderground.rapidfire", true)
+	viper.Set("upload.services.wunderground.interval", 2.5)
+	viper.Set("upload.services.wunderground.url", server.URL)
+	runners, err := Open(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := runners[0]
+	if !r.Realtime || r.Interval != 2500*time.Millisecond {
+		t.Error("Unexpected runner", r.Realtime, r.Interval)
+	}
+
+	o := Observation{Time: observation.Time, Values: map[string]float64{
+		WindSpeedAvg2m:    4.4704,
+		WindGustDir10m:    45,
+		IndoorTemperature: 0,
+	}}
+	if err := r.Send(o); err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range map[string]string{
+		"realtime":         "1",
+		"rtfreq":           "2.5",
+		"windspdmph_avg2m": "10.00",
+		"windgustdir_10m":  "45",
+		"indoortempf":      "32.00",
+	} {
+		if query.Get(key) != value {
+			t.Errorf("%s: expected %s, got %s", key, value, query.Get(key))
+		}
+	}
+}
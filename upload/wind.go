@@ -0,0 +1,103 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package upload
+
+import (
+	"math"
+	"time"
+)
+
+type windSample struct {
+	t      time.Time
+	speed  float64
+	dir    float64
+	hasDir bool
+}
+
+type dirSample struct {
+	t   time.Time
+	dir float64
+}
+
+// Wind keeps the wind samples of the last ten minutes, for the rolling
+// averages and gusts reported by Weather Underground.
+type Wind struct {
+	speeds []windSample
+	dirs   []dirSample
+}
+
+// AddDir adds a wind direction sample.
+func (w *Wind) AddDir(t time.Time, dir float64) {
+	w.dirs = append(w.dirs, dirSample{t, dir})
+	w.expire(t)
+}
+
+// AddSpeed adds a wind speed sample, with the latest direction.
+func (w *Wind) AddSpeed(t time.Time, speed float64) {
+	s := windSample{t: t, speed: speed}
+	if n := len(w.dirs); n > 0 {
+		s.dir, s.hasDir = w.dirs[n-1].dir, true
+	}
+	w.speeds = append(w.speeds, s)
+	w.expire(t)
+}
+
+func (w *Wind) expire(now time.Time) {
+	cutoff := now.Add(-10 * time.Minute)
+	for len(w.speeds) > 0 && w.speeds[0].t.Before(cutoff) {
+		w.speeds = w.speeds[1:]
+	}
+	// The last direction stays, to pair with later speeds.
+	for len(w.dirs) > 1 && w.dirs[0].t.Before(cutoff) {
+		w.dirs = w.dirs[1:]
+	}
+}
+
+// Fill sets the two minute averages and ten minute gusts of the
+// observation, from the samples up to its time.
+func (w *Wind) Fill(o *Observation) {
+	now := o.Time
+	w.expire(now)
+
+	var sum float64
+	var n int
+	var gust *windSample
+	for i := range w.speeds {
+		s := &w.speeds[i]
+		if s.t.After(now) {
+			continue
+		}
+		if gust == nil || s.speed > gust.speed {
+			gust = s
+		}
+		if !s.t.Before(now.Add(-2 * time.Minute)) {
+			sum += s.speed
+			n++
+		}
+	}
+	if n > 0 {
+		o.Values[WindSpeedAvg2m] = sum / float64(n)
+	}
+	if gust != nil {
+		o.Values[WindGust10m] = gust.speed
+		if gust.hasDir {
+			o.Values[WindGustDir10m] = gust.dir
+		}
+	}
+
+	var sin, cos float64
+	n = 0
+	for _, d := range w.dirs {
+		if d.t.After(now) || d.t.Before(now.Add(-2*time.Minute)) {
+			continue
+		}
+		rad := d.dir * math.Pi / 180
+		sin += math.Sin(rad)
+		cos += math.Cos(rad)
+		n++
+	}
+	if n > 0 {
+		dir := math.Atan2(sin, cos) * 180 / math.Pi
+		o.Values[WindDirAvg2m] = math.Mod(dir+360, 360)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package upload
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWind(t *testing.T) {
+	var w Wind
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+
+	w.AddDir(at(0), 90)
+	w.AddSpeed(at(0), 20) // expires before the end
+	w.AddSpeed(at(60), 8) // the gust, from the east
+	w.AddDir(at(300), 350)
+	w.AddSpeed(at(300), 3)
+	w.AddDir(at(630), 10)
+	w.AddSpeed(at(630), 2)
+	w.AddSpeed(at(660), 4)
+
+	o := Observation{Time: at(660), Values: make(map[string]float64)}
+	w.Fill(&o)
+	if o.Values[WindGust10m] != 8 || o.Values[WindGustDir10m] != 90 {
+		t.Error("Unexpected gust", o.Values)
+	}
+	if o.Values[WindSpeedAvg2m] != 3 {
+		t.Error("Unexpected two minute average", o.Values[WindSpeedAvg2m])
+	}
+	if dir := o.Values[WindDirAvg2m]; math.Abs(dir-10) > 1e-9 {
+		t.Error("Unexpected two minute direction", dir)
+	}
+
+	// The average crosses north.
+	w.AddDir(at(670), 350)
+	o = Observation{Time: at(670), Values: make(map[string]float64)}
+	w.Fill(&o)
+	if dir := o.Values[WindDirAvg2m]; math.Abs(dir) > 1e-9 && math.Abs(dir-360) > 1e-9 {
+		t.Error("Unexpected two minute direction", dir)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package upload
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+
+	"github.com/geoffholden/gowx/units"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	RegisterUploaderType("windy", newWindy)
+}
+
+// windy uploads to Windy's station API. Windy takes metric units, except
+// for the pressure in Pa.
+type windy struct {
+	url     string
+	key     string
+	station int
+}
+
+func newWindy(config *viper.Viper) (Uploader, error) {
+	w := &windy{
+		url:     "https://stations.windy.com/pws/update/",
+		key:     config.GetString("key"),
+		station: config.GetInt("station"),
+	}
+	if config.IsSet("url") {
+		w.url = config.GetString("url")
+	}
+	if w.key == "" {
+		return nil, errors.New("key is required")
+	}
+	return w, nil
+}
+
+func (w *windy) Request(o Observation) (Request, error) {
+	observation := map[string]interface{}{}
+	set := func(name string, field string, convert func(float64) float64) {
+		if v, ok := o.Get(field); ok {
+			if convert != nil {
+				v = convert(v)
+			}
+			observation[name] = math.Round(v*100) / 100
+		}
+	}
+	set("temp", Temperature, nil)
+	set("rh", Humidity, nil)
+	set("dewpoint", DewPoint, nil)
+	set("pressure", Pressure, func(v float64) float64 {
+		u := units.NewPressureHectopascal(v)
+		return u.Pascal()
+	})
+	set("wind", WindSpeed, nil)
+	set("winddir", WindDir, math.Round)
+	set("gust", WindGust, nil)
+	set("precip", RainHour, nil)
+	set("uv", UV, nil)
+	if len(observation) == 0 {
+		return nil, errors.New("windy: no data")
+	}
+	observation["station"] = w.station
+	observation["dateutc"] = o.Time.UTC().Format("2006-01-02T15:04:05")
+
+	body, err := json.Marshal(map[string]interface{}{
+		"observations": []interface{}{observation},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &HTTPRequest{
+		Method:  http.MethodPost,
+		URL:     w.url + url.PathEscape(w.key),
+		Header:  http.Header{"Content-Type": {"application/json"}},
+		Body:    body,
+		Secrets: []string{w.key},
+	}, nil
+}
@@ -0,0 +1,74 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package upload
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/geoffholden/gowx/units"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	RegisterUploaderType("wow", newWOW)
+}
+
+// wowFields are the Weather Underground parameters accepted by WOW.
+// Visibility is sent in kilometres instead.
+var wowFields = map[string]string{
+	"tempf":        Temperature,
+	"humidity":     Humidity,
+	"dewptf":       DewPoint,
+	"baromin":      Pressure,
+	"windspeedmph": WindSpeed,
+	"winddir":      WindDir,
+	"windgustmph":  WindGust,
+	"windgustdir":  WindGustDir,
+	"rainin":       RainHour,
+	"dailyrainin":  RainDay,
+	"soiltempf":    SoilTemperature,
+	"soilmoisture": SoilMoisture,
+}
+
+// wow uploads to the Met Office Weather Observations Website.
+type wow struct {
+	url    string
+	siteID string
+	key    string
+}
+
+func newWOW(config *viper.Viper) (Uploader, error) {
+	w := &wow{
+		url:    "https://wow.metoffice.gov.uk/automaticreading",
+		siteID: config.GetString("siteid"),
+		key:    config.GetString("authkey"),
+	}
+	if config.IsSet("url") {
+		w.url = config.GetString("url")
+	}
+	if w.siteID == "" || w.key == "" {
+		return nil, errors.New("siteid and authkey are required")
+	}
+	return w, nil
+}
+
+func (w *wow) Request(o Observation) (Request, error) {
+	v := wundergroundValues(o, wowFields)
+	if visibility, ok := o.Get(Visibility); ok {
+		u := units.NewDistanceMeters(visibility)
+		v.Set("visibility", strconv.FormatFloat(u.Kilometers(), 'f', 2, 64))
+	}
+	if len(v) == 0 {
+		return nil, errors.New("wow: no data")
+	}
+	v.Set("siteid", w.siteID)
+	v.Set("siteAuthenticationKey", w.key)
+	v.Set("dateutc", o.Time.UTC().Format("2006-01-02 15:04:05"))
+	v.Set("softwaretype", "gowx")
+
+	return &HTTPRequest{
+		URL:     w.url + "?" + v.Encode(),
+		Secrets: []string{w.key},
+	}, nil
+}
@@ -0,0 +1,145 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/geoffholden/gowx/units"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	RegisterUploaderType("wunderground", newWunderground)
+}
+
+// WundergroundFields maps the Weather Underground parameters to the fields
+// of an observation.
+var WundergroundFields = map[string]string{
+	"tempf":            Temperature,
+	"humidity":         Humidity,
+	"dewptf":           DewPoint,
+	"baromin":          Pressure,
+	"windspeedmph":     WindSpeed,
+	"winddir":          WindDir,
+	"windgustmph":      WindGust,
+	"windgustdir":      WindGustDir,
+	"windspdmph_avg2m": WindSpeedAvg2m,
+	"winddir_avg2m":    WindDirAvg2m,
+	"windgustmph_10m":  WindGust10m,
+	"windgustdir_10m":  WindGustDir10m,
+	"rainin":           RainHour,
+	"dailyrainin":      RainDay,
+	"UV":               UV,
+	"solarradiation":   SolarRadiation,
+	"soiltempf":        SoilTemperature,
+	"soilmoisture":     SoilMoisture,
+	"visibility":       Visibility,
+	"indoortempf":      IndoorTemperature,
+	"indoorhumidity":   IndoorHumidity,
+}
+
+// wunderground uploads to Weather Underground. In RapidFire mode, it uploads
+// every few seconds from the samples instead of after each aggregate.
+type wunderground struct {
+	url       string
+	id        string
+	key       string
+	rapidfire time.Duration
+}
+
+func newWunderground(config *viper.Viper) (Uploader, error) {
+	w := &wunderground{
+		url: "https://weatherstation.wunderground.com/weatherstation/updateweatherstation.php",
+		id:  config.GetString("id"),
+		key: config.GetString("key"),
+	}
+	if config.GetBool("rapidfire") {
+		w.url = "https://rtupdate.wunderground.com/weatherstation/updateweatherstation.php"
+		w.rapidfire = 5 * time.Second
+		if config.IsSet("interval") {
+			w.rapidfire = time.Duration(config.GetFloat64("interval") * float64(time.Second))
+		}
+	}
+	if config.IsSet("url") {
+		w.url = config.GetString("url")
+	}
+	if w.id == "" || w.key == "" {
+		return nil, errors.New("id and key are required")
+	}
+	return w, nil
+}
+
+func (w *wunderground) Realtime() time.Duration {
+	return w.rapidfire
+}
+
+// wundergroundValue converts a field to the unit and format of its
+// parameter.
+func wundergroundValue(param string, v float64) string {
+	switch param {
+	case "tempf", "dewptf", "soiltempf", "indoortempf":
+		u := units.NewTemperatureCelsius(v)
+		v = u.Fahrenheit()
+	case "windspeedmph", "windgustmph", "windspdmph_avg2m", "windgustmph_10m":
+		u := units.NewSpeedMetersPerSecond(v)
+		v = u.MilesPerHour()
+	case "winddir", "windgustdir", "winddir_avg2m", "windgustdir_10m":
+		return strconv.FormatFloat(math.Mod(math.Round(v)+360, 360), 'f', 0, 64)
+	case "rainin", "dailyrainin":
+		u := units.NewDistanceMillimeters(v)
+		v = u.Inches()
+	case "baromin":
+		u := units.NewPressureHectopascal(v)
+		v = u.InchMercury()
+	case "visibility":
+		u := units.NewDistanceMeters(v)
+		v = u.NauticalMiles()
+	}
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// wundergroundValues returns the Weather Underground parameters of the
+// given fields, which other services use too.
+func wundergroundValues(o Observation, fields map[string]string) url.Values {
+	v := url.Values{}
+	for param, field := range fields {
+		if value, ok := o.Get(field); ok {
+			v.Set(param, wundergroundValue(param, value))
+		}
+	}
+	return v
+}
+
+func (w *wunderground) Request(o Observation) (Request, error) {
+	v := wundergroundValues(o, WundergroundFields)
+	if len(v) == 0 {
+		return nil, errors.New("wunderground: no data")
+	}
+	v.Set("action", "updateraw")
+	v.Set("ID", w.id)
+	v.Set("PASSWORD", w.key)
+	v.Set("dateutc", o.Time.UTC().Format("2006-01-02 15:04:05"))
+	if w.rapidfire > 0 {
+		v.Set("realtime", "1")
+		v.Set("rtfreq", strconv.FormatFloat(w.rapidfire.Seconds(), 'f', -1, 64))
+	}
+
+	return &HTTPRequest{
+		URL:     w.url + "?" + v.Encode(),
+		Secrets: []string{w.key},
+		Validate: func(res *http.Response, body []byte) error {
+			if s := strings.TrimSpace(string(body)); s != "success" {
+				return Permanent(fmt.Errorf("wunderground: %s %s", res.Status, s))
+			}
+			return nil
+		},
+	}, nil
+}
@@ -8,17 +8,90 @@ function getQueryVariable(variable) {
     return(false);
 }
 
-function populateCurrentData() {
-    $.getJSON("/currentdata.json", function(data) {
-        $('#current_temp').html(data['Temperature'].toFixed(1));
-        $('#current_humidity').html(data['Humidity'].toFixed(0));
-        $('#current_pressure').html(data['Pressure'].toFixed(1));
-        $('#current_wind').html(data['Wind'].toFixed(1));
-        $('#current_wind_dir').html(degreesToCardinal(data['WindDir']));
-        $('#current_wind_angle').css("transform", "rotate(" + (data['WindDir'] + 90) + "deg)");
-        $('#current_rain').html(data['RainRate'].toFixed(2));
+// station is the station shown, from the "station" parameter, or false for
+// the station of the server.
+var station = getQueryVariable("station");
+
+// withStation adds the station shown to the parameters of a URL, unless
+// they have one.
+function withStation(url) {
+    if (!station || /[?&]station=/.test(url)) {
+        return url;
+    }
+    return url + (url.indexOf("?") < 0 ? "?" : "&") + "station=" + station;
+}
+
+// populateStations lists the stations to choose from, if there's more than
+// one, and shows the chosen one instead.
+function populateStations() {
+    $.getJSON("/api/v1/stations", function(stations) {
+        if (stations.length < 2) {
+            return;
+        }
+        var select = $('#station');
+        select.append($('<option>').val("").text("Default station"));
+        $.each(stations, function(i, s) {
+            select.append($('<option>').val(s.Name).text(s.Name));
+        });
+        select.val(station ? decodeURIComponent(station) : "");
+        select.change(function() {
+            var params = [];
+            var time = getQueryVariable("time");
+            if (time) {
+                params.push("time=" + time);
+            }
+            if (select.val()) {
+                params.push("station=" + encodeURIComponent(select.val()));
+            }
+            window.location.search = params.join("&");
+        });
+        $('div.station').show();
     });
-    $.getJSON("/change.json?key=Pressure&type=pressure&time=3h", function(data) {
+}
+
+function showCurrentData(data) {
+    $('#current_temp').html(data['Temperature'].toFixed(1));
+    $('#current_humidity').html(data['Humidity'].toFixed(0));
+    $('#current_pressure').html(data['Pressure'].toFixed(1));
+    $('#current_wind').html(data['Wind'].toFixed(1));
+    $('#current_wind_dir').html(degreesToCardinal(data['WindDir']));
+    $('#current_wind_angle').css("transform", "rotate(" + (data['WindDir'] + 90) + "deg)");
+    $('#current_rain').html(data['RainRate'].toFixed(2));
+}
+
+// liveEvents opens the stream of live updates, calling the handlers with the
+// data of each event. It returns false if the browser can't stream them.
+function liveEvents(params, handlers) {
+    if (typeof EventSource === 'undefined') {
+        return false;
+    }
+    var source = new EventSource(withStation("/events?" + params));
+    $.each(handlers, function(name, handler) {
+        source.addEventListener(name, function(e) {
+            handler(JSON.parse(e.data));
+        });
+    });
+    return true;
+}
+
+function populateCurrentData() {
+    populateStations();
+    $.getJSON(withStation("/currentdata.json"), showCurrentData);
+    if (liveEvents("events=current", {current: showCurrentData})) {
+        populateTendencies();
+    } else {
+        pollCurrentData();
+    }
+}
+
+function pollCurrentData() {
+    $.getJSON(withStation("/currentdata.json"), showCurrentData);
+    populateTendencies(false);
+    setTimeout(pollCurrentData, 30000);
+}
+
+function populateTendencies(repeat) {
+    $.getJSON(withStation("/change.json?key=Pressure&type=pressure&time=3h"), function(data) {
         if (data.Change[0] >= 0.1) {
             result = "Rising";
         } else if (data.Change[0] <= -0.1) {
@@ -40,10 +113,12 @@ function populateCurrentData() {
         }
         $('#pressure_tendency').html(result);
     });
-    $.getJSON("/change.json?key=RainTotal&type=rain&time=24h", function(data) {
+    $.getJSON(withStation("/change.json?key=RainTotal&type=rain&time=24h"), function(data) {
         $('#rain_total').html(data.Change[0].toFixed(2));
     });
-    setTimeout(populateCurrentData, 30000);
+    if (repeat !== false) {
+        setTimeout(populateTendencies, 300000);
+    }
 }
 
 function degreesToCardinal(angle) {
@@ -89,6 +164,7 @@ function degreesToCardinal(angle) {
 
 function makeChart(query, container, name, units, showRange) {
     showRange = typeof showRange !== 'undefined' ? showRange : true;
+    query = withStation(query);
     var options = {
         chart: {
             renderTo: container,
@@ -140,6 +216,27 @@ function makeChart(query, container, name, units, showRange) {
         }
         var chart = new Highcharts.Chart(options);
 
+        // The aggregates are appended to the series, and their ranges to
+        // the range series, as they come in, dropping the oldest points to
+        // keep the time span.
+        var spans = data.Data.map(function(points) {
+            return points.length > 0 ? points[points.length - 1][0] - points[0][0] : 0;
+        });
+        if (liveEvents(query.replace(/^[^?]*\?/, "events=point&"), {
+            point: function(p) {
+                var index = showRange ? p.Series * 2 : p.Series;
+                var series = chart.series[index];
+                var points = series.options.data;
+                var shift = points.length > 1 && p.Data[0] - points[0][0] > spans[p.Series];
+                series.addPoint(p.Data, !showRange, shift);
+                if (showRange) {
+                    chart.series[index + 1].addPoint(p.Errorbar, true, shift);
+                }
+            },
+        })) {
+            return;
+        }
+
         setInterval(function() {
             $.getJSON(query, function(data) {
                 for (var i = 0; i < data.Data.length; i++) {
@@ -0,0 +1,10 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+// Package web holds the default web page and its assets, built into the
+// binary so the server doesn't need the web directory.
+package web
+
+import "embed"
+
+//go:embed *.html *.css *.js *.ico
+var Assets embed.FS
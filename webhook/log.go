@@ -0,0 +1,86 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package webhook
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// logSize is the number of deliveries kept in memory.
+const logSize = 100
+
+// Log records the deliveries, keeping the most recent in memory and, with
+// a path, appending them all to a file as JSON lines.
+type Log struct {
+	path    string
+	mu      sync.Mutex
+	entries []Delivery
+}
+
+func NewLog(path string) *Log {
+	return &Log{path: path}
+}
+
+// Add records a delivery.
+func (l *Log) Add(d Delivery) {
+	if d.Error != "" {
+		jww.ERROR.Printf("Webhook %s: %s event failed after %d attempts: %s\n", d.Hook, d.Event, d.Attempts, d.Error)
+	} else {
+		jww.DEBUG.Printf("Webhook %s: delivered %s event\n", d.Hook, d.Event)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, d)
+	if len(l.entries) > logSize {
+		l.entries = l.entries[len(l.entries)-logSize:]
+	}
+	if l.path == "" {
+		return
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		jww.ERROR.Println(err)
+		return
+	}
+	defer f.Close()
+	if err := json.NewEncoder(f).Encode(d); err != nil {
+		jww.ERROR.Println(err)
+	}
+}
+
+// Entries returns the recent deliveries, oldest first.
+func (l *Log) Entries() []Delivery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Delivery(nil), l.entries...)
+}
+
+// ReadLog returns the last n deliveries of a log file, or all of them if n
+// isn't positive.
+func ReadLog(path string, n int) ([]Delivery, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var result []Delivery
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var d Delivery
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			continue
+		}
+		result = append(result, d)
+		if n > 0 && len(result) > n {
+			result = result[1:]
+		}
+	}
+	return result, scanner.Err()
+}
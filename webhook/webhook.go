@@ -0,0 +1,373 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+// Package webhook posts aggregates and station events to HTTP endpoints,
+// as JSON or through a user-provided template.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+)
+
+// The types of event.
+const (
+	Aggregate = "aggregate" // the aggregates of an interval
+	Record    = "record"    // a new daily high or low
+	Status    = "status"    // a sensor went offline or came back
+)
+
+// Sensor identifies a sensor, with its registry name if it has one.
+type Sensor struct {
+	ID      string `json:"id"`
+	Channel int    `json:"channel"`
+	Serial  string `json:"serial,omitempty"`
+	Name    string `json:"name,omitempty"`
+}
+
+// Value is one aggregated value.
+type Value struct {
+	Sensor
+	Key string  `json:"key"`
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	Avg float64 `json:"avg"`
+}
+
+// RecordChange is a new daily high or low.
+type RecordChange struct {
+	Sensor
+	Key      string  `json:"key"`
+	Day      string  `json:"day"`
+	Kind     string  `json:"kind"`
+	Value    float64 `json:"value"`
+	Previous float64 `json:"previous"`
+}
+
+// StatusChange is a sensor going offline or coming back online.
+type StatusChange struct {
+	Sensor
+	Status   string    `json:"status"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// Event is what a hook delivers. Only the field matching the type is set.
+type Event struct {
+	Type    string        `json:"type"`
+	Time    time.Time     `json:"time"`
+	Station string        `json:"station,omitempty"`
+	Values  []Value       `json:"values,omitempty"`
+	Record  *RecordChange `json:"record,omitempty"`
+	Status  *StatusChange `json:"status,omitempty"`
+}
+
+// Sign returns the signature of a delivery: the hex HMAC-SHA256 of the
+// timestamp, a dot and the body.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Hook is a configured endpoint.
+type Hook struct {
+	Name   string
+	URL    string
+	Method string
+	Header http.Header
+	// Events are the event types delivered, all of them if empty.
+	Events map[string]bool
+	// IDs and Keys restrict the values, records and statuses delivered to
+	// those of the listed sensors (by ID or registry name) and keys.
+	IDs  []string
+	Keys []string
+	// Template renders the body, which is the event as JSON without it.
+	Template    *template.Template
+	ContentType string
+	// Secret signs the deliveries, in the X-Gowx-Signature header.
+	Secret  string
+	Retries int
+	Backoff time.Duration
+	Client  *http.Client
+}
+
+// NewHook creates a hook from its configuration section.
+func NewHook(name string, config *viper.Viper) (*Hook, error) {
+	h := &Hook{
+		Name:        name,
+		URL:         config.GetString("url"),
+		Method:      http.MethodPost,
+		Header:      make(http.Header),
+		Events:      make(map[string]bool),
+		IDs:         config.GetStringSlice("ids"),
+		Keys:        config.GetStringSlice("keys"),
+		ContentType: "application/json",
+		Secret:      config.GetString("secret"),
+		Retries:     3,
+		Backoff:     time.Second,
+		Client:      &http.Client{Timeout: 30 * time.Second},
+	}
+	if h.URL == "" {
+		return nil, errors.New("url is required")
+	}
+	if config.IsSet("method") {
+		h.Method = strings.ToUpper(config.GetString("method"))
+	}
+	for name, value := range config.GetStringMapString("headers") {
+		h.Header.Set(name, value)
+	}
+	for _, event := range config.GetStringSlice("events") {
+		switch event {
+		case Aggregate, Record, Status:
+			h.Events[event] = true
+		default:
+			return nil, fmt.Errorf("unknown event %q", event)
+		}
+	}
+
+	text := config.GetString("template")
+	if file := config.GetString("template_file"); file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		text = string(b)
+	}
+	if text != "" {
+		t, err := template.New(name).Funcs(template.FuncMap{"json": toJSON}).Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		h.Template = t
+		h.ContentType = "text/plain; charset=utf-8"
+	}
+	if config.IsSet("content_type") {
+		h.ContentType = config.GetString("content_type")
+	}
+
+	if config.IsSet("retries") {
+		h.Retries = config.GetInt("retries")
+	}
+	if config.IsSet("timeout") {
+		h.Client.Timeout = time.Duration(config.GetFloat64("timeout") * float64(time.Second))
+	}
+	return h, nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func (h *Hook) matches(s Sensor, key string) bool {
+	if len(h.IDs) > 0 {
+		found := false
+		for _, id := range h.IDs {
+			if id == s.ID || (s.Name != "" && id == s.Name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(h.Keys) > 0 && key != "" {
+		for _, k := range h.Keys {
+			if k == key {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Filter returns the part of the event the hook wants, and false if that's
+// nothing.
+func (h *Hook) Filter(e Event) (Event, bool) {
+	if len(h.Events) > 0 && !h.Events[e.Type] {
+		return e, false
+	}
+	switch {
+	case e.Values != nil:
+		var values []Value
+		for _, v := range e.Values {
+			if h.matches(v.Sensor, v.Key) {
+				values = append(values, v)
+			}
+		}
+		e.Values = values
+		return e, len(values) > 0
+	case e.Record != nil:
+		return e, h.matches(e.Record.Sensor, e.Record.Key)
+	case e.Status != nil:
+		return e, h.matches(e.Status.Sensor, "")
+	}
+	return e, true
+}
+
+// Body renders the body of a delivery.
+func (h *Hook) Body(e Event) ([]byte, error) {
+	if h.Template == nil {
+		return json.Marshal(e)
+	}
+	var buf bytes.Buffer
+	if err := h.Template.Execute(&buf, e); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Delivery is an entry of the delivery log.
+type Delivery struct {
+	Time     time.Time     `json:"time"`
+	Hook     string        `json:"hook"`
+	Event    string        `json:"event"`
+	Status   int           `json:"status,omitempty"`
+	Attempts int           `json:"attempts"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Deliver sends an event, retrying failures with an exponential backoff.
+// Only server errors, rate limiting and connection failures are retried.
+func (h *Hook) Deliver(e Event) Delivery {
+	start := time.Now()
+	d := Delivery{Time: start.UTC(), Hook: h.Name, Event: e.Type}
+	body, err := h.Body(e)
+	if err != nil {
+		d.Error = err.Error()
+		return d
+	}
+
+	backoff := h.Backoff
+	for attempt := 0; attempt <= h.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		d.Attempts++
+		var retry bool
+		d.Status, retry, err = h.send(e, body)
+		if err == nil {
+			d.Error = ""
+			break
+		}
+		d.Error = err.Error()
+		if !retry {
+			break
+		}
+	}
+	d.Duration = time.Since(start)
+	return d
+}
+
+func (h *Hook) send(e Event, body []byte) (int, bool, error) {
+	req, err := http.NewRequest(h.Method, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, err
+	}
+	for name, values := range h.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", h.ContentType)
+	req.Header.Set("X-Gowx-Event", e.Type)
+	if h.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Gowx-Timestamp", timestamp)
+		req.Header.Set("X-Gowx-Signature", "sha256="+Sign(h.Secret, timestamp, body))
+	}
+
+	res, err := h.Client.Do(req)
+	if err != nil {
+		return 0, true, err
+	}
+	defer res.Body.Close()
+	b, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode >= 300 {
+		retry := res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests
+		return res.StatusCode, retry, fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(b)))
+	}
+	return res.StatusCode, false, nil
+}
+
+// Webhooks delivers events to every configured hook. Each hook has a
+// goroutine of its own, so a slow endpoint doesn't hold up the others.
+type Webhooks struct {
+	Log    *Log
+	hooks  []*Hook
+	queues []chan Event
+}
+
+// New starts delivering to the hooks.
+func New(hooks []*Hook, log *Log) *Webhooks {
+	w := &Webhooks{Log: log, hooks: hooks}
+	for _, h := range hooks {
+		queue := make(chan Event, 64)
+		w.queues = append(w.queues, queue)
+		go w.run(h, queue)
+	}
+	return w
+}
+
+// Open creates the hooks in the "webhooks.hooks" section, logging the
+// deliveries to the "webhooks.log" file.
+func Open() (*Webhooks, error) {
+	var hooks []*Hook
+	for name := range viper.GetStringMap("webhooks.hooks") {
+		config := viper.Sub("webhooks.hooks." + name)
+		if config == nil {
+			continue
+		}
+		h, err := NewHook(name, config)
+		if err != nil {
+			return nil, fmt.Errorf("webhook %s: %s", name, err.Error())
+		}
+		hooks = append(hooks, h)
+	}
+	return New(hooks, NewLog(viper.GetString("webhooks.log"))), nil
+}
+
+// Hooks returns the hooks.
+func (w *Webhooks) Hooks() []*Hook {
+	return w.hooks
+}
+
+// Send queues an event for delivery to the hooks that want it.
+func (w *Webhooks) Send(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	for i, h := range w.hooks {
+		filtered, ok := h.Filter(e)
+		if !ok {
+			continue
+		}
+		select {
+		case w.queues[i] <- filtered:
+		default:
+			jww.ERROR.Printf("Webhook %s: queue full, dropping %s event\n", h.Name, e.Type)
+		}
+	}
+}
+
+func (w *Webhooks) run(h *Hook, queue chan Event) {
+	for e := range queue {
+		w.Log.Add(h.Deliver(e))
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright © 2026 Geoff Holden <geoff@geoffholden.com>
+
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var event = Event{
+	Type: Aggregate,
+	Time: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+	Values: []Value{
+		{Sensor{ID: "OS3:1D20", Channel: 1, Name: "outdoor"}, "Temperature", 9, 11, 10},
+		{Sensor{ID: "OS3:1D20", Channel: 1, Name: "outdoor"}, "Humidity", 70, 80, 75},
+		{Sensor{ID: "BMP", Channel: 0}, "Pressure", 1000, 1001, 1000.5},
+	},
+}
+
+func newTestHook(t *testing.T, handler http.HandlerFunc, settings map[string]interface{}) *Hook {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	config := viper.New()
+	config.Set("url", server.URL)
+	for key, value := range settings {
+		config.Set(key, value)
+	}
+	h, err := NewHook("test", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Backoff = time.Millisecond
+	return h
+}
+
+func TestDeliver(t *testing.T) {
+	var header http.Header
+	var body []byte
+	h := newTestHook(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+	}, map[string]interface{}{"secret": "secret", "headers": map[string]string{"Authorization": "Bearer token"}})
+
+	d := h.Deliver(event)
+	if d.Error != "" || d.Status != http.StatusOK || d.Attempts != 1 {
+		t.Fatal("Unexpected delivery", d)
+	}
+	if header.Get("Content-Type") != "application/json" || header.Get("X-Gowx-Event") != "aggregate" || header.Get("Authorization") != "Bearer token" {
+		t.Error("Unexpected headers", header)
+	}
+	if header.Get("X-Gowx-Signature") != "sha256="+Sign("secret", header.Get("X-Gowx-Timestamp"), body) {
+		t.Error("Bad signature", header)
+	}
+	var received Event
+	if err := json.Unmarshal(body, &received); err != nil {
+		t.Fatal(err)
+	}
+	if received.Type != Aggregate || !received.Time.Equal(event.Time) || len(received.Values) != 3 || received.Values[0].Name != "outdoor" {
+		t.Error("Unexpected body", string(body))
+	}
+}
+
+func TestFilter(t *testing.T) {
+	h := newTestHook(t, nil, map[string]interface{}{
+		"events": []string{"aggregate", "status"},
+		"ids":    []string{"outdoor"},
+		"keys":   []string{"Temperature"},
+	})
+	if e, ok := h.Filter(event); !ok || len(e.Values) != 1 || e.Values[0].Key != "Temperature" {
+		t.Error("Unexpected values", e.Values)
+	}
+	if len(event.Values) != 3 {
+		t.Error("Filtering shouldn't change the event")
+	}
+	if _, ok := h.Filter(Event{Type: Record, Record: &RecordChange{Sensor: Sensor{Name: "outdoor"}, Key: "Temperature"}}); ok {
+		t.Error("Records weren't asked for")
+	}
+	if _, ok := h.Filter(Event{Type: Status, Status: &StatusChange{Sensor: Sensor{ID: "OS3:1D20", Name: "outdoor"}}}); !ok {
+		t.Error("Statuses should only be filtered by sensor")
+	}
+	if _, ok := h.Filter(Event{Type: Status, Status: &StatusChange{Sensor: Sensor{ID: "BMP"}}}); ok {
+		t.Error("Other sensors should be filtered out")
+	}
+	if _, err := NewHook("bad", viperOf(map[string]interface{}{"url": "http://localhost", "events": []string{"rain"}})); err == nil {
+		t.Error("Unknown events should be an error")
+	}
+}
+
+func viperOf(settings map[string]interface{}) *viper.Viper {
+	config := viper.New()
+	for key, value := range settings {
+		config.Set(key, value)
+	}
+	return config
+}
+
+func TestTemplate(t *testing.T) {
+	var body []byte
+	var contentType string
+	h := newTestHook(t, func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		body, _ = ioutil.ReadAll(r.Body)
+	}, map[string]interface{}{"template": `{{range .Values}}{{.Key}}={{.Avg}};{{end}}{{json .Time}}`})
+	if d := h.Deliver(event); d.Error != "" {
+		t.Fatal(d.Error)
+	}
+	if string(body) != `Temperature=10;Humidity=75;Pressure=1000.5;"2026-10-19T12:00:00Z"` || contentType != "text/plain; charset=utf-8" {
+		t.Error("Unexpected body", contentType, string(body))
+	}
+}
+
+func TestRetries(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	attempts := 0
+	h := newTestHook(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts > 2 {
+			status = http.StatusNoContent
+		}
+		w.WriteHeader(status)
+	}, nil)
+	if d := h.Deliver(event); d.Error != "" || d.Attempts != 3 || d.Status != http.StatusNoContent {
+		t.Error("Expected success after 3 attempts", d)
+	}
+
+	attempts = 0
+	h = newTestHook(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	}, nil)
+	if d := h.Deliver(event); d.Error == "" || d.Attempts != 1 || d.Status != http.StatusBadRequest {
+		t.Error("Client errors shouldn't be retried", d)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	received := make(chan Event, 1)
+	h := newTestHook(t, func(w http.ResponseWriter, r *http.Request) {
+		var e Event
+		json.NewDecoder(r.Body).Decode(&e)
+		received <- e
+	}, map[string]interface{}{"keys": []string{"Pressure"}})
+
+	path := filepath.Join(t.TempDir(), "webhooks.log")
+	w := New([]*Hook{h}, NewLog(path))
+	w.Send(event)
+	select {
+	case e := <-received:
+		if len(e.Values) != 1 || e.Values[0].Key != "Pressure" {
+			t.Error("Unexpected event", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Nothing delivered")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(w.Log.Entries()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	deliveries, err := ReadLog(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Hook != "test" || deliveries[0].Status != http.StatusOK {
+		t.Error("Unexpected log", deliveries)
+	}
+}